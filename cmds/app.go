@@ -1,37 +1,94 @@
 package main
 
 import (
+	"errors"
 	"log"
+	"os"
 
 	"go.dfds.cloud/bootstrap"
+	oopsAws "go.dfds.cloud/oops/core/aws"
 	"go.dfds.cloud/oops/core/config"
 	"go.dfds.cloud/oops/core/logging"
 	"go.dfds.cloud/oops/feats/api"
+	"go.dfds.cloud/oops/feats/cli"
+	"go.dfds.cloud/oops/feats/dnsserver"
+	"go.dfds.cloud/oops/feats/export"
 	"go.dfds.cloud/oops/feats/jobs"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.dfds.cloud/oops/feats/notify"
+	"go.dfds.cloud/oops/feats/storage"
+	_ "go.dfds.cloud/oops/feats/storage/s3"
 	"go.uber.org/zap"
 )
 
 func main() {
+	if cli.IsCommand(os.Args[1:]) {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
 	// setup base
 	conf, err := config.LoadConfig()
 	if err != nil {
-		log.Fatal("failed to load config")
+		log.Fatalf("failed to load config: %s", err)
 	}
 
 	builder := bootstrap.Builder()
 	builder.EnableLogging(conf.LogDebug, conf.LogLevel)
 	builder.EnableHttpRouter(false)
 	builder.EnableMetrics()
-	builder.EnableOrchestrator("orchestrator")
 	manager := builder.Build()
 	logging.Logger = manager.Logger
-	manager.Orchestrator.Init(logging.Logger)
 
 	logging.Logger.Info("oops launched")
 
-	api.Configure(manager.HttpRouter)
+	history, err := runner.NewHistoryStore(conf)
+	if err != nil {
+		logging.Logger.Fatal("failed to set up run history", zap.Error(err))
+	}
+	jobRunner := runner.New(manager.Context, history)
+
+	if err := validateProviders(conf); err != nil {
+		logging.Logger.Fatal("invalid config", zap.Error(err))
+	}
+	oopsAws.SetDefaults(conf.AwsDefaults())
+	if err := notify.Init(conf.Notifications); err != nil {
+		logging.Logger.Fatal("failed to set up notifications", zap.Error(err))
+	}
+	jobRunner.OnFinish(notify.RunFinished)
+
+	store := config.NewStore(conf)
+	scheduler := jobs.Init(manager.Context, jobRunner, store)
+	store.OnChange(func(old config.Config, new config.Config) {
+		oopsAws.SetDefaults(new.AwsDefaults())
+		if err := notify.Init(new.Notifications); err != nil {
+			logging.Logger.Error("failed to apply notification settings, keeping the previous ones", zap.Error(err))
+		}
+
+		changed := handlers.ChangedJobs(old, new)
+		schedules := handlers.Schedules(new)
+		for _, name := range changed {
+			scheduler.Reschedule(name, schedules[name])
+		}
+		logging.Logger.Info("Job settings changed, applying them from the next run", zap.Strings("jobs", changed))
+	})
+	if conf.Reload.Enabled {
+		go store.Watch(manager.Context, conf.Reload.Interval, validateProviders)
+	}
 
-	jobs.Init(manager.Orchestrator)
+	if err := api.Configure(manager.HttpRouter, store, jobRunner); err != nil {
+		logging.Logger.Fatal("failed to configure api", zap.Error(err))
+	}
+
+	if conf.SecondaryDns.Enabled {
+		dnsServer := dnsserver.New(store)
+		jobRunner.OnFinish(dnsServer.RunFinished)
+		go func() {
+			if err := dnsServer.Run(manager.Context); err != nil {
+				logging.Logger.Error("Secondary DNS stopped", zap.Error(err))
+			}
+		}()
+	}
 
 	<-manager.Context.Done()
 	if err := manager.HttpServer.Shutdown(manager.Context); err != nil {
@@ -40,3 +97,17 @@ func main() {
 
 	logging.Logger.Info("server shutting down")
 }
+
+// validateProviders checks the specs of backup locations and notification sinks and the export formats, which the config
+// package doesn't know about
+func validateProviders(conf config.Config) error {
+	errs := []error{export.Validate(conf.Job.Route53Backup.Exports)}
+	for _, location := range conf.BackupLocations {
+		errs = append(errs, storage.ValidateLocation(conf, location))
+	}
+
+	_, err := notify.New(conf.Notifications)
+	errs = append(errs, err)
+
+	return errors.Join(errs...)
+}
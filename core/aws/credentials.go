@@ -0,0 +1,158 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"go.dfds.cloud/oops/core/config"
+)
+
+// RoleOptions configure how a role is assumed
+type RoleOptions struct {
+	// HubRoleArn is assumed first, its credentials are then used to assume the role. AWS limits chained sessions to an hour.
+	HubRoleArn string
+	ExternalId string
+	// Duration of the session, the SDK default of 15 minutes is used if zero. Credentials are refreshed before they expire.
+	Duration time.Duration
+	Tags     map[string]string
+}
+
+// providerIdleTimeout is how long an unused provider is kept, session names contain the run id so providers of finished
+// runs are never asked for again
+const providerIdleTimeout = 2 * time.Hour
+
+// credentialsExpiryWindow is how long before they expire credentials are refreshed, so calls in flight don't fail
+const credentialsExpiryWindow = 5 * time.Minute
+
+type providerKey struct {
+	settings    config.AwsSettings
+	sts         StsApi
+	roleArn     string
+	hubRoleArn  string
+	externalId  string
+	duration    time.Duration
+	tags        string
+	sessionName string
+}
+
+type cachedProvider struct {
+	provider *aws.CredentialsCache
+	lastUsed time.Time
+}
+
+var providers = struct {
+	sync.Mutex
+	cache map[providerKey]*cachedProvider
+}{cache: make(map[providerKey]*cachedProvider)}
+
+// AssumeRoleCredentials returns a credentials provider for roleArn, shared by every caller with the same settings, options
+// and session name. Credentials are retrieved on first use and refreshed automatically before they expire.
+func (f *ConfigFactory) AssumeRoleCredentials(ctx context.Context, roleArn string, opts RoleOptions) (aws.CredentialsProvider, error) {
+	providers.Lock()
+	defer providers.Unlock()
+
+	now := time.Now()
+	for k, cached := range providers.cache {
+		if now.Sub(cached.lastUsed) > providerIdleTimeout {
+			delete(providers.cache, k)
+		}
+	}
+
+	return f.assumeRoleCredentials(ctx, roleArn, opts, now)
+}
+
+// assumeRoleCredentials expects the providers lock to be held
+func (f *ConfigFactory) assumeRoleCredentials(ctx context.Context, roleArn string, opts RoleOptions, now time.Time) (*aws.CredentialsCache, error) {
+	key := providerKey{
+		settings:    f.settings,
+		sts:         f.sts,
+		roleArn:     roleArn,
+		hubRoleArn:  opts.HubRoleArn,
+		externalId:  opts.ExternalId,
+		duration:    opts.Duration,
+		tags:        fmt.Sprint(opts.Tags),
+		sessionName: sessionNameFrom(ctx),
+	}
+	if cached, ok := providers.cache[key]; ok {
+		cached.lastUsed = now
+		return cached.provider, nil
+	}
+
+	cfg, err := f.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if opts.HubRoleArn != "" {
+		// The hub role is assumed with the default credentials, and shared between the roles chained from it
+		hub, err := f.assumeRoleCredentials(ctx, opts.HubRoleArn, RoleOptions{}, now)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Credentials = hub
+	}
+
+	provider := aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(f.Sts(cfg), roleArn, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = key.sessionName
+		if opts.ExternalId != "" {
+			o.ExternalID = aws.String(opts.ExternalId)
+		}
+		if opts.Duration > 0 {
+			o.Duration = opts.Duration
+		}
+		for _, k := range slices.Sorted(maps.Keys(opts.Tags)) {
+			o.Tags = append(o.Tags, types.Tag{Key: aws.String(k), Value: aws.String(opts.Tags[k])})
+		}
+	}), func(o *aws.CredentialsCacheOptions) {
+		o.ExpiryWindow = credentialsExpiryWindow
+	})
+	providers.cache[key] = &cachedProvider{provider: provider, lastUsed: now}
+
+	return provider, nil
+}
+
+// AssumeRoleConfig returns a config using the credentials of AssumeRoleCredentials
+func (f *ConfigFactory) AssumeRoleConfig(ctx context.Context, roleArn string, opts RoleOptions) (aws.Config, error) {
+	provider, err := f.AssumeRoleCredentials(ctx, roleArn, opts)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	return f.Load(ctx, awsConfig.WithCredentialsProvider(provider))
+}
+
+type sessionNameKey struct{}
+
+var sessionNameInvalid = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// SessionName builds the role session name recorded in CloudTrail, identifying the job and run
+func SessionName(job string, runId string) string {
+	name := strings.Join(slices.DeleteFunc([]string{"oops", job, runId}, func(s string) bool { return s == "" }), "-")
+	name = sessionNameInvalid.ReplaceAllString(name, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
+
+// WithSessionName sets the role session name for roles assumed within ctx
+func WithSessionName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, sessionNameKey{}, name)
+}
+
+func sessionNameFrom(ctx context.Context) string {
+	if name, ok := ctx.Value(sessionNameKey{}).(string); ok && name != "" {
+		return name
+	}
+	return "oops"
+}
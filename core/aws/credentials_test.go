@@ -0,0 +1,126 @@
+package aws
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"go.dfds.cloud/oops/core/aws/fake"
+	"go.dfds.cloud/oops/core/config"
+)
+
+func TestSessionName(t *testing.T) {
+	assert.Equal(t, "oops-route53Backup-m1x2y3", SessionName("route53Backup", "m1x2y3"))
+	assert.Equal(t, "oops-restore", SessionName("restore", ""))
+	// Characters STS rejects are replaced, and names are cut to its 64 character limit
+	assert.Equal(t, "oops-a_b-1", SessionName("a/b", "1"))
+	assert.Len(t, SessionName(strings.Repeat("x", 100), "1"), 64)
+}
+
+func TestAssumeRoleCredentials_Cache(t *testing.T) {
+	ctx := WithSessionName(context.Background(), "oops-test")
+	sts := fake.NewSts()
+	factory := NewConfigFactory().WithSts(sts)
+
+	first, err := factory.AssumeRoleCredentials(ctx, "arn:aws:iam::111111111111:role/reader", RoleOptions{})
+	assert.NoError(t, err)
+	same, err := factory.AssumeRoleCredentials(ctx, "arn:aws:iam::111111111111:role/reader", RoleOptions{})
+	assert.NoError(t, err)
+	assert.Same(t, first, same)
+
+	// Every option is part of the key, so differently assumed sessions aren't mixed up
+	others := []struct {
+		ctx     context.Context
+		roleArn string
+		opts    RoleOptions
+	}{
+		{ctx, "arn:aws:iam::222222222222:role/reader", RoleOptions{}},
+		{ctx, "arn:aws:iam::111111111111:role/reader", RoleOptions{ExternalId: "x"}},
+		{ctx, "arn:aws:iam::111111111111:role/reader", RoleOptions{Duration: time.Hour}},
+		{ctx, "arn:aws:iam::111111111111:role/reader", RoleOptions{Tags: map[string]string{"job": "restore"}}},
+		{WithSessionName(context.Background(), "oops-other"), "arn:aws:iam::111111111111:role/reader", RoleOptions{}},
+	}
+	for _, other := range others {
+		provider, err := factory.AssumeRoleCredentials(other.ctx, other.roleArn, other.opts)
+		assert.NoError(t, err)
+		assert.NotSame(t, first, provider)
+	}
+
+	// Credentials are only retrieved once while they're valid
+	for range 3 {
+		creds, err := first.Retrieve(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "arn:aws:iam::111111111111:role/reader", creds.SessionToken)
+	}
+	calls := sts.Calls()
+	assert.Len(t, calls, 1)
+	assert.Equal(t, "oops-test", aws.ToString(calls[0].RoleSessionName))
+}
+
+func TestAssumeRoleCredentials_Refresh(t *testing.T) {
+	ctx := WithSessionName(context.Background(), "oops-test")
+	sts := fake.NewSts()
+	// Credentials expiring within the expiry window are refreshed on every use
+	sts.Lifetime = credentialsExpiryWindow / 2
+	factory := NewConfigFactory().WithSts(sts)
+
+	provider, err := factory.AssumeRoleCredentials(ctx, "arn:aws:iam::111111111111:role/reader", RoleOptions{})
+	assert.NoError(t, err)
+
+	first, err := provider.Retrieve(ctx)
+	assert.NoError(t, err)
+	second, err := provider.Retrieve(ctx)
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, first.AccessKeyID, second.AccessKeyID)
+	assert.Len(t, sts.Calls(), 2)
+}
+
+func TestAssumeRoleCredentials_HubRole(t *testing.T) {
+	// The fake serves the STS API, so the SDK signs every request with the credentials it's chained from
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIADEFAULT")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "default")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	sts := fake.NewSts()
+	server := httptest.NewServer(sts)
+	defer server.Close()
+
+	ctx := WithSessionName(context.Background(), "oops-test")
+	factory := NewConfigFactory(config.AwsSettings{StsEndpoint: server.URL})
+	hub := "arn:aws:iam::999999999999:role/hub"
+	opts := RoleOptions{HubRoleArn: hub, ExternalId: "x", Duration: time.Hour}
+
+	for _, account := range []string{"111111111111", "222222222222"} {
+		provider, err := factory.AssumeRoleCredentials(ctx, factory.RoleArn(account, "reader"), opts)
+		assert.NoError(t, err)
+		_, err = provider.Retrieve(ctx)
+		assert.NoError(t, err)
+	}
+
+	// The hub role is assumed once with the default credentials, without the options of the roles chained from it,
+	// which are assumed with the credentials of the hub session
+	calls := sts.Calls()
+	assert.Len(t, calls, 3)
+	assert.Equal(t, []string{"AKIADEFAULT", "ASIAFAKE1", "ASIAFAKE1"}, sts.Callers())
+	assert.Equal(t, hub, aws.ToString(calls[0].RoleArn))
+	assert.Nil(t, calls[0].ExternalId)
+	assert.Equal(t, "arn:aws:iam::111111111111:role/reader", aws.ToString(calls[1].RoleArn))
+	assert.Equal(t, "x", aws.ToString(calls[1].ExternalId))
+	assert.Equal(t, int32(3600), aws.ToInt32(calls[1].DurationSeconds))
+	assert.Equal(t, "oops-test", aws.ToString(calls[1].RoleSessionName))
+	assert.Equal(t, "arn:aws:iam::222222222222:role/reader", aws.ToString(calls[2].RoleArn))
+
+	// Chained roles aren't assumed when the hub role is denied
+	sts.Deny(hub)
+	provider, err := factory.AssumeRoleCredentials(WithSessionName(ctx, "oops-denied"), factory.RoleArn("333333333333", "reader"), opts)
+	assert.NoError(t, err)
+	_, err = provider.Retrieve(ctx)
+	assert.ErrorContains(t, err, "AccessDenied")
+	assert.Len(t, sts.Calls(), 4)
+}
@@ -0,0 +1,167 @@
+package aws
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// RecordChange is a difference in a resource record set between two sets of records
+type RecordChange struct {
+	Account       string `json:"account"`
+	Zone          string `json:"zone"`
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	SetIdentifier string `json:"setIdentifier,omitempty"`
+	Change        string `json:"change"`
+}
+
+func (r RecordChange) String() string {
+	name := fmt.Sprintf("%s %s", r.Name, r.Type)
+	if r.SetIdentifier != "" {
+		name = fmt.Sprintf("%s (%s)", name, r.SetIdentifier)
+	}
+	return fmt.Sprintf("%s %s/%s: %s", r.Change, r.Account, r.Zone, name)
+}
+
+type recordKey struct {
+	name          string
+	recordType    string
+	setIdentifier string
+}
+
+// DiffRecords compares two backups of records by account and zone, returning the changes from old to new sorted by account, zone and name
+func DiffRecords(old map[string]map[string][]route53Types.ResourceRecordSet, new map[string]map[string][]route53Types.ResourceRecordSet) []RecordChange {
+	var payload []RecordChange
+
+	accounts := make(map[string]bool)
+	for acc := range old {
+		accounts[acc] = true
+	}
+	for acc := range new {
+		accounts[acc] = true
+	}
+
+	for acc := range accounts {
+		zones := make(map[string]bool)
+		for zone := range old[acc] {
+			zones[zone] = true
+		}
+		for zone := range new[acc] {
+			zones[zone] = true
+		}
+
+		for zone := range zones {
+			payload = append(payload, DiffZone(acc, zone, old[acc][zone], new[acc][zone])...)
+		}
+	}
+
+	sort.Slice(payload, func(i, j int) bool {
+		if payload[i].Account != payload[j].Account {
+			return payload[i].Account < payload[j].Account
+		}
+		if payload[i].Zone != payload[j].Zone {
+			return payload[i].Zone < payload[j].Zone
+		}
+		if payload[i].Name != payload[j].Name {
+			return payload[i].Name < payload[j].Name
+		}
+		return payload[i].Type < payload[j].Type
+	})
+
+	return payload
+}
+
+// DiffZone compares two versions of the records of a single zone
+func DiffZone(account string, zone string, old []route53Types.ResourceRecordSet, new []route53Types.ResourceRecordSet) []RecordChange {
+	var payload []RecordChange
+
+	oldByKey := indexRecords(old)
+	newByKey := indexRecords(new)
+
+	for key, oldRec := range oldByKey {
+		newRec, ok := newByKey[key]
+		switch {
+		case !ok:
+			payload = append(payload, newRecordChange(account, zone, key, ChangeRemoved))
+		case !recordSetsEqual(oldRec, newRec):
+			payload = append(payload, newRecordChange(account, zone, key, ChangeModified))
+		}
+	}
+
+	for key := range newByKey {
+		if _, ok := oldByKey[key]; !ok {
+			payload = append(payload, newRecordChange(account, zone, key, ChangeAdded))
+		}
+	}
+
+	return payload
+}
+
+func newRecordChange(account string, zone string, key recordKey, change string) RecordChange {
+	return RecordChange{
+		Account:       account,
+		Zone:          zone,
+		Name:          key.name,
+		Type:          key.recordType,
+		SetIdentifier: key.setIdentifier,
+		Change:        change,
+	}
+}
+
+func indexRecords(records []route53Types.ResourceRecordSet) map[recordKey]route53Types.ResourceRecordSet {
+	payload := make(map[recordKey]route53Types.ResourceRecordSet)
+	for _, rec := range records {
+		payload[recordKey{
+			name:          aws.ToString(rec.Name),
+			recordType:    string(rec.Type),
+			setIdentifier: aws.ToString(rec.SetIdentifier),
+		}] = rec
+	}
+	return payload
+}
+
+func recordSetsEqual(a route53Types.ResourceRecordSet, b route53Types.ResourceRecordSet) bool {
+	if aws.ToInt64(a.TTL) != aws.ToInt64(b.TTL) {
+		return false
+	}
+
+	if !reflect.DeepEqual(recordValues(a), recordValues(b)) {
+		return false
+	}
+
+	if (a.AliasTarget == nil) != (b.AliasTarget == nil) {
+		return false
+	}
+	if a.AliasTarget != nil {
+		if aws.ToString(a.AliasTarget.DNSName) != aws.ToString(b.AliasTarget.DNSName) ||
+			aws.ToString(a.AliasTarget.HostedZoneId) != aws.ToString(b.AliasTarget.HostedZoneId) ||
+			a.AliasTarget.EvaluateTargetHealth != b.AliasTarget.EvaluateTargetHealth {
+			return false
+		}
+	}
+
+	return aws.ToInt64(a.Weight) == aws.ToInt64(b.Weight) &&
+		a.Failover == b.Failover &&
+		a.Region == b.Region &&
+		aws.ToString(a.HealthCheckId) == aws.ToString(b.HealthCheckId) &&
+		aws.ToBool(a.MultiValueAnswer) == aws.ToBool(b.MultiValueAnswer)
+}
+
+func recordValues(rec route53Types.ResourceRecordSet) []string {
+	payload := []string{}
+	for _, val := range rec.ResourceRecords {
+		payload = append(payload, aws.ToString(val.Value))
+	}
+	sort.Strings(payload)
+	return payload
+}
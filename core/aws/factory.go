@@ -0,0 +1,93 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"go.dfds.cloud/oops/core/config"
+)
+
+// builtinDefaults apply until SetDefaults is called, e.g. in tests
+var builtinDefaults = config.AwsSettings{Region: "eu-west-1", Partition: "aws", StsEndpoint: "regional"}
+
+var defaults atomic.Pointer[config.AwsSettings]
+
+// SetDefaults replaces the settings that factories fall back to, it's called with Config.Aws on start and on config reload
+func SetDefaults(settings config.AwsSettings) {
+	merged := builtinDefaults.Merge(settings)
+	defaults.Store(&merged)
+}
+
+// Defaults returns the current default settings
+func Defaults() config.AwsSettings {
+	if current := defaults.Load(); current != nil {
+		return *current
+	}
+	return builtinDefaults
+}
+
+// ConfigFactory creates AWS SDK configs and STS clients for one set of settings
+type ConfigFactory struct {
+	settings config.AwsSettings
+	// sts replaces the STS clients of the factory if set
+	sts StsApi
+}
+
+// NewConfigFactory applies the overrides, in order, on top of the defaults
+func NewConfigFactory(overrides ...config.AwsSettings) *ConfigFactory {
+	settings := Defaults()
+	for _, override := range overrides {
+		settings = settings.Merge(override)
+	}
+
+	return &ConfigFactory{settings: settings}
+}
+
+func (f *ConfigFactory) Settings() config.AwsSettings {
+	return f.settings
+}
+
+// WithSts returns a copy of the factory that assumes roles through client, e.g. a fake in tests
+func (f *ConfigFactory) WithSts(client StsApi) *ConfigFactory {
+	copied := *f
+	copied.sts = client
+	return &copied
+}
+
+// RoleArn builds the ARN of a role in the partition of the factory
+func (f *ConfigFactory) RoleArn(account string, role string) string {
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", f.settings.Partition, account, role)
+}
+
+// Load returns a config for the region of the factory, optFns are applied after the defaults so they can e.g. set credentials
+func (f *ConfigFactory) Load(ctx context.Context, optFns ...func(*awsConfig.LoadOptions) error) (aws.Config, error) {
+	opts := append([]func(*awsConfig.LoadOptions) error{
+		awsConfig.WithRegion(f.settings.Region),
+		awsConfig.WithHTTPClient(CreateHttpClientWithoutKeepAlive()),
+	}, optFns...)
+
+	return awsConfig.LoadDefaultConfig(ctx, opts...)
+}
+
+// Sts returns an STS client for cfg that uses the configured STS endpoint
+func (f *ConfigFactory) Sts(cfg aws.Config) StsApi {
+	if f.sts != nil {
+		return f.sts
+	}
+
+	return sts.NewFromConfig(cfg, func(o *sts.Options) {
+		switch f.settings.StsEndpoint {
+		case "", "regional":
+		case "global":
+			// The global endpoint signs with us-east-1
+			o.Region = "us-east-1"
+			o.BaseEndpoint = aws.String("https://sts.amazonaws.com")
+		default:
+			o.BaseEndpoint = aws.String(f.settings.StsEndpoint)
+		}
+	})
+}
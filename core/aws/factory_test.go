@@ -0,0 +1,80 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/stretchr/testify/assert"
+	"go.dfds.cloud/oops/core/aws/fake"
+	"go.dfds.cloud/oops/core/config"
+)
+
+func TestConfigFactory_RoleArn(t *testing.T) {
+	tests := []struct {
+		partition string
+		expected  string
+	}{
+		{"aws", "arn:aws:iam::111111111111:role/reader"},
+		{"aws-cn", "arn:aws-cn:iam::111111111111:role/reader"},
+		{"aws-us-gov", "arn:aws-us-gov:iam::111111111111:role/reader"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.partition, func(t *testing.T) {
+			factory := NewConfigFactory(config.AwsSettings{Partition: tt.partition})
+			assert.Equal(t, tt.expected, factory.RoleArn("111111111111", "reader"))
+		})
+	}
+}
+
+func TestConfigFactory_Sts(t *testing.T) {
+	tests := []struct {
+		name         string
+		endpoint     string
+		region       string
+		baseEndpoint *string
+	}{
+		{"regional", "regional", "eu-west-1", nil},
+		{"unset", "", "eu-west-1", nil},
+		{"global", "global", "us-east-1", aws.String("https://sts.amazonaws.com")},
+		{"vpc endpoint", "https://vpce-1.sts.eu-west-1.vpce.amazonaws.com", "eu-west-1", aws.String("https://vpce-1.sts.eu-west-1.vpce.amazonaws.com")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory := &ConfigFactory{settings: config.AwsSettings{Region: "eu-west-1", Partition: "aws", StsEndpoint: tt.endpoint}}
+
+			client, ok := factory.Sts(aws.Config{Region: "eu-west-1"}).(*sts.Client)
+			assert.True(t, ok)
+			assert.Equal(t, tt.region, client.Options().Region)
+			assert.Equal(t, tt.baseEndpoint, client.Options().BaseEndpoint)
+		})
+	}
+
+	fakeSts := fake.NewSts()
+	factory := NewConfigFactory().WithSts(fakeSts)
+	assert.Same(t, fakeSts, factory.Sts(aws.Config{}))
+}
+
+func TestNewConfigFactory_Merge(t *testing.T) {
+	t.Cleanup(func() { defaults.Store(nil) })
+
+	assert.Equal(t, builtinDefaults, NewConfigFactory().Settings())
+
+	// Defaults fall back to the builtin ones for fields they don't set
+	SetDefaults(config.AwsSettings{Region: "eu-central-1"})
+	assert.Equal(t, config.AwsSettings{Region: "eu-central-1", Partition: "aws", StsEndpoint: "regional"}, NewConfigFactory().Settings())
+
+	// Later overrides take precedence, empty fields keep what's underneath
+	factory := NewConfigFactory(
+		config.AwsSettings{Region: "us-gov-west-1", Partition: "aws-us-gov"},
+		config.AwsSettings{StsEndpoint: "https://sts.us-gov-west-1.amazonaws.com"},
+		config.AwsSettings{Region: "us-gov-east-1"},
+	)
+	assert.Equal(t, config.AwsSettings{
+		Region:      "us-gov-east-1",
+		Partition:   "aws-us-gov",
+		StsEndpoint: "https://sts.us-gov-west-1.amazonaws.com",
+	}, factory.Settings())
+}
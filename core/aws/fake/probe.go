@@ -0,0 +1,34 @@
+package fake
+
+import (
+	"context"
+	"errors"
+)
+
+// Probe answers the lookups of the lint and takeover checks from maps. Hosts and buckets missing from the maps fail
+// like a lookup timing out, names without TXT values have none.
+type Probe struct {
+	Hosts   map[string]bool
+	Buckets map[string]bool
+	Txt     map[string][]string
+}
+
+func (p *Probe) HostExists(_ context.Context, host string) (bool, error) {
+	return lookup(p.Hosts, host)
+}
+
+func (p *Probe) BucketExists(_ context.Context, bucket string) (bool, error) {
+	return lookup(p.Buckets, bucket)
+}
+
+func (p *Probe) LookupTXT(_ context.Context, name string) ([]string, error) {
+	return p.Txt[name], nil
+}
+
+func lookup(exists map[string]bool, name string) (bool, error) {
+	ok, found := exists[name]
+	if !found {
+		return false, errors.New("timeout")
+	}
+	return ok, nil
+}
@@ -0,0 +1,23 @@
+package fake
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// Record returns a record set with the values, for building zones in tests
+func Record(name string, recordType types.RRType, ttl int64, values ...string) types.ResourceRecordSet {
+	rec := types.ResourceRecordSet{Name: aws.String(name), Type: recordType, TTL: aws.Int64(ttl)}
+	for _, value := range values {
+		rec.ResourceRecords = append(rec.ResourceRecords, types.ResourceRecord{Value: aws.String(value)})
+	}
+	return rec
+}
+
+// Alias returns an alias record set pointing at target, in the hosted zone of CloudFront
+func Alias(name string, recordType types.RRType, target string) types.ResourceRecordSet {
+	return types.ResourceRecordSet{Name: aws.String(name), Type: recordType, AliasTarget: &types.AliasTarget{
+		DNSName:      aws.String(target),
+		HostedZoneId: aws.String("Z2FDTNDATAQYW2"),
+	}}
+}
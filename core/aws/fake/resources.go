@@ -0,0 +1,84 @@
+package fake
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	cloudfrontTypes "github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+	elasticbeanstalkTypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	elbTypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2Types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Resources holds the resources of one account and region, each listed in a single page
+type Resources struct {
+	Buckets []string
+	// Distributions, Environments and LoadBalancers are DNS names, e.g. d111.cloudfront.net
+	Distributions        []string
+	Environments         []string
+	LoadBalancers        []string
+	ClassicLoadBalancers []string
+	// Err is returned by every call, e.g. for accounts where the role isn't allowed to list resources
+	Err error
+}
+
+func (r *Resources) ListBuckets(_ context.Context, _ *s3.ListBucketsInput, _ ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	payload := &s3.ListBucketsOutput{}
+	for _, name := range r.Buckets {
+		payload.Buckets = append(payload.Buckets, s3Types.Bucket{Name: aws.String(name)})
+	}
+	return payload, nil
+}
+
+func (r *Resources) ListDistributions(_ context.Context, _ *cloudfront.ListDistributionsInput, _ ...func(*cloudfront.Options)) (*cloudfront.ListDistributionsOutput, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	list := &cloudfrontTypes.DistributionList{IsTruncated: aws.Bool(false), Quantity: aws.Int32(int32(len(r.Distributions)))}
+	for _, name := range r.Distributions {
+		list.Items = append(list.Items, cloudfrontTypes.DistributionSummary{DomainName: aws.String(name)})
+	}
+	return &cloudfront.ListDistributionsOutput{DistributionList: list}, nil
+}
+
+func (r *Resources) DescribeEnvironments(_ context.Context, _ *elasticbeanstalk.DescribeEnvironmentsInput, _ ...func(*elasticbeanstalk.Options)) (*elasticbeanstalk.DescribeEnvironmentsOutput, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	payload := &elasticbeanstalk.DescribeEnvironmentsOutput{}
+	for _, name := range r.Environments {
+		payload.Environments = append(payload.Environments, elasticbeanstalkTypes.EnvironmentDescription{CNAME: aws.String(name)})
+	}
+	return payload, nil
+}
+
+func (r *Resources) DescribeLoadBalancers(_ context.Context, _ *elasticloadbalancingv2.DescribeLoadBalancersInput, _ ...func(*elasticloadbalancingv2.Options)) (*elasticloadbalancingv2.DescribeLoadBalancersOutput, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	payload := &elasticloadbalancingv2.DescribeLoadBalancersOutput{}
+	for _, name := range r.LoadBalancers {
+		payload.LoadBalancers = append(payload.LoadBalancers, elbv2Types.LoadBalancer{DNSName: aws.String(name)})
+	}
+	return payload, nil
+}
+
+func (r *Resources) DescribeClassicLoadBalancers(_ context.Context, _ *elasticloadbalancing.DescribeLoadBalancersInput, _ ...func(*elasticloadbalancing.Options)) (*elasticloadbalancing.DescribeLoadBalancersOutput, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	payload := &elasticloadbalancing.DescribeLoadBalancersOutput{}
+	for _, name := range r.ClassicLoadBalancers {
+		payload.LoadBalancerDescriptions = append(payload.LoadBalancerDescriptions, elbTypes.LoadBalancerDescription{DNSName: aws.String(name)})
+	}
+	return payload, nil
+}
@@ -0,0 +1,238 @@
+// Package fake implements the Route53 and STS APIs in memory, for testing code that talks to AWS without AWS
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+)
+
+// Route53 holds the hosted zones of one account
+type Route53 struct {
+	// PageSize limits the items per page, so callers' pagination gets exercised
+	PageSize int
+
+	mu       sync.Mutex
+	zones    []*hostedZone
+	failures map[string]error
+	changes  int
+}
+
+type hostedZone struct {
+	zone    types.HostedZone
+	records []types.ResourceRecordSet
+}
+
+func NewRoute53() *Route53 {
+	return &Route53{PageSize: 100, failures: make(map[string]error)}
+}
+
+// AddZone creates a public hosted zone and returns its id. Like Route53, the zone starts out with SOA and NS records at
+// the apex, the records are added after them.
+func (r *Route53) AddZone(name string, records ...types.ResourceRecordSet) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	id := fmt.Sprintf("/hostedzone/Z%d", len(r.zones)+1)
+	apex := []types.ResourceRecordSet{
+		{Name: aws.String(name), Type: types.RRTypeSoa, TTL: aws.Int64(900), ResourceRecords: []types.ResourceRecord{
+			{Value: aws.String("ns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400")},
+		}},
+		{Name: aws.String(name), Type: types.RRTypeNs, TTL: aws.Int64(172800), ResourceRecords: []types.ResourceRecord{
+			{Value: aws.String("ns-1.awsdns-01.org.")},
+			{Value: aws.String("ns-2.awsdns-02.com.")},
+		}},
+	}
+	r.zones = append(r.zones, &hostedZone{
+		zone:    types.HostedZone{Id: aws.String(id), Name: aws.String(name), CallerReference: aws.String(id)},
+		records: append(apex, records...),
+	})
+
+	return id
+}
+
+// FailZone makes listing the records of the zone return err
+func (r *Route53) FailZone(id string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures[normaliseId(id)] = err
+}
+
+// Records returns the current records of the zone
+func (r *Route53) Records(id string) []types.ResourceRecordSet {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	zone, err := r.find(aws.String(id))
+	if err != nil {
+		return nil
+	}
+	return append([]types.ResourceRecordSet{}, zone.records...)
+}
+
+func (r *Route53) ListHostedZones(_ context.Context, params *route53.ListHostedZonesInput, _ ...func(*route53.Options)) (*route53.ListHostedZonesOutput, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	start := 0
+	if params.Marker != nil {
+		start, _ = strconv.Atoi(*params.Marker)
+	}
+	end := min(start+r.PageSize, len(r.zones))
+
+	payload := &route53.ListHostedZonesOutput{MaxItems: aws.Int32(int32(r.PageSize))}
+	for _, zone := range r.zones[start:end] {
+		payload.HostedZones = append(payload.HostedZones, zone.zone)
+	}
+	if end < len(r.zones) {
+		payload.IsTruncated = true
+		payload.NextMarker = aws.String(strconv.Itoa(end))
+	}
+
+	return payload, nil
+}
+
+// ListResourceRecordSets continues from the record named by StartRecordName, StartRecordType and StartRecordIdentifier
+func (r *Route53) ListResourceRecordSets(_ context.Context, params *route53.ListResourceRecordSetsInput, _ ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	zone, err := r.find(params.HostedZoneId)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.failures[normaliseId(*params.HostedZoneId)]; err != nil {
+		return nil, err
+	}
+
+	start := 0
+	if params.StartRecordName != nil {
+		for i, rec := range zone.records {
+			if aws.ToString(rec.Name) == *params.StartRecordName && rec.Type == params.StartRecordType &&
+				aws.ToString(rec.SetIdentifier) == aws.ToString(params.StartRecordIdentifier) {
+				start = i
+				break
+			}
+		}
+	}
+	end := min(start+r.PageSize, len(zone.records))
+
+	payload := &route53.ListResourceRecordSetsOutput{
+		MaxItems:           aws.Int32(int32(r.PageSize)),
+		ResourceRecordSets: append([]types.ResourceRecordSet{}, zone.records[start:end]...),
+	}
+	if end < len(zone.records) {
+		next := zone.records[end]
+		payload.IsTruncated = true
+		payload.NextRecordName = next.Name
+		payload.NextRecordType = next.Type
+		payload.NextRecordIdentifier = next.SetIdentifier
+	}
+
+	return payload, nil
+}
+
+// ChangeResourceRecordSets applies the batch atomically, like Route53 does
+func (r *Route53) ChangeResourceRecordSets(_ context.Context, params *route53.ChangeResourceRecordSetsInput, _ ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	zone, err := r.find(params.HostedZoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	records := append([]types.ResourceRecordSet{}, zone.records...)
+	for _, change := range params.ChangeBatch.Changes {
+		rec := *change.ResourceRecordSet
+		index := -1
+		for i, existing := range records {
+			if aws.ToString(existing.Name) == aws.ToString(rec.Name) && existing.Type == rec.Type &&
+				aws.ToString(existing.SetIdentifier) == aws.ToString(rec.SetIdentifier) {
+				index = i
+				break
+			}
+		}
+
+		switch {
+		case change.Action == types.ChangeActionCreate && index >= 0:
+			return nil, invalidChangeBatch("record %s %s already exists", aws.ToString(rec.Name), rec.Type)
+		case change.Action == types.ChangeActionDelete && index < 0:
+			return nil, invalidChangeBatch("record %s %s not found", aws.ToString(rec.Name), rec.Type)
+		case change.Action == types.ChangeActionDelete:
+			records = append(records[:index], records[index+1:]...)
+		case index >= 0:
+			records[index] = rec
+		default:
+			records = append(records, rec)
+		}
+	}
+	zone.records = records
+	r.changes++
+
+	return &route53.ChangeResourceRecordSetsOutput{ChangeInfo: &types.ChangeInfo{
+		Id:     aws.String(fmt.Sprintf("/change/C%d", r.changes)),
+		Status: types.ChangeStatusInsync,
+	}}, nil
+}
+
+// CreateHostedZone creates a public zone with the apex records AddZone creates
+func (r *Route53) CreateHostedZone(_ context.Context, params *route53.CreateHostedZoneInput, _ ...func(*route53.Options)) (*route53.CreateHostedZoneOutput, error) {
+	name := aws.ToString(params.Name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	r.mu.Lock()
+	for _, zone := range r.zones {
+		if aws.ToString(zone.zone.CallerReference) == aws.ToString(params.CallerReference) {
+			r.mu.Unlock()
+			return nil, &types.HostedZoneAlreadyExists{Message: aws.String("caller reference was used before")}
+		}
+	}
+	r.mu.Unlock()
+
+	id := r.AddZone(name)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	zone, _ := r.find(aws.String(id))
+	zone.zone.CallerReference = params.CallerReference
+
+	return &route53.CreateHostedZoneOutput{
+		HostedZone:    &zone.zone,
+		DelegationSet: &types.DelegationSet{NameServers: []string{"ns-1.awsdns-01.org", "ns-2.awsdns-02.com"}},
+		ChangeInfo:    &types.ChangeInfo{Id: aws.String("/change/CZONE"), Status: types.ChangeStatusInsync},
+		Location:      aws.String("https://route53.amazonaws.com/2013-04-01/hostedzone/" + normaliseId(id)),
+	}, nil
+}
+
+func (r *Route53) find(id *string) (*hostedZone, error) {
+	for _, zone := range r.zones {
+		if normaliseId(*zone.zone.Id) == normaliseId(aws.ToString(id)) {
+			return zone, nil
+		}
+	}
+
+	return nil, &types.NoSuchHostedZone{Message: aws.String(fmt.Sprintf("no hosted zone found with id %s", aws.ToString(id)))}
+}
+
+func invalidChangeBatch(format string, args ...any) error {
+	return &smithy.GenericAPIError{Code: "InvalidChangeBatch", Message: fmt.Sprintf(format, args...)}
+}
+
+// normaliseId accepts ids with and without the /hostedzone/ prefix, like Route53 does
+func normaliseId(id string) string {
+	return strings.TrimPrefix(id, "/hostedzone/")
+}
@@ -0,0 +1,163 @@
+package fake
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/aws/smithy-go"
+)
+
+// Sts hands out credentials for every role that isn't denied, and records the calls. Besides being used as a client it
+// serves the STS query API over HTTP, recording the access key the requests are signed with, e.g. to test role chaining.
+type Sts struct {
+	// Lifetime overrides the requested duration of the credentials handed out, e.g. to test refreshing them
+	Lifetime time.Duration
+
+	mu      sync.Mutex
+	denied  map[string]bool
+	calls   []sts.AssumeRoleInput
+	callers []string
+}
+
+func NewSts() *Sts {
+	return &Sts{denied: make(map[string]bool)}
+}
+
+// Deny makes assuming the role fail with AccessDenied
+func (s *Sts) Deny(roleArn string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.denied[roleArn] = true
+}
+
+// Calls returns the AssumeRole requests received so far
+func (s *Sts) Calls() []sts.AssumeRoleInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]sts.AssumeRoleInput{}, s.calls...)
+}
+
+// Callers returns the access key ids the HTTP requests received so far were signed with, calls made directly through
+// AssumeRole are recorded with an empty caller
+func (s *Sts) Callers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.callers...)
+}
+
+func (s *Sts) AssumeRole(_ context.Context, params *sts.AssumeRoleInput, _ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	return s.assumeRole(params, "")
+}
+
+func (s *Sts) assumeRole(params *sts.AssumeRoleInput, caller string) (*sts.AssumeRoleOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, *params)
+	s.callers = append(s.callers, caller)
+	roleArn := aws.ToString(params.RoleArn)
+	if s.denied[roleArn] {
+		return nil, &smithy.GenericAPIError{Code: "AccessDenied", Message: fmt.Sprintf("not authorized to perform sts:AssumeRole on %s", roleArn)}
+	}
+
+	duration := time.Hour
+	if params.DurationSeconds != nil {
+		duration = time.Duration(*params.DurationSeconds) * time.Second
+	}
+	if s.Lifetime != 0 {
+		duration = s.Lifetime
+	}
+
+	return &sts.AssumeRoleOutput{
+		AssumedRoleUser: &types.AssumedRoleUser{Arn: params.RoleArn, AssumedRoleId: params.RoleSessionName},
+		Credentials: &types.Credentials{
+			AccessKeyId:     aws.String(fmt.Sprintf("ASIAFAKE%d", len(s.calls))),
+			SecretAccessKey: aws.String("fake"),
+			SessionToken:    aws.String(roleArn),
+			Expiration:      aws.Time(time.Now().Add(duration)),
+		},
+	}, nil
+}
+
+var signingKeyId = regexp.MustCompile(`Credential=([^/]+)/`)
+
+type assumeRoleResponse struct {
+	XMLName     xml.Name `xml:"https://sts.amazonaws.com/doc/2011-06-15/ AssumeRoleResponse"`
+	Arn         string   `xml:"AssumeRoleResult>AssumedRoleUser>Arn"`
+	RoleId      string   `xml:"AssumeRoleResult>AssumedRoleUser>AssumedRoleId"`
+	AccessKeyId string   `xml:"AssumeRoleResult>Credentials>AccessKeyId"`
+	Secret      string   `xml:"AssumeRoleResult>Credentials>SecretAccessKey"`
+	Token       string   `xml:"AssumeRoleResult>Credentials>SessionToken"`
+	Expiration  string   `xml:"AssumeRoleResult>Credentials>Expiration"`
+	RequestId   string   `xml:"ResponseMetadata>RequestId"`
+}
+
+type errorResponse struct {
+	XMLName   xml.Name `xml:"ErrorResponse"`
+	Type      string   `xml:"Error>Type"`
+	Code      string   `xml:"Error>Code"`
+	Message   string   `xml:"Error>Message"`
+	RequestId string   `xml:"RequestId"`
+}
+
+// ServeHTTP answers AssumeRole requests of the STS query API, so the fake can be used as STS endpoint of an SDK client
+func (s *Sts) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Form.Get("Action") != "AssumeRole" {
+		http.Error(w, "only AssumeRole is supported", http.StatusBadRequest)
+		return
+	}
+
+	params := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(r.Form.Get("RoleArn")),
+		RoleSessionName: aws.String(r.Form.Get("RoleSessionName")),
+	}
+	if externalId := r.Form.Get("ExternalId"); externalId != "" {
+		params.ExternalId = aws.String(externalId)
+	}
+	if seconds, err := strconv.Atoi(r.Form.Get("DurationSeconds")); err == nil {
+		params.DurationSeconds = aws.Int32(int32(seconds))
+	}
+	for i := 1; r.Form.Has(fmt.Sprintf("Tags.member.%d.Key", i)); i++ {
+		params.Tags = append(params.Tags, types.Tag{
+			Key:   aws.String(r.Form.Get(fmt.Sprintf("Tags.member.%d.Key", i))),
+			Value: aws.String(r.Form.Get(fmt.Sprintf("Tags.member.%d.Value", i))),
+		})
+	}
+
+	var caller string
+	if match := signingKeyId.FindStringSubmatch(r.Header.Get("Authorization")); match != nil {
+		caller = match[1]
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	out, err := s.assumeRole(params, caller)
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		w.WriteHeader(http.StatusForbidden)
+		xml.NewEncoder(w).Encode(errorResponse{Type: "Sender", Code: apiErr.ErrorCode(), Message: apiErr.ErrorMessage(), RequestId: "fake"})
+		return
+	}
+
+	xml.NewEncoder(w).Encode(assumeRoleResponse{
+		Arn:         aws.ToString(out.AssumedRoleUser.Arn),
+		RoleId:      aws.ToString(out.AssumedRoleUser.AssumedRoleId),
+		AccessKeyId: aws.ToString(out.Credentials.AccessKeyId),
+		Secret:      aws.ToString(out.Credentials.SecretAccessKey),
+		Token:       aws.ToString(out.Credentials.SessionToken),
+		Expiration:  out.Credentials.Expiration.UTC().Format(time.RFC3339),
+		RequestId:   "fake",
+	})
+}
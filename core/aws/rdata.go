@@ -0,0 +1,277 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// maxCharacterString is the length limit of a single character string, longer text is split over several strings
+const maxCharacterString = 255
+
+// rdataNameFields are the positions of domain names within the RDATA of a type. Route53 accepts them without trailing
+// dot, while a zone file would read such a name relative to the origin.
+var rdataNameFields = map[route53Types.RRType][]int{
+	route53Types.RRTypeCname: {0},
+	route53Types.RRTypeNs:    {0},
+	route53Types.RRTypePtr:   {0},
+	route53Types.RRTypeMx:    {1},
+	route53Types.RRTypeSrv:   {3},
+	route53Types.RRTypeSoa:   {0, 1},
+	route53Types.RRTypeNaptr: {5},
+	route53Types.RRTypeHttps: {1},
+	route53Types.RRTypeSvcb:  {1},
+}
+
+// rdataFieldCounts are the number of fields of types with a fixed layout
+var rdataFieldCounts = map[route53Types.RRType]int{
+	route53Types.RRTypeA:     1,
+	route53Types.RRTypeAaaa:  1,
+	route53Types.RRTypeCname: 1,
+	route53Types.RRTypeNs:    1,
+	route53Types.RRTypePtr:   1,
+	route53Types.RRTypeMx:    2,
+	route53Types.RRTypeSrv:   4,
+	route53Types.RRTypeSoa:   7,
+	route53Types.RRTypeCaa:   3,
+	route53Types.RRTypeNaptr: 6,
+	route53Types.RRTypeSshfp: 3,
+	route53Types.RRTypeTlsa:  4,
+	route53Types.RRTypeDs:    4,
+}
+
+// rdataSplitDataTypes end in hex data, which BIND style values may split over several fields
+var rdataSplitDataTypes = []route53Types.RRType{route53Types.RRTypeDs, route53Types.RRTypeTlsa, route53Types.RRTypeSshfp}
+
+// FormatRecordValue converts a Route53 value into zone file RDATA. Names are made absolute, and character strings are
+// quoted, escaped and split into strings of at most 255 bytes, so the result reads back the same in BIND compatible parsers.
+func FormatRecordValue(recordType route53Types.RRType, value string) (string, error) {
+	var fields []string
+
+	switch recordType {
+	case route53Types.RRTypeTxt, route53Types.RRTypeSpf:
+		texts, err := SplitCharacterStrings(value)
+		if err != nil {
+			return "", fmt.Errorf("%s value %s: %w", recordType, value, err)
+		}
+		for _, text := range texts {
+			fields = append(fields, quoteCharacterString(text, true))
+		}
+	case route53Types.RRTypeCaa:
+		fields = splitRdataFields(value)
+		if len(fields) == 3 {
+			texts, err := SplitCharacterStrings(fields[2])
+			if err != nil || len(texts) != 1 {
+				return "", fmt.Errorf("CAA value %s: the value has to be a single string", value)
+			}
+			fields[2] = quoteCharacterString(texts[0], false)
+		}
+	case route53Types.RRTypeNaptr:
+		texts, err := SplitCharacterStrings(value)
+		if err != nil {
+			return "", fmt.Errorf("NAPTR value %s: %w", value, err)
+		}
+		for i, text := range texts {
+			if i >= 2 && i <= 4 {
+				// Flags, service and regexp are character strings
+				fields = append(fields, quoteCharacterString(text, false))
+			} else {
+				fields = append(fields, text)
+			}
+		}
+	default:
+		fields = splitRdataFields(value)
+	}
+
+	if count, ok := rdataFieldCounts[recordType]; ok {
+		if len(fields) < count || (len(fields) > count && !slices.Contains(rdataSplitDataTypes, recordType)) {
+			return "", fmt.Errorf("%s value %s has %d fields, expected %d", recordType, value, len(fields), count)
+		}
+	}
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%s record has an empty value", recordType)
+	}
+
+	for _, i := range rdataNameFields[recordType] {
+		if i < len(fields) {
+			fields[i] = absoluteRdataName(fields[i])
+		}
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+// absoluteRdataName adds the trailing dot to a name. The root, ".", e.g. as HTTPS target, stays as it is.
+func absoluteRdataName(name string) string {
+	if strings.HasSuffix(name, ".") && !strings.HasSuffix(name, `\.`) {
+		return name
+	}
+	return name + "."
+}
+
+// absoluteRdataNames applies absoluteRdataName to the name fields of a value, values that can't be split are returned
+// as they are
+func absoluteRdataNames(recordType route53Types.RRType, value string) string {
+	indices, ok := rdataNameFields[recordType]
+	if !ok || recordType == route53Types.RRTypeNaptr {
+		return value
+	}
+
+	fields := splitRdataFields(value)
+	for _, i := range indices {
+		if i < len(fields) {
+			fields[i] = absoluteRdataName(fields[i])
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// splitRdataFields splits a value at whitespace outside of quotes, so quoted text stays a single field even where it
+// starts within a field, e.g. alpn="h2 h3". Escapes are kept as they are.
+func splitRdataFields(value string) []string {
+	var payload []string
+	var field strings.Builder
+	inField, quoted := false, false
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\' && i+1 < len(value):
+			field.WriteByte(c)
+			field.WriteByte(value[i+1])
+			inField = true
+			i++
+		case c == '"':
+			field.WriteByte(c)
+			inField = true
+			quoted = !quoted
+		case !quoted && (c == ' ' || c == '\t'):
+			if inField {
+				payload = append(payload, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteByte(c)
+			inField = true
+		}
+	}
+	if inField {
+		payload = append(payload, field.String())
+	}
+
+	return payload
+}
+
+// SplitCharacterStrings reads whitespace separated, optionally quoted, character strings and resolves their escapes, e.g.
+// the strings of a TXT value
+func SplitCharacterStrings(value string) ([]string, error) {
+	var payload []string
+
+	for i := 0; i < len(value); {
+		switch {
+		case value[i] == ' ' || value[i] == '\t':
+			i++
+			continue
+		case value[i] == '"':
+			text, next, err := readCharacterString(value, i+1, true)
+			if err != nil {
+				return nil, err
+			}
+			payload = append(payload, text)
+			i = next
+		default:
+			text, next, err := readCharacterString(value, i, false)
+			if err != nil {
+				return nil, err
+			}
+			payload = append(payload, text)
+			i = next
+		}
+	}
+
+	return payload, nil
+}
+
+// readCharacterString reads from start until the closing quote, or whitespace for unquoted strings, and returns the
+// position after it
+func readCharacterString(value string, start int, quoted bool) (string, int, error) {
+	var sb strings.Builder
+
+	for i := start; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quoted && c == '"':
+			return sb.String(), i + 1, nil
+		case !quoted && (c == ' ' || c == '\t'):
+			return sb.String(), i, nil
+		case c == '\\':
+			if i+3 < len(value) && isDigits(value[i+1:i+4]) {
+				code, _ := strconv.Atoi(value[i+1 : i+4])
+				if code > 255 {
+					return "", 0, fmt.Errorf("invalid escape \\%s", value[i+1:i+4])
+				}
+				sb.WriteByte(byte(code))
+				i += 3
+			} else if i+1 < len(value) {
+				sb.WriteByte(value[i+1])
+				i++
+			} else {
+				return "", 0, errors.New("value ends with a backslash")
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	if quoted {
+		return "", 0, errors.New("unterminated quoted string")
+	}
+	return sb.String(), len(value), nil
+}
+
+// quoteCharacterString escapes quotes, backslashes and control characters. With split, text longer than 255 bytes becomes
+// several quoted strings, as a single character string can't hold more.
+func quoteCharacterString(text string, split bool) string {
+	var chunks []string
+	for split && len(text) > maxCharacterString {
+		chunks = append(chunks, text[:maxCharacterString])
+		text = text[maxCharacterString:]
+	}
+	chunks = append(chunks, text)
+
+	quoted := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		var sb strings.Builder
+		sb.WriteByte('"')
+		for j := 0; j < len(chunk); j++ {
+			c := chunk[j]
+			switch {
+			case c == '"' || c == '\\':
+				sb.WriteByte('\\')
+				sb.WriteByte(c)
+			case c < 0x20 || c == 0x7f:
+				sb.WriteString(fmt.Sprintf("\\%03d", c))
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		sb.WriteByte('"')
+		quoted[i] = sb.String()
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
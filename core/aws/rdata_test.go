@@ -0,0 +1,78 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatRecordValue(t *testing.T) {
+	long := strings.Repeat("a", 300)
+
+	tests := []struct {
+		recordType route53Types.RRType
+		value      string
+		expected   string
+	}{
+		{route53Types.RRTypeA, "192.0.2.1", "192.0.2.1"},
+		{route53Types.RRTypeAaaa, "2001:db8::1", "2001:db8::1"},
+		{route53Types.RRTypeCname, "example.com", "example.com."},
+		{route53Types.RRTypeNs, "ns1.example.com.", "ns1.example.com."},
+		{route53Types.RRTypePtr, "host.example.com", "host.example.com."},
+		{route53Types.RRTypeMx, "10 mail.example.com", "10 mail.example.com."},
+		{route53Types.RRTypeSrv, "10 60 5060  sip.example.com", "10 60 5060 sip.example.com."},
+		{route53Types.RRTypeSoa, "ns-1.awsdns-01.org awsdns-hostmaster.amazon.com 1 7200 900 1209600 86400", "ns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400"},
+		{route53Types.RRTypeTxt, `"v=spf1 -all"`, `"v=spf1 -all"`},
+		{route53Types.RRTypeTxt, `"first" "second"`, `"first" "second"`},
+		{route53Types.RRTypeTxt, `"say \"hi\" \\o/"`, `"say \"hi\" \\o/"`},
+		{route53Types.RRTypeTxt, `"tab\009here"`, `"tab\009here"`},
+		{route53Types.RRTypeTxt, `unquoted`, `"unquoted"`},
+		{route53Types.RRTypeTxt, `"` + long + `"`, `"` + long[:255] + `" "` + long[255:] + `"`},
+		{route53Types.RRTypeSpf, `"v=spf1 include:example.net ~all"`, `"v=spf1 include:example.net ~all"`},
+		{route53Types.RRTypeCaa, `0 issue "letsencrypt.org"`, `0 issue "letsencrypt.org"`},
+		{route53Types.RRTypeCaa, `128 iodef mailto:security@example.com`, `128 iodef "mailto:security@example.com"`},
+		{route53Types.RRTypeNaptr, `100 50 "S" "SIP+D2U" "" _sip._udp.example.com`, `100 50 "S" "SIP+D2U" "" _sip._udp.example.com.`},
+		{route53Types.RRTypeNaptr, `100 10 "U" "E2U+sip" "!^.*$!sip:info@example.com!" .`, `100 10 "U" "E2U+sip" "!^.*$!sip:info@example.com!" .`},
+		{route53Types.RRTypeDs, "12345 13 2 49FD46E6C4B45C55D4AC", "12345 13 2 49FD46E6C4B45C55D4AC"},
+		{route53Types.RRTypeSshfp, "1 2 123456789abcdef67890123456789abcdef67890", "1 2 123456789abcdef67890123456789abcdef67890"},
+		{route53Types.RRTypeTlsa, "3 1 1 0123456789ABCDEF", "3 1 1 0123456789ABCDEF"},
+		{route53Types.RRTypeHttps, `1 . alpn="h2,h3"`, `1 . alpn="h2,h3"`},
+		{route53Types.RRTypeSvcb, `1 svc.example.com port=8443`, `1 svc.example.com. port=8443`},
+		// Values as BIND style tools write them, with extra whitespace, split hex data and quoted spaces
+		{route53Types.RRTypeCaa, `0  issue  "letsencrypt.org"`, `0 issue "letsencrypt.org"`},
+		{route53Types.RRTypeCaa, `0 issue "ca.example.net; account=1 2"`, `0 issue "ca.example.net; account=1 2"`},
+		{route53Types.RRTypeDs, "12345 13 2 49FD46E6C4B45C55 D4AC", "12345 13 2 49FD46E6C4B45C55 D4AC"},
+		{route53Types.RRTypeTlsa, "3 1 1 01234567 89ABCDEF", "3 1 1 01234567 89ABCDEF"},
+		{route53Types.RRTypeSvcb, `1 svc.example.com alpn="h2 h3" port=8443`, `1 svc.example.com. alpn="h2 h3" port=8443`},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.recordType)+" "+tt.value[:min(len(tt.value), 20)], func(t *testing.T) {
+			formatted, err := FormatRecordValue(tt.recordType, tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, formatted)
+		})
+	}
+}
+
+func TestFormatRecordValue_Errors(t *testing.T) {
+	tests := []struct {
+		recordType route53Types.RRType
+		value      string
+	}{
+		{route53Types.RRTypeMx, "mail.example.com."},
+		{route53Types.RRTypeSoa, "ns-1.awsdns-01.org. 1 7200"},
+		{route53Types.RRTypeTxt, `"unterminated`},
+		{route53Types.RRTypeCaa, `0 issue "a" "b"`},
+		{route53Types.RRTypeDs, "12345 13 2"},
+		{route53Types.RRTypeMx, "10 mail.example.com. extra"},
+		{route53Types.RRTypeCname, ""},
+	}
+
+	for _, tt := range tests {
+		_, err := FormatRecordValue(tt.recordType, tt.value)
+		assert.Error(t, err, "%s %s", tt.recordType, tt.value)
+	}
+}
@@ -0,0 +1,54 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Resources combines the clients of the services records point at
+type Resources struct {
+	s3               *s3.Client
+	cloudFront       *cloudfront.Client
+	elasticBeanstalk *elasticbeanstalk.Client
+	loadBalancers    *elasticloadbalancingv2.Client
+	classic          *elasticloadbalancing.Client
+}
+
+// NewResources returns clients for the region of cfg. S3 buckets and CloudFront distributions are listed globally,
+// whatever the region.
+func NewResources(cfg aws.Config) *Resources {
+	return &Resources{
+		s3:               s3.NewFromConfig(cfg),
+		cloudFront:       cloudfront.NewFromConfig(cfg),
+		elasticBeanstalk: elasticbeanstalk.NewFromConfig(cfg),
+		loadBalancers:    elasticloadbalancingv2.NewFromConfig(cfg),
+		classic:          elasticloadbalancing.NewFromConfig(cfg),
+	}
+}
+
+func (r *Resources) ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+	return r.s3.ListBuckets(ctx, params, optFns...)
+}
+
+func (r *Resources) ListDistributions(ctx context.Context, params *cloudfront.ListDistributionsInput, optFns ...func(*cloudfront.Options)) (*cloudfront.ListDistributionsOutput, error) {
+	return r.cloudFront.ListDistributions(ctx, params, optFns...)
+}
+
+func (r *Resources) DescribeEnvironments(ctx context.Context, params *elasticbeanstalk.DescribeEnvironmentsInput, optFns ...func(*elasticbeanstalk.Options)) (*elasticbeanstalk.DescribeEnvironmentsOutput, error) {
+	return r.elasticBeanstalk.DescribeEnvironments(ctx, params, optFns...)
+}
+
+func (r *Resources) DescribeLoadBalancers(ctx context.Context, params *elasticloadbalancingv2.DescribeLoadBalancersInput, optFns ...func(*elasticloadbalancingv2.Options)) (*elasticloadbalancingv2.DescribeLoadBalancersOutput, error) {
+	return r.loadBalancers.DescribeLoadBalancers(ctx, params, optFns...)
+}
+
+// DescribeClassicLoadBalancers lists classic load balancers, the API names the operation like the one of ELBv2
+func (r *Resources) DescribeClassicLoadBalancers(ctx context.Context, params *elasticloadbalancing.DescribeLoadBalancersInput, optFns ...func(*elasticloadbalancing.Options)) (*elasticloadbalancing.DescribeLoadBalancersOutput, error) {
+	return r.classic.DescribeLoadBalancers(ctx, params, optFns...)
+}
@@ -19,12 +19,15 @@ func (rs recordSorter) Less(i, j int) bool {
 	return string(rs[i].Type) < string(rs[j].Type)
 }
 
-func GenerateZoneFile(records []route53Types.ResourceRecordSet, zoneName string) (string, error) {
+// GenerateZoneFile writes the records as BIND zone file. Values that can't be formatted are written as comments instead
+// of failing the zone, and returned in skipped. Only the SOA record has to be valid.
+func GenerateZoneFile(records []route53Types.ResourceRecordSet, zoneName string) (string, []string, error) {
 	if len(records) == 0 {
-		return "", nil
+		return "", nil, nil
 	}
 
 	var sb strings.Builder
+	var skipped []string
 	var soaRecord *route53Types.ResourceRecordSet
 	var apexNsRecords []route53Types.ResourceRecordSet
 	var otherRecords []route53Types.ResourceRecordSet
@@ -52,22 +55,21 @@ func GenerateZoneFile(records []route53Types.ResourceRecordSet, zoneName string)
 	}
 
 	if soaRecord == nil {
-		return "", fmt.Errorf("SOA record for zone %s not found", zoneName)
+		return "", nil, fmt.Errorf("SOA record for zone %s not found", zoneName)
 	}
 
-	soaValue := *soaRecord.ResourceRecords[0].Value
-	soaParts := strings.Fields(soaValue)
-	if len(soaParts) < 7 {
-		return "", fmt.Errorf("invalid SOA record value: %s", soaValue)
+	soaValue, err := FormatRecordValue(route53Types.RRTypeSoa, *soaRecord.ResourceRecords[0].Value)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid SOA record value: %w", err)
 	}
-	defaultTTL := soaParts[6]
+	defaultTTL := strings.Fields(soaValue)[6]
 	sb.WriteString(fmt.Sprintf("$TTL %s\n", defaultTTL))
 
 	sb.WriteString(fmt.Sprintf("@\t%d\tIN\tSOA\t%s\n\n", *soaRecord.TTL, soaValue))
 
 	for _, ns := range apexNsRecords {
 		for _, val := range ns.ResourceRecords {
-			sb.WriteString(fmt.Sprintf("@\t%d\tIN\tNS\t%s\n", *ns.TTL, *val.Value))
+			sb.WriteString(fmt.Sprintf("@\t%d\tIN\tNS\t%s\n", *ns.TTL, absoluteRdataName(*val.Value)))
 		}
 	}
 	sb.WriteString("\n")
@@ -76,16 +78,19 @@ func GenerateZoneFile(records []route53Types.ResourceRecordSet, zoneName string)
 
 	for _, rec := range otherRecords {
 		for _, val := range rec.ResourceRecords {
-			formattedValue, err := formatRecordValue(string(rec.Type), *val.Value)
-			if err != nil {
-				return "", err
-			}
-
 			recordName := *rec.Name
 			if recordName == zoneName {
 				recordName = "@"
 			}
 
+			formattedValue, err := FormatRecordValue(rec.Type, *val.Value)
+			if err != nil {
+				// Keep the value for whoever restores the zone, but don't let one odd value cost the whole zone
+				skipped = append(skipped, fmt.Sprintf("%s %s", *rec.Name, err))
+				sb.WriteString(fmt.Sprintf("; invalid record skipped: %s\t%d\tIN\t%s\t%s\n", recordName, *rec.TTL, rec.Type, strings.ReplaceAll(*val.Value, "\n", " ")))
+				continue
+			}
+
 			sb.WriteString(fmt.Sprintf(
 				"%s\t%d\tIN\t%s\t%s\n",
 				recordName,
@@ -96,15 +101,5 @@ func GenerateZoneFile(records []route53Types.ResourceRecordSet, zoneName string)
 		}
 	}
 
-	return sb.String(), nil
-}
-
-func formatRecordValue(recordType, value string) (string, error) {
-	switch recordType {
-	case "TXT":
-		//return strconv.Quote(value), nil
-		return value, nil
-	default:
-		return value, nil
-	}
+	return sb.String(), skipped, nil
 }
@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"go.dfds.cloud/oops/core/config"
+)
+
+func init() {
+	config.RegisterSecretResolver("awsSecretsManager", ResolveSecretsManagerSecret)
+	config.RegisterSecretResolver("awsSsm", ResolveSsmParameter)
+}
+
+// ResolveSecretsManagerSecret reads a secret referenced as "secretId" or "secretId#jsonKey", the latter picking a key of a
+// secret stored as JSON. The secret id may be a name or an ARN.
+func ResolveSecretsManagerSecret(ctx context.Context, ref string) (string, error) {
+	secretId, jsonKey, _ := strings.Cut(ref, "#")
+
+	cfg, err := secretsConfig(ctx, secretId)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := secretsmanager.NewFromConfig(cfg).GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &secretId})
+	if err != nil {
+		return "", err
+	}
+
+	value := aws.ToString(resp.SecretString)
+	if jsonKey == "" {
+		return value, nil
+	}
+
+	var fields map[string]interface{}
+	err = json.Unmarshal([]byte(value), &fields)
+	if err != nil {
+		return "", fmt.Errorf("secret isn't a JSON object, unable to pick key %s", jsonKey)
+	}
+	field, ok := fields[jsonKey]
+	if !ok {
+		return "", fmt.Errorf("key %s not found", jsonKey)
+	}
+
+	return fmt.Sprint(field), nil
+}
+
+// ResolveSsmParameter reads a parameter by name or ARN, decrypting SecureString parameters
+func ResolveSsmParameter(ctx context.Context, ref string) (string, error) {
+	cfg, err := secretsConfig(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{Name: &ref, WithDecryption: aws.Bool(true)})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(resp.Parameter.Value), nil
+}
+
+// secretsConfig uses the region and partition of the reference if it's an ARN, the defaults otherwise
+func secretsConfig(ctx context.Context, ref string) (aws.Config, error) {
+	var override config.AwsSettings
+	if parsed, err := arn.Parse(ref); err == nil {
+		override = config.AwsSettings{Region: parsed.Region, Partition: parsed.Partition}
+	}
+
+	return NewConfigFactory(override).Load(ctx)
+}
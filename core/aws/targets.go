@@ -0,0 +1,92 @@
+package aws
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// TargetKind is the kind of AWS resource a CNAME or alias record points at
+type TargetKind string
+
+const (
+	TargetS3Bucket         TargetKind = "s3-bucket"
+	TargetCloudFront       TargetKind = "cloudfront-distribution"
+	TargetElasticBeanstalk TargetKind = "elastic-beanstalk-environment"
+	TargetLoadBalancer     TargetKind = "load-balancer"
+)
+
+// Target is an AWS resource a record points at
+type Target struct {
+	Kind TargetKind `json:"kind"`
+	// Name is the bucket name, or the lower case DNS name of distributions, environments and load balancers
+	Name string `json:"name"`
+	// Region is empty for S3 buckets and CloudFront distributions, which are looked up globally
+	Region string `json:"region,omitempty"`
+	// Host is the CNAME value or alias target the resource was derived from
+	Host string `json:"host"`
+}
+
+// s3Endpoint matches the virtual hosted, legacy regional and website endpoints of S3, capturing the bucket name
+var s3Endpoint = regexp.MustCompile(`^(.+?)\.s3(-website)?([.-][a-z0-9-]+)*\.amazonaws\.com(\.cn)?$`)
+
+// s3WebsiteAlias matches the alias target of S3 website endpoints, the bucket is named after the record
+var s3WebsiteAlias = regexp.MustCompile(`^s3-website[.-][a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// elasticBeanstalkCname matches environment CNAMEs, those created before regions were part of them are in us-east-1
+var elasticBeanstalkCname = regexp.MustCompile(`^[a-z0-9-]+\.(([a-z]{2}-[a-z]+-[0-9])\.)?elasticbeanstalk\.com$`)
+
+// loadBalancerName matches classic and application load balancers, <name>.<region>.elb.amazonaws.com, and network load
+// balancers, <name>.elb.<region>.amazonaws.com. Alias targets carry a dualstack prefix.
+var loadBalancerName = regexp.MustCompile(`^(dualstack\.)?([a-z0-9-]+\.(([a-z]{2}-[a-z]+-[0-9])\.elb|elb\.([a-z]{2}-[a-z]+-[0-9]))\.amazonaws\.com(\.cn)?)$`)
+
+// RecordTargets returns the AWS resources the CNAME or alias record points at. Other records and targets outside AWS
+// return none.
+func RecordTargets(rec route53Types.ResourceRecordSet) []Target {
+	var hosts []string
+	switch {
+	case rec.AliasTarget != nil:
+		hosts = append(hosts, aws.ToString(rec.AliasTarget.DNSName))
+	case rec.Type == route53Types.RRTypeCname:
+		for _, value := range rec.ResourceRecords {
+			hosts = append(hosts, aws.ToString(value.Value))
+		}
+	}
+
+	var payload []Target
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		target := Target{Host: host}
+
+		switch {
+		case rec.AliasTarget != nil && s3WebsiteAlias.MatchString(host):
+			target.Kind = TargetS3Bucket
+			target.Name = strings.ToLower(strings.TrimSuffix(aws.ToString(rec.Name), "."))
+		case s3Endpoint.MatchString(host):
+			target.Kind = TargetS3Bucket
+			target.Name = s3Endpoint.FindStringSubmatch(host)[1]
+		case strings.HasSuffix(host, ".cloudfront.net"):
+			target.Kind = TargetCloudFront
+			target.Name = host
+		case elasticBeanstalkCname.MatchString(host):
+			target.Kind = TargetElasticBeanstalk
+			target.Name = host
+			target.Region = elasticBeanstalkCname.FindStringSubmatch(host)[2]
+			if target.Region == "" {
+				target.Region = "us-east-1"
+			}
+		case loadBalancerName.MatchString(host):
+			match := loadBalancerName.FindStringSubmatch(host)
+			target.Kind = TargetLoadBalancer
+			target.Name = match[2]
+			target.Region = match[4] + match[5]
+		default:
+			continue
+		}
+		payload = append(payload, target)
+	}
+
+	return payload
+}
@@ -0,0 +1,38 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordTargets(t *testing.T) {
+	cname := func(value string) route53Types.ResourceRecordSet {
+		return route53Types.ResourceRecordSet{Name: aws.String("www.example.com."), Type: route53Types.RRTypeCname, ResourceRecords: []route53Types.ResourceRecord{{Value: aws.String(value)}}}
+	}
+	alias := func(target string) route53Types.ResourceRecordSet {
+		return route53Types.ResourceRecordSet{Name: aws.String("Assets.example.com."), Type: route53Types.RRTypeA, AliasTarget: &route53Types.AliasTarget{DNSName: aws.String(target)}}
+	}
+
+	tests := []struct {
+		rec      route53Types.ResourceRecordSet
+		expected []Target
+	}{
+		{cname("my.bucket.s3.eu-west-1.amazonaws.com."), []Target{{Kind: TargetS3Bucket, Name: "my.bucket", Host: "my.bucket.s3.eu-west-1.amazonaws.com"}}},
+		{cname("site.s3-website-eu-west-1.amazonaws.com"), []Target{{Kind: TargetS3Bucket, Name: "site", Host: "site.s3-website-eu-west-1.amazonaws.com"}}},
+		{alias("s3-website.eu-central-1.amazonaws.com."), []Target{{Kind: TargetS3Bucket, Name: "assets.example.com", Host: "s3-website.eu-central-1.amazonaws.com"}}},
+		{alias("D111.cloudfront.net."), []Target{{Kind: TargetCloudFront, Name: "d111.cloudfront.net", Host: "d111.cloudfront.net"}}},
+		{cname("myapp.eu-west-1.elasticbeanstalk.com."), []Target{{Kind: TargetElasticBeanstalk, Name: "myapp.eu-west-1.elasticbeanstalk.com", Region: "eu-west-1", Host: "myapp.eu-west-1.elasticbeanstalk.com"}}},
+		{cname("legacy.elasticbeanstalk.com."), []Target{{Kind: TargetElasticBeanstalk, Name: "legacy.elasticbeanstalk.com", Region: "us-east-1", Host: "legacy.elasticbeanstalk.com"}}},
+		{alias("dualstack.my-alb-123.eu-west-1.elb.amazonaws.com."), []Target{{Kind: TargetLoadBalancer, Name: "my-alb-123.eu-west-1.elb.amazonaws.com", Region: "eu-west-1", Host: "dualstack.my-alb-123.eu-west-1.elb.amazonaws.com"}}},
+		{cname("my-nlb-abc.elb.eu-central-1.amazonaws.com."), []Target{{Kind: TargetLoadBalancer, Name: "my-nlb-abc.elb.eu-central-1.amazonaws.com", Region: "eu-central-1", Host: "my-nlb-abc.elb.eu-central-1.amazonaws.com"}}},
+		{cname("example.net."), nil},
+		{route53Types.ResourceRecordSet{Name: aws.String("example.com."), Type: route53Types.RRTypeA, ResourceRecords: []route53Types.ResourceRecord{{Value: aws.String("192.0.2.1")}}}, nil},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, RecordTargets(test.rec))
+	}
+}
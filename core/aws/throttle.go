@@ -0,0 +1,62 @@
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/smithy-go/middleware"
+	"golang.org/x/time/rate"
+)
+
+// Route53RequestsPerSecond is the limit Route53 enforces per account
+const Route53RequestsPerSecond = 5
+
+// route53ThrottleCodes are the errors Route53 returns when requests come in too fast, or while a change is still pending
+var route53ThrottleCodes = map[string]struct{}{
+	"Throttling":              {},
+	"ThrottlingException":     {},
+	"PriorRequestNotComplete": {},
+}
+
+// NewRoute53Limiter returns a limiter to share between every Route53 client of one account
+func NewRoute53Limiter(requestsPerSecond float64) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(requestsPerSecond), 1)
+}
+
+// NewRoute53Client returns a client whose attempts, retries included, wait for limiter and that retries throttled requests
+// with adaptive backoff
+func NewRoute53Client(cfg aws.Config, limiter *rate.Limiter) *route53.Client {
+	return route53.NewFromConfig(cfg, func(o *route53.Options) {
+		o.Retryer = route53Retryer()
+		o.APIOptions = append(o.APIOptions, rateLimitMiddleware(limiter))
+	})
+}
+
+func route53Retryer() aws.Retryer {
+	return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+		o.Throttles = append(o.Throttles, retry.ThrottleErrorCode{Codes: route53ThrottleCodes})
+		o.StandardOptions = append(o.StandardOptions, func(so *retry.StandardOptions) {
+			so.MaxAttempts = 10
+			so.MaxBackoff = 30 * time.Second
+			so.Retryables = append(so.Retryables, retry.RetryableErrorCode{Codes: route53ThrottleCodes})
+			// The limiter already paces attempts, the retry quota would only give up on accounts with many zones
+			so.RateLimiter = ratelimit.None
+		})
+	})
+}
+
+// rateLimitMiddleware runs after the retry middleware, so every attempt waits for the limiter
+func rateLimitMiddleware(limiter *rate.Limiter) func(stack *middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("OopsRateLimit", func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			if err := limiter.Wait(ctx); err != nil {
+				return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+			}
+			return next.HandleFinalize(ctx, in)
+		}), middleware.After)
+	}
+}
@@ -0,0 +1,44 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRoute53Client_RetriesThrottling(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		switch requests.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>Throttling</Code><Message>Rate exceeded</Message></Error></ErrorResponse>`))
+		case 2:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>PriorRequestNotComplete</Code><Message>Pending</Message></Error></ErrorResponse>`))
+		default:
+			w.Write([]byte(`<ListHostedZonesResponse><HostedZones></HostedZones><IsTruncated>false</IsTruncated><MaxItems>100</MaxItems></ListHostedZonesResponse>`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:       "eu-west-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("id", "secret", ""),
+		BaseEndpoint: aws.String(server.URL),
+	}
+	limiter := NewRoute53Limiter(100)
+	client := NewRoute53Client(cfg, limiter)
+
+	_, err := client.ListHostedZones(context.Background(), &route53.ListHostedZonesInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), requests.Load())
+}
@@ -0,0 +1,367 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+)
+
+type zoneToken struct {
+	value  string
+	quoted bool
+}
+
+// zoneEntry is a logical line of a zone file, parentheses may have joined several physical lines into it
+type zoneEntry struct {
+	line       int
+	blankOwner bool
+	tokens     []zoneToken
+}
+
+// ParseZoneFile reads an RFC 1035 master file into record sets, the reverse of GenerateZoneFile. It supports $TTL, $ORIGIN,
+// @, relative names, parentheses spanning lines and quoted strings. Records with the same name and type form a single
+// record set, which takes the TTL of its first record like BIND does. Values are returned in the format Route53 uses.
+// Records of DNS types Route53 doesn't support, e.g. HINFO or DNSKEY, are skipped and described in the returned warnings.
+func ParseZoneFile(r io.Reader, origin string) ([]route53Types.ResourceRecordSet, []string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := tokenizeZoneFile(string(content))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	origin = absoluteName(origin, ".")
+	var payload []route53Types.ResourceRecordSet
+	var warnings []string
+	index := make(map[recordKey]int)
+	var defaultTtl, lastTtl *int64
+	lastOwner := ""
+
+	for _, entry := range entries {
+		tokens := entry.tokens
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s", entry.line, fmt.Sprintf(format, args...))
+		}
+
+		if !entry.blankOwner && strings.HasPrefix(tokens[0].value, "$") {
+			directive := strings.ToUpper(tokens[0].value)
+			if len(tokens) < 2 {
+				return nil, nil, fail("%s needs an argument", directive)
+			}
+			switch directive {
+			case "$ORIGIN":
+				origin = absoluteName(tokens[1].value, origin)
+			case "$TTL":
+				ttl, err := parseTtl(tokens[1].value)
+				if err != nil {
+					return nil, nil, fail("%s", err)
+				}
+				defaultTtl = &ttl
+			default:
+				return nil, nil, fail("unsupported directive %s", directive)
+			}
+			continue
+		}
+
+		owner := lastOwner
+		if !entry.blankOwner {
+			owner = absoluteName(tokens[0].value, origin)
+			tokens = tokens[1:]
+		}
+		if owner == "" {
+			return nil, nil, fail("record without owner name")
+		}
+		lastOwner = owner
+
+		// TTL and class are optional and may come in either order
+		var ttl *int64
+	prefix:
+		for i := 0; i < 2 && len(tokens) > 0 && !tokens[0].quoted; i++ {
+			parsed, err := parseTtl(tokens[0].value)
+			switch class := strings.ToUpper(tokens[0].value); {
+			case err == nil:
+				ttl = &parsed
+			case class == "IN":
+			case class == "CH" || class == "HS" || class == "CS":
+				return nil, nil, fail("unsupported class %s", class)
+			default:
+				break prefix
+			}
+			tokens = tokens[1:]
+		}
+
+		if len(tokens) < 2 {
+			return nil, nil, fail("record of %s has no type or data", owner)
+		}
+		recordType := route53Types.RRType(strings.ToUpper(tokens[0].value))
+		if !slices.Contains(recordType.Values(), recordType) {
+			if isDnsType(string(recordType)) {
+				if ttl != nil {
+					lastTtl = ttl
+				}
+				warnings = append(warnings, fmt.Sprintf("line %d: skipped %s record of %s, Route53 doesn't support the type", entry.line, recordType, owner))
+				continue
+			}
+			return nil, nil, fail("unsupported record type %s", tokens[0].value)
+		}
+
+		switch {
+		case ttl != nil:
+			lastTtl = ttl
+		case defaultTtl != nil:
+			ttl = defaultTtl
+		case lastTtl != nil:
+			ttl = lastTtl
+		default:
+			return nil, nil, fail("no TTL for %s and no $TTL set", owner)
+		}
+
+		value, err := formatZoneFileRdata(recordType, tokens[1:], origin)
+		if err != nil {
+			return nil, nil, fail("%s", err)
+		}
+
+		key := recordKey{name: owner, recordType: string(recordType)}
+		i, ok := index[key]
+		if !ok {
+			i = len(payload)
+			index[key] = i
+			payload = append(payload, route53Types.ResourceRecordSet{Name: aws.String(owner), Type: recordType, TTL: aws.Int64(*ttl)})
+		}
+		payload[i].ResourceRecords = append(payload[i].ResourceRecords, route53Types.ResourceRecord{Value: aws.String(value)})
+	}
+
+	return payload, warnings, nil
+}
+
+// VerifyZoneFile generates the zone file of the records and checks that it parses back into the same records
+func VerifyZoneFile(records []route53Types.ResourceRecordSet, zoneName string) ([]RecordChange, error) {
+	content, _, err := GenerateZoneFile(records, zoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	return CompareZoneFile(content, records, zoneName)
+}
+
+// CompareZoneFile parses a zone file and returns the record sets that differ from records. Alias records and records
+// with a routing policy can't be expressed in a zone file and are left out of the comparison, as are values
+// GenerateZoneFile skips because they can't be formatted.
+func CompareZoneFile(content string, records []route53Types.ResourceRecordSet, zoneName string) ([]RecordChange, error) {
+	parsed, _, err := ParseZoneFile(strings.NewReader(content), zoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	var expected []route53Types.ResourceRecordSet
+	routed := make(map[recordKey]bool)
+	for _, rec := range records {
+		switch {
+		case rec.AliasTarget != nil:
+		case rec.SetIdentifier != nil:
+			routed[recordKey{name: aws.ToString(rec.Name), recordType: string(rec.Type)}] = true
+		default:
+			// Names without trailing dot are the same to Route53, the zone file has them absolute
+			normalised := rec
+			normalised.ResourceRecords = nil
+			for _, value := range rec.ResourceRecords {
+				if _, err := FormatRecordValue(rec.Type, aws.ToString(value.Value)); err != nil {
+					continue
+				}
+				normalised.ResourceRecords = append(normalised.ResourceRecords, route53Types.ResourceRecord{
+					Value: aws.String(absoluteRdataNames(rec.Type, aws.ToString(value.Value))),
+				})
+			}
+			if len(normalised.ResourceRecords) > 0 {
+				expected = append(expected, normalised)
+			}
+		}
+	}
+	parsed = slices.DeleteFunc(parsed, func(rec route53Types.ResourceRecordSet) bool {
+		return routed[recordKey{name: aws.ToString(rec.Name), recordType: string(rec.Type)}]
+	})
+
+	return DiffZone("", zoneName, expected, parsed), nil
+}
+
+func tokenizeZoneFile(content string) ([]zoneEntry, error) {
+	var payload []zoneEntry
+	var entry zoneEntry
+	var token strings.Builder
+	inToken := false
+	depth := 0
+	line := 1
+
+	endToken := func() {
+		if inToken {
+			entry.tokens = append(entry.tokens, zoneToken{value: token.String()})
+			token.Reset()
+			inToken = false
+		}
+	}
+	startEntry := func(i int) {
+		entry = zoneEntry{line: line, blankOwner: i < len(content) && (content[i] == ' ' || content[i] == '\t')}
+	}
+
+	startEntry(0)
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == ';':
+			for i+1 < len(content) && content[i+1] != '\n' {
+				i++
+			}
+		case c == '"' && inToken:
+			// Quotes within a token are kept, e.g. in SVCB parameters like alpn="h2,h3"
+			end := strings.IndexAny(content[i+1:], "\"\n")
+			if end < 0 || content[i+1+end] != '"' {
+				return nil, fmt.Errorf("line %d: unterminated quoted string", line)
+			}
+			token.WriteString(content[i : i+end+2])
+			i += end + 1
+		case c == '"':
+			var quoted strings.Builder
+			closed := false
+			for i++; i < len(content); i++ {
+				if content[i] == '\\' && i+1 < len(content) {
+					quoted.WriteByte(content[i])
+					i++
+				} else if content[i] == '"' {
+					closed = true
+					break
+				} else if content[i] == '\n' {
+					break
+				}
+				quoted.WriteByte(content[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("line %d: unterminated quoted string", line)
+			}
+			entry.tokens = append(entry.tokens, zoneToken{value: quoted.String(), quoted: true})
+		case c == '(':
+			endToken()
+			depth++
+		case c == ')':
+			endToken()
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("line %d: unbalanced parentheses", line)
+			}
+		case c == '\n':
+			endToken()
+			line++
+			if depth == 0 {
+				if len(entry.tokens) > 0 {
+					payload = append(payload, entry)
+				}
+				startEntry(i + 1)
+			}
+		case c == '\r' || unicode.IsSpace(rune(c)):
+			endToken()
+		case c == '\\' && i+1 < len(content):
+			// Escapes are kept as they are, Route53 uses the same escaping in names and values
+			token.WriteByte(c)
+			token.WriteByte(content[i+1])
+			inToken = true
+			i++
+		default:
+			token.WriteByte(c)
+			inToken = true
+		}
+	}
+	endToken()
+
+	if depth != 0 {
+		return nil, errors.New("unbalanced parentheses at end of file")
+	}
+	if len(entry.tokens) > 0 {
+		payload = append(payload, entry)
+	}
+
+	return payload, nil
+}
+
+// formatZoneFileRdata completes relative names with the origin, and then formats the value the way GenerateZoneFile does,
+// which is also the format Route53 uses
+func formatZoneFileRdata(recordType route53Types.RRType, tokens []zoneToken, origin string) (string, error) {
+	fields := make([]string, len(tokens))
+	for i, token := range tokens {
+		fields[i] = token.value
+		if token.quoted {
+			fields[i] = `"` + token.value + `"`
+		}
+	}
+
+	for _, i := range rdataNameFields[recordType] {
+		if i < len(tokens) && !tokens[i].quoted {
+			fields[i] = absoluteName(fields[i], origin)
+		}
+	}
+
+	return FormatRecordValue(recordType, strings.Join(fields, " "))
+}
+
+// isDnsType reports whether the upper case name is a DNS record type, known by name or in the generic TYPE<number> form
+func isDnsType(name string) bool {
+	if _, ok := dns.StringToType[name]; ok {
+		return true
+	}
+	number, ok := strings.CutPrefix(name, "TYPE")
+	_, err := strconv.ParseUint(number, 10, 16)
+	return ok && err == nil
+}
+
+// absoluteName completes a relative name with the origin
+func absoluteName(name string, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, ".") && !strings.HasSuffix(name, `\.`):
+		return name
+	case origin == ".":
+		return name + "."
+	default:
+		return name + "." + origin
+	}
+}
+
+// parseTtl reads a TTL in seconds or with BIND's units, like 1h30m
+func parseTtl(value string) (int64, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+		return seconds, nil
+	}
+
+	units := map[byte]int64{'s': 1, 'm': 60, 'h': 3600, 'd': 86400, 'w': 604800}
+	var payload, current int64
+	digits := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= '0' && c <= '9':
+			current = current*10 + int64(c-'0')
+			digits = true
+		case digits && units[byte(unicode.ToLower(rune(c)))] > 0:
+			payload += current * units[byte(unicode.ToLower(rune(c)))]
+			current = 0
+			digits = false
+		default:
+			return 0, fmt.Errorf("invalid TTL %s", value)
+		}
+	}
+	if digits || value == "" {
+		return 0, fmt.Errorf("invalid TTL %s", value)
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,171 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dfds.cloud/oops/core/aws/fake"
+)
+
+func TestParseZoneFile(t *testing.T) {
+	content := `$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns-1.awsdns-01.org. hostmaster (
+		1       ; serial
+		7200    ; refresh
+		900 1209600 86400 )
+	IN	NS	ns-1.awsdns-01.org.
+	IN	NS	ns-2.awsdns-02.com.
+www	300	IN	CNAME	@
+mail	IN	300	MX	10 mx1
+txt		TXT	"v=spf1 include:_spf.example.net -all" "second; string" unquoted
+esc		TXT	"say \"hi\""
+caa		CAA	0 issue "letsencrypt.org"
+$ORIGIN sub.example.com.
+srv	5m	SRV	0 5 443 target
+`
+
+	records, warnings, err := ParseZoneFile(strings.NewReader(content), "example.com")
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	assert.Equal(t, []route53Types.ResourceRecordSet{
+		fake.Record("example.com.", route53Types.RRTypeSoa, 3600, "ns-1.awsdns-01.org. hostmaster.example.com. 1 7200 900 1209600 86400"),
+		fake.Record("example.com.", route53Types.RRTypeNs, 3600, "ns-1.awsdns-01.org.", "ns-2.awsdns-02.com."),
+		fake.Record("www.example.com.", route53Types.RRTypeCname, 300, "example.com."),
+		fake.Record("mail.example.com.", route53Types.RRTypeMx, 300, "10 mx1.example.com."),
+		fake.Record("txt.example.com.", route53Types.RRTypeTxt, 3600, `"v=spf1 include:_spf.example.net -all" "second; string" "unquoted"`),
+		fake.Record("esc.example.com.", route53Types.RRTypeTxt, 3600, `"say \"hi\""`),
+		fake.Record("caa.example.com.", route53Types.RRTypeCaa, 3600, `0 issue "letsencrypt.org"`),
+		fake.Record("srv.sub.example.com.", route53Types.RRTypeSrv, 300, "0 5 443 target.sub.example.com."),
+	}, records)
+}
+
+func TestParseZoneFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"no ttl", "www IN A 192.0.2.1\n", "line 1: no TTL"},
+		{"unbalanced", "$TTL 300\n@ SOA a. b. ( 1 2 3 4 5\n", "unbalanced parentheses"},
+		{"unterminated", "$TTL 300\ntxt TXT \"open\n", "line 2: unterminated quoted string"},
+		{"unknown type", "$TTL 300\nwww IN BOGUS x\n", "line 2: unsupported record type BOGUS"},
+		{"include", "$INCLUDE other.zone\n", "unsupported directive $INCLUDE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseZoneFile(strings.NewReader(tt.content), "example.com.")
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestParseZoneFile_UnsupportedTypes(t *testing.T) {
+	content := `$ORIGIN example.com.
+host	600	HINFO	"x86" "linux"
+	A	192.0.2.1
+@	DNSKEY	257 3 13 aGVsbG8=
+www	TYPE65280	\# 2 abcd
+`
+
+	records, warnings, err := ParseZoneFile(strings.NewReader(content), "example.com")
+	require.NoError(t, err)
+
+	// The TTL of a skipped record still carries over to the next record without one
+	assert.Equal(t, []route53Types.ResourceRecordSet{
+		fake.Record("host.example.com.", route53Types.RRTypeA, 600, "192.0.2.1"),
+	}, records)
+	assert.Equal(t, []string{
+		"line 2: skipped HINFO record of host.example.com., Route53 doesn't support the type",
+		"line 4: skipped DNSKEY record of example.com., Route53 doesn't support the type",
+		"line 5: skipped TYPE65280 record of www.example.com., Route53 doesn't support the type",
+	}, warnings)
+}
+
+// TestZoneFileRoundTrip is the harness for zone files as restore source: whatever GenerateZoneFile writes has to parse
+// back into the same records
+func TestZoneFileRoundTrip(t *testing.T) {
+	soa := fake.Record("example.com.", route53Types.RRTypeSoa, 900, "ns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400")
+	ns := fake.Record("example.com.", route53Types.RRTypeNs, 172800, "ns-1.awsdns-01.org.", "ns-2.awsdns-02.com.")
+
+	weighted := fake.Record("weighted.example.com.", route53Types.RRTypeA, 60, "192.0.2.10")
+	weighted.SetIdentifier = aws.String("blue")
+	weighted.Weight = aws.Int64(10)
+
+	alias := route53Types.ResourceRecordSet{Name: aws.String("cdn.example.com."), Type: route53Types.RRTypeA, AliasTarget: &route53Types.AliasTarget{
+		DNSName:      aws.String("d111111abcdef8.cloudfront.net."),
+		HostedZoneId: aws.String("Z2FDTNDATAQYW2"),
+	}}
+
+	tests := []struct {
+		name    string
+		records []route53Types.ResourceRecordSet
+	}{
+		{"apex only", []route53Types.ResourceRecordSet{soa, ns}},
+		{"common types", []route53Types.ResourceRecordSet{soa, ns,
+			fake.Record("example.com.", route53Types.RRTypeA, 300, "192.0.2.1", "192.0.2.2"),
+			fake.Record("example.com.", route53Types.RRTypeAaaa, 300, "2001:db8::1"),
+			fake.Record("example.com.", route53Types.RRTypeMx, 3600, "10 mx1.example.com.", "20 mx2.example.com."),
+			fake.Record("www.example.com.", route53Types.RRTypeCname, 300, "example.com."),
+			fake.Record("_sip._tcp.example.com.", route53Types.RRTypeSrv, 300, "10 60 5060 sip.example.com."),
+			fake.Record("sub.example.com.", route53Types.RRTypeNs, 300, "ns1.example.net."),
+			fake.Record("example.com.", route53Types.RRTypeCaa, 300, `0 issue "amazon.com"`),
+		}},
+		{"txt", []route53Types.ResourceRecordSet{soa, ns,
+			fake.Record("example.com.", route53Types.RRTypeTxt, 300, `"v=spf1 include:amazonses.com -all"`, `"google-site-verification=abc"`),
+			fake.Record("split.example.com.", route53Types.RRTypeTxt, 300, `"first part" "second part"`),
+		}},
+		{"every type", []route53Types.ResourceRecordSet{soa, ns,
+			fake.Record("relative.example.com.", route53Types.RRTypeCname, 300, "example.com"),
+			fake.Record("example.com.", route53Types.RRTypeSpf, 300, `"v=spf1 -all"`),
+			fake.Record("escaped.example.com.", route53Types.RRTypeTxt, 300, `"say \"hi\" \\o/"`),
+			fake.Record("long.example.com.", route53Types.RRTypeTxt, 300, `"`+strings.Repeat("a", 255)+`" "`+strings.Repeat("b", 100)+`"`),
+			fake.Record("example.com.", route53Types.RRTypeCaa, 300, `0 iodef "mailto:security@example.com"`),
+			fake.Record("sip.example.com.", route53Types.RRTypeNaptr, 300, `100 50 "S" "SIP+D2U" "" _sip._udp.example.com.`),
+			fake.Record("secure.example.com.", route53Types.RRTypeDs, 300, "12345 13 2 49FD46E6C4B45C55D4AC"),
+			fake.Record("host.example.com.", route53Types.RRTypeSshfp, 300, "1 2 123456789abcdef67890123456789abcdef67890"),
+			fake.Record("_443._tcp.example.com.", route53Types.RRTypeTlsa, 300, "3 1 1 0123456789ABCDEF"),
+			fake.Record("example.com.", route53Types.RRTypeHttps, 300, `1 . alpn="h2,h3"`),
+			fake.Record("_svc.example.com.", route53Types.RRTypeSvcb, 300, "1 svc.example.com. port=8443"),
+			fake.Record("1.2.0.192.example.com.", route53Types.RRTypePtr, 300, "host.example.com."),
+		}},
+		{"wildcard", []route53Types.ResourceRecordSet{soa, ns,
+			fake.Record(`\052.example.com.`, route53Types.RRTypeA, 300, "192.0.2.3"),
+		}},
+		{"alias and routing policies are left out", []route53Types.ResourceRecordSet{soa, ns, weighted, alias}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := VerifyZoneFile(tt.records, "example.com")
+			require.NoError(t, err)
+			assert.Empty(t, changes)
+		})
+	}
+}
+
+func TestGenerateZoneFile_InvalidValue(t *testing.T) {
+	records := []route53Types.ResourceRecordSet{
+		fake.Record("example.com.", route53Types.RRTypeSoa, 900, "ns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400"),
+		fake.Record("example.com.", route53Types.RRTypeNs, 172800, "ns-1.awsdns-01.org."),
+		fake.Record("example.com.", route53Types.RRTypeMx, 3600, "10 mx1.example.com.", "mx2.example.com."),
+	}
+
+	zone, skipped, err := GenerateZoneFile(records, "example.com")
+	require.NoError(t, err)
+	assert.Len(t, skipped, 1)
+	assert.Contains(t, skipped[0], "example.com.")
+	assert.Contains(t, zone, "; invalid record skipped: @\t3600\tIN\tMX\tmx2.example.com.\n")
+	assert.Contains(t, zone, "mx1.example.com.")
+
+	// The skipped value isn't reported as a difference between Route53 and the zone file
+	changes, err := VerifyZoneFile(records, "example.com")
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
@@ -1,11 +1,13 @@
 package config
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"os"
+	"fmt"
 	"strings"
+	"time"
 
-	"github.com/kelseyhightower/envconfig"
 	selfserviceapi "go.dfds.cloud/oops/core/ssu/selfservice-api"
 )
 
@@ -23,13 +25,118 @@ type Config struct {
 		Operator  bool `json:"operator" default:"true"`
 	} `json:"enable"`
 	SelfserviceApi selfserviceapi.Config `json:"selfserviceApi"`
-	Job            struct {
+	Auth           struct {
+		Enabled bool `json:"enabled"`
+		// AnonymousPermissions are granted to every request while auth is disabled, space separated
+		AnonymousPermissions string `json:"anonymousPermissions" default:"backups:read jobs:read"`
+		TenantId             string `json:"tenantId"`
+		Audience             string `json:"audience"`
+		// Issuer and JwksUrl default to the Azure AD v2.0 endpoints of TenantId
+		Issuer  string `json:"issuer"`
+		JwksUrl string `json:"jwksUrl"`
+		// RolePermissions maps app roles or group ids to permissions, e.g. "Oops.Reader=backups:read,Oops.Operator=backups:read jobs:trigger,Oops.Admin=*"
+		RolePermissions string `json:"rolePermissions"`
+		// CapabilityScoping lets capability members without backup permissions access backups of their capability's AWS account
+		CapabilityScoping  bool          `json:"capabilityScoping"`
+		CapabilityCacheTtl time.Duration `json:"capabilityCacheTtl" default:"5m"`
+	} `json:"auth"`
+	// Aws holds the defaults for AWS calls, jobs and backup locations can override them
+	Aws struct {
+		Region    string `json:"region" default:"eu-west-1"`
+		Partition string `json:"partition" default:"aws"`
+		// StsEndpoint is regional, global or the URL of e.g. a VPC endpoint
+		StsEndpoint string `json:"stsEndpoint" default:"regional"`
+	} `json:"aws"`
+	// Job holds the settings of each job. Enable and Interval schedule the job, also as SSU_OOPS_JOB_<NAME>_ENABLE and
+	// SSU_OOPS_JOB_<NAME>_INTERVAL, jobs can be run through the API either way.
+	Job struct {
+		Dummy struct {
+			Enable   bool          `json:"enable"`
+			Interval time.Duration `json:"interval" default:"3m"`
+		} `json:"dummy"`
 		Route53Backup struct {
-			AssumeRole string `json:"assumeRole"`
-			Accounts   string `json:"accounts"`
+			Enable     bool          `json:"enable"`
+			Interval   time.Duration `json:"interval" default:"1440m"`
+			AssumeRole string        `json:"assumeRole"`
+			// HubRoleArn is assumed before AssumeRole in every account, for when only the hub role is trusted there
+			HubRoleArn      string            `json:"hubRoleArn"`
+			ExternalId      string            `json:"externalId"`
+			SessionDuration time.Duration     `json:"sessionDuration"`
+			SessionTags     map[string]string `json:"sessionTags"`
+			Accounts        string            `json:"accounts"`
+			Aws             AwsSettings       `json:"aws"`
+			// RequestsPerSecond is shared by the zones of an account, Route53 allows 5 per account
+			RequestsPerSecond float64 `json:"requestsPerSecond" default:"5"`
+			ZoneConcurrency   int     `json:"zoneConcurrency" default:"4"`
+			// Exports are rendered into every backup next to the zone files, e.g. terraform. The formats are registered and
+			// validated by the export package.
+			Exports []string `json:"exports"`
+			// Lint checks the fetched records for takeover risks and misconfigurations, findings are stored as lint.json
+			Lint struct {
+				Enabled bool `json:"enabled" default:"true"`
+				// ProbeTargets looks up S3 buckets, CloudFront distributions, name servers and SPF includes outside the
+				// backup, to find records pointing at resources that no longer exist
+				ProbeTargets bool          `json:"probeTargets" default:"true"`
+				ProbeTimeout time.Duration `json:"probeTimeout" default:"5s"`
+				MaxTtl       time.Duration `json:"maxTtl" default:"48h"`
+			} `json:"lint"`
+			// Delegations compares the NS records delegating subzones with the name servers of the subzones, issues are
+			// stored as delegations.json grouped by capability
+			Delegations struct {
+				Enabled bool `json:"enabled" default:"true"`
+				// QueryServers asks the delegated name servers for the subzones, to find lame delegations
+				QueryServers bool          `json:"queryServers" default:"true"`
+				QueryTimeout time.Duration `json:"queryTimeout" default:"3s"`
+			} `json:"delegations"`
 		} `json:"route53Backup"`
+		// TakeoverScan lists the S3 buckets, CloudFront distributions, Elastic Beanstalk environments and load balancers
+		// of every account with the role of route53Backup, which needs s3:ListAllMyBuckets, cloudfront:ListDistributions,
+		// elasticbeanstalk:DescribeEnvironments and elasticloadbalancing:DescribeLoadBalancers for it
+		TakeoverScan struct {
+			Enable   bool          `json:"enable"`
+			Interval time.Duration `json:"interval" default:"1440m"`
+			// Probe looks up resources missing from our accounts publicly, telling names anyone can claim apart from
+			// resources of third parties
+			Probe        bool          `json:"probe" default:"true"`
+			ProbeTimeout time.Duration `json:"probeTimeout" default:"5s"`
+		} `json:"takeoverScan"`
+		BackupStaleness struct {
+			Enable   bool          `json:"enable"`
+			Interval time.Duration `json:"interval" default:"60m"`
+			// Threshold is the age after which the newest backup in a location is considered stale
+			Threshold time.Duration `json:"threshold" default:"26h"`
+		} `json:"backupStaleness"`
 	} `json:"job"`
+	RunHistory struct {
+		// Store is one of memory, file or configmap
+		Store         string `json:"store" default:"memory"`
+		Retention     int    `json:"retention" default:"20"`
+		Path          string `json:"path" default:"runs.json"`
+		Namespace     string `json:"namespace"`
+		ConfigMapName string `json:"configMapName" default:"oops-run-history"`
+	} `json:"runHistory"`
+	Reload struct {
+		// Enabled polls the files in AdditionalConfigPath, applying backup locations, notifications and job settings without a restart
+		Enabled  bool          `json:"enabled"`
+		Interval time.Duration `json:"interval" default:"30s"`
+	} `json:"reload"`
+	SecondaryDns struct {
+		// Enabled serves the public zones of the latest backup authoritatively, for when Route53 can't be reached
+		Enabled bool   `json:"enabled"`
+		Listen  string `json:"listen" default:":5353"`
+		// Location is the backup location zones are loaded from, the first enabled location if empty
+		Location        string        `json:"location"`
+		RefreshInterval time.Duration `json:"refreshInterval" default:"5m"`
+		// AllowTransfer lists the addresses or networks allowed to transfer zones, e.g. 192.0.2.0/24. IXFR requests are
+		// answered with the whole zone, like AXFR.
+		AllowTransfer []string `json:"allowTransfer"`
+		// Notify lists secondaries, as host:port, told about new zone versions after a reload
+		Notify []string `json:"notify"`
+		// NameServers replace the Route53 name servers in the apex NS and SOA records, for when the delegation points here
+		NameServers []string `json:"nameServers"`
+	} `json:"secondaryDns"`
 	BackupLocations []BackupLocation `json:"backupLocations"`
+	Notifications   Notifications    `json:"notifications"`
 }
 
 func (c *Config) Route53AwsAccounts() []string {
@@ -37,6 +144,32 @@ func (c *Config) Route53AwsAccounts() []string {
 	return strings.Split(buf, ",")
 }
 
+// AwsSettings override the AWS defaults of Config.Aws where set
+type AwsSettings struct {
+	Region      string `json:"region"`
+	Partition   string `json:"partition"`
+	StsEndpoint string `json:"stsEndpoint"`
+}
+
+// AwsDefaults returns the AWS defaults as settings
+func (c *Config) AwsDefaults() AwsSettings {
+	return AwsSettings{Region: c.Aws.Region, Partition: c.Aws.Partition, StsEndpoint: c.Aws.StsEndpoint}
+}
+
+// Merge returns the settings with the non-empty fields of override applied
+func (s AwsSettings) Merge(override AwsSettings) AwsSettings {
+	if override.Region != "" {
+		s.Region = override.Region
+	}
+	if override.Partition != "" {
+		s.Partition = override.Partition
+	}
+	if override.StsEndpoint != "" {
+		s.StsEndpoint = override.StsEndpoint
+	}
+	return s
+}
+
 type BackupLocation struct {
 	Name     string                 `json:"name"`
 	Provider string                 `json:"provider"`
@@ -44,42 +177,64 @@ type BackupLocation struct {
 	Spec     map[string]interface{} `json:"spec"`
 }
 
-const APP_CONF_PREFIX = "SSU_OOPS"
-
-func LoadConfig() (Config, error) {
-	var conf Config
-	err := envconfig.Process(APP_CONF_PREFIX, &conf)
+type Notifications struct {
+	Sinks []NotificationSink `json:"sinks"`
+	// Templates overrides the subject and body templates per event type, keyed by "<event>.subject" and "<event>.body"
+	Templates map[string]string `json:"templates"`
+}
 
-	return conf, err
+type NotificationSink struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	Enabled  bool   `json:"enabled"`
+	// Events limits the sink to the listed event types, all events are sent if empty
+	Events []string               `json:"events"`
+	Spec   map[string]interface{} `json:"spec"`
 }
 
-func LoadConfigFromJsonFile(path string) (*Config, error) {
-	var conf *Config
+const APP_CONF_PREFIX = "SSU_OOPS"
+
+// LocationSpecToType decodes the spec of a backup location, resolving secret references first
+func LocationSpecToType[T any](location BackupLocation) (*T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
 
-	buf, err := os.ReadFile(path)
+	spec, err := ResolveSecretRefs(ctx, location.Spec)
 	if err != nil {
-		return conf, err
+		return nil, fmt.Errorf("backup location %s: %w", location.Name, err)
 	}
 
-	err = json.Unmarshal(buf, &conf)
+	return SpecToType[T](spec)
+}
+
+// SinkSpecToType decodes the spec of a notification sink, resolving secret references first
+func SinkSpecToType[T any](sink NotificationSink) (*T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+
+	spec, err := ResolveSecretRefs(ctx, sink.Spec)
 	if err != nil {
-		return conf, err
+		return nil, fmt.Errorf("notification sink %s: %w", sink.Name, err)
 	}
 
-	return conf, nil
+	return SpecToType[T](spec)
 }
 
-func LocationSpecToType[T any](location BackupLocation) (*T, error) {
-	serialised, err := json.Marshal(location.Spec)
+// SpecToType decodes a spec into T, rejecting keys T doesn't have so misspelled settings don't go unnoticed
+func SpecToType[T any](spec map[string]interface{}) (*T, error) {
+	serialised, err := json.Marshal(spec)
 	if err != nil {
 		return nil, err
 	}
 
-	var payload *T
-	err = json.Unmarshal(serialised, &payload)
+	// Decode into a value, a missing spec serialises to null and would otherwise leave a nil pointer
+	var payload T
+	decoder := json.NewDecoder(bytes.NewReader(serialised))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&payload)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid spec: %w", err)
 	}
 
-	return payload, nil
+	return &payload, nil
 }
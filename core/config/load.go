@@ -0,0 +1,155 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"sigs.k8s.io/yaml"
+)
+
+const DefaultAdditionalConfigPath = "conf.json"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// LoadConfig resolves the configuration from, in increasing order of precedence:
+//
+//  1. the defaults declared on Config
+//  2. the JSON or YAML files listed in AdditionalConfigPath, separated by commas, later files taking precedence
+//  3. SSU_OOPS_* environment variables, e.g. SSU_OOPS_JOB_ROUTE53BACKUP_ASSUMEROLE
+//
+// AdditionalConfigPath itself can only be set through the environment and defaults to conf.json, which may be absent.
+// Keys in the files are the json names of the fields. Unknown keys are rejected, as is a config failing Validate.
+func LoadConfig() (Config, error) {
+	var conf Config
+	err := envconfig.Process(APP_CONF_PREFIX, &conf)
+	if err != nil {
+		return conf, err
+	}
+
+	_, explicitPath := os.LookupEnv(APP_CONF_PREFIX + "_ADDITIONALCONFIGPATH")
+	if !explicitPath {
+		conf.AdditionalConfigPath = DefaultAdditionalConfigPath
+	}
+
+	for _, path := range configPaths(conf.AdditionalConfigPath) {
+		buf, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) && !explicitPath {
+			continue
+		}
+		if err != nil {
+			return conf, err
+		}
+
+		err = mergeConfigFile(&conf, path, buf)
+		if err != nil {
+			return conf, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	err = conf.Validate()
+	if err != nil {
+		return conf, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return conf, nil
+}
+
+// mergeConfigFile decodes the file onto conf, leaving out values overridden by environment variables
+func mergeConfigFile(conf *Config, path string, buf []byte) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		converted, err := yaml.YAMLToJSON(buf)
+		if err != nil {
+			return err
+		}
+		buf = converted
+	}
+
+	var raw map[string]interface{}
+	err := json.Unmarshal(buf, &raw)
+	if err != nil {
+		return err
+	}
+
+	err = prepareFileValues(reflect.TypeOf(*conf), raw, APP_CONF_PREFIX, "")
+	if err != nil {
+		return err
+	}
+
+	serialised, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(serialised))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(conf)
+}
+
+// prepareFileValues walks the decoded file along the struct type. Keys with a matching environment variable, named the way
+// envconfig names them, are removed so the environment takes precedence. Durations are converted from strings like "5m".
+func prepareFileValues(t reflect.Type, raw map[string]interface{}, envPrefix string, path string) error {
+	for key, value := range raw {
+		field, ok := fieldByJsonName(t, key)
+		if !ok {
+			return fmt.Errorf("unknown config key %s%s", path, key)
+		}
+
+		envKey := strings.ToUpper(envPrefix + "_" + field.Name)
+		if _, ok := os.LookupEnv(envKey); ok {
+			delete(raw, key)
+			continue
+		}
+
+		switch {
+		case field.Type == durationType:
+			str, ok := value.(string)
+			if !ok {
+				continue
+			}
+			d, err := time.ParseDuration(str)
+			if err != nil {
+				return fmt.Errorf("%s%s: %w", path, key, err)
+			}
+			raw[key] = int64(d)
+		case field.Type.Kind() == reflect.Struct:
+			nested, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s%s must be an object", path, key)
+			}
+			err := prepareFileValues(field.Type, nested, envKey, path+key+".")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// fieldByJsonName finds the field decoded from the key, matching case-insensitively like encoding/json does
+func fieldByJsonName(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	jsonPath := writeConfigFile(t, "conf.json", `{
+		"logLevel": "warn",
+		"job": {"route53Backup": {"assumeRole": "from-file", "accounts": "111111111111"}},
+		"runHistory": {"retention": 5},
+		"backupLocations": [{"name": "primary", "provider": "s3", "enabled": true, "spec": {"bucket": "backups"}}]
+	}`)
+	yamlPath := writeConfigFile(t, "override.yaml", `
+job:
+  backupStaleness:
+    threshold: 48h
+runHistory:
+  retention: 7
+`)
+
+	t.Setenv("SSU_OOPS_ADDITIONALCONFIGPATH", jsonPath+","+yamlPath)
+	t.Setenv("SSU_OOPS_JOB_ROUTE53BACKUP_ASSUMEROLE", "from-env")
+
+	conf, err := LoadConfig()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "warn", conf.LogLevel)
+	assert.Equal(t, "from-env", conf.Job.Route53Backup.AssumeRole)
+	assert.Equal(t, "111111111111", conf.Job.Route53Backup.Accounts)
+	assert.Equal(t, 48*time.Hour, conf.Job.BackupStaleness.Threshold)
+	assert.Equal(t, 7, conf.RunHistory.Retention)
+	// Defaults apply to everything not set in the files or environment
+	assert.Equal(t, "memory", conf.RunHistory.Store)
+	assert.Equal(t, 5*time.Minute, conf.Auth.CapabilityCacheTtl)
+	assert.Len(t, conf.BackupLocations, 1)
+	assert.Equal(t, "backups", conf.BackupLocations[0].Spec["bucket"])
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"unknown key", `{"job": {"route53Backup": {"assumeRoel": "x"}}}`, "unknown config key job.route53Backup.assumeRoel"},
+		{"invalid duration", `{"auth": {"capabilityCacheTtl": "5 minutes"}}`, "auth.capabilityCacheTtl"},
+		{"missing assume role", `{"job": {"route53Backup": {"accounts": "111111111111"}}}`, "job.route53Backup.assumeRole is required"},
+		{"unknown partition", `{"aws": {"partition": "aws-mars"}}`, "aws.partition must be one of"},
+		{"global sts outside aws", `{"job": {"route53Backup": {"aws": {"partition": "aws-cn", "stsEndpoint": "global"}}}}`, "job.route53Backup.aws.stsEndpoint global is only available"},
+		{"global sts in default partition", `{"aws": {"partition": "aws-cn"}, "job": {"route53Backup": {"aws": {"stsEndpoint": "global"}}}}`, "job.route53Backup.aws.stsEndpoint global is only available"},
+		{"chained session too long", `{"job": {"route53Backup": {"hubRoleArn": "arn:aws:iam::999999999999:role/hub", "sessionDuration": "2h"}}}`, "job.route53Backup.sessionDuration must be between 15m and 1h0m0s"},
+		{"enabled job without interval", `{"job": {"takeoverScan": {"enable": true, "interval": "0s"}}}`, "job.takeoverScan.interval must be positive"},
+		{"invalid transfer network", `{"secondaryDns": {"enabled": true, "allowTransfer": ["192.0.2.0/33"]}}`, "secondaryDns.allowTransfer 192.0.2.0/33 is neither"},
+		{"duplicate location", `{"backupLocations": [{"name": "a", "provider": "s3"}, {"name": "a", "provider": "s3"}]}`, "backup location a is defined more than once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SSU_OOPS_ADDITIONALCONFIGPATH", writeConfigFile(t, "conf.json", tt.content))
+
+			_, err := LoadConfig()
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoadConfig_MissingFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	// The default conf.json is optional
+	_, err := LoadConfig()
+	assert.NoError(t, err)
+
+	t.Setenv("SSU_OOPS_ADDITIONALCONFIGPATH", "missing.json")
+	_, err = LoadConfig()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
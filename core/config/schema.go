@@ -0,0 +1,130 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// Schema is a JSON Schema document
+type Schema map[string]interface{}
+
+// SchemaOf generates the JSON Schema of a spec type from its json tags. Fields can be annotated further with
+// `required:"true"`, `enum:"a,b"` and `description:"..."`. Unknown keys are disallowed, matching SpecToType.
+func SchemaOf[T any]() Schema {
+	var value T
+	payload := schemaOf(reflect.TypeOf(value))
+	payload["$schema"] = schemaDialect
+	return payload
+}
+
+func schemaOf(t reflect.Type) Schema {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == secretType:
+		return Schema{"oneOf": []interface{}{Schema{"type": "string"}, secretRefSchema()}}
+	case t == durationType:
+		// Config files take strings like "5m", specs only decode nanoseconds
+		return Schema{"type": []interface{}{"string", "integer"}}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	default:
+		// interface{} and the like accept anything
+		return Schema{}
+	}
+}
+
+func structSchema(t reflect.Type) Schema {
+	properties := Schema{}
+	required := []interface{}{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemaOf(field.Type)
+		if enum := field.Tag.Get("enum"); enum != "" {
+			var values []interface{}
+			for _, value := range strings.Split(enum, ",") {
+				values = append(values, value)
+			}
+			// Enums of lists constrain their items
+			if items, ok := property["items"].(Schema); ok {
+				items["enum"] = values
+			} else {
+				property["enum"] = values
+			}
+		}
+		if description := field.Tag.Get("description"); description != "" {
+			property["description"] = description
+		}
+		if field.Tag.Get("required") == "true" {
+			required = append(required, name)
+		}
+
+		properties[name] = property
+	}
+
+	payload := Schema{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		payload["required"] = required
+	}
+	return payload
+}
+
+func secretRefSchema() Schema {
+	kinds := Schema{}
+	for _, kind := range SecretRefKinds() {
+		kinds[kind] = Schema{"type": "string"}
+	}
+
+	return Schema{
+		"type": "object",
+		"properties": Schema{
+			"secretRef": Schema{
+				"type":                 "object",
+				"properties":           kinds,
+				"additionalProperties": false,
+				"minProperties":        1,
+				"maxProperties":        1,
+			},
+		},
+		"required":             []interface{}{"secretRef"},
+		"additionalProperties": false,
+	}
+}
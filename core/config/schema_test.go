@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaSpec struct {
+	Auth    string            `json:"auth" enum:"a,b"`
+	Bucket  string            `json:"bucket" required:"true" description:"target bucket"`
+	Key     Secret            `json:"key"`
+	Tags    map[string]string `json:"tags"`
+	Targets []string          `json:"targets"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema := SchemaOf[schemaSpec]()
+
+	assert.Equal(t, schemaDialect, schema["$schema"])
+	assert.Equal(t, false, schema["additionalProperties"])
+	assert.Equal(t, []interface{}{"bucket"}, schema["required"])
+
+	properties := schema["properties"].(Schema)
+	assert.Equal(t, Schema{"type": "string", "enum": []interface{}{"a", "b"}}, properties["auth"])
+	assert.Equal(t, Schema{"type": "string", "description": "target bucket"}, properties["bucket"])
+	assert.Equal(t, Schema{"type": "object", "additionalProperties": Schema{"type": "string"}}, properties["tags"])
+	assert.Equal(t, Schema{"type": "array", "items": Schema{"type": "string"}}, properties["targets"])
+	assert.Len(t, properties["key"].(Schema)["oneOf"], 2)
+}
+
+func TestSpecToType_Strict(t *testing.T) {
+	_, err := SpecToType[schemaSpec](map[string]interface{}{"bucket": "backups", "buckte": "typo"})
+	assert.ErrorContains(t, err, `unknown field "buckte"`)
+
+	spec, err := SpecToType[schemaSpec](map[string]interface{}{"bucket": "backups"})
+	assert.NoError(t, err)
+	assert.Equal(t, "backups", spec.Bucket)
+}
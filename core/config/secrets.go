@@ -0,0 +1,188 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const secretRefKey = "secretRef"
+const secretCacheTtl = 5 * time.Minute
+const secretResolveTimeout = 30 * time.Second
+
+// Secret is a spec value that may be resolved from a secret reference. It's redacted when formatted or marshalled, so
+// resolved specs can be logged safely. Use Value to get the secret itself.
+type Secret string
+
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// SecretResolver returns the value a reference points to, e.g. "ns/name#key" for a Kubernetes secret
+type SecretResolver func(ctx context.Context, ref string) (string, error)
+
+var secretResolvers = map[string]SecretResolver{
+	"env":  resolveEnvSecret,
+	"file": resolveFileSecret,
+}
+var secretResolversMu sync.RWMutex
+
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+var secretCache = map[string]cachedSecret{}
+var secretCacheMu sync.Mutex
+
+// RegisterSecretResolver makes a kind of secret reference available in specs, e.g. {"secretRef": {"<kind>": "<ref>"}}
+func RegisterSecretResolver(kind string, resolver SecretResolver) {
+	secretResolversMu.Lock()
+	defer secretResolversMu.Unlock()
+	secretResolvers[kind] = resolver
+}
+
+// ResolveSecretRefs returns a copy of the spec with every {"secretRef": {"<kind>": "<ref>"}} object, at any depth, replaced
+// by the value it references. Values are cached for a few minutes so specs can be resolved per request.
+func ResolveSecretRefs(ctx context.Context, spec map[string]interface{}) (map[string]interface{}, error) {
+	resolved, err := resolveSecretRefs(ctx, spec, "")
+	if err != nil {
+		return nil, err
+	}
+	payload, _ := resolved.(map[string]interface{})
+	return payload, nil
+}
+
+func resolveSecretRefs(ctx context.Context, value interface{}, path string) (interface{}, error) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := typed[secretRefKey]; ok {
+			if len(typed) != 1 {
+				return nil, fmt.Errorf("%s: secretRef must be the only key of its object", path)
+			}
+			return resolveSecretRef(ctx, ref, path)
+		}
+
+		payload := make(map[string]interface{}, len(typed))
+		for key, nested := range typed {
+			resolved, err := resolveSecretRefs(ctx, nested, joinSpecPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			payload[key] = resolved
+		}
+		return payload, nil
+	case []interface{}:
+		payload := make([]interface{}, len(typed))
+		for i, nested := range typed {
+			resolved, err := resolveSecretRefs(ctx, nested, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			payload[i] = resolved
+		}
+		return payload, nil
+	default:
+		return value, nil
+	}
+}
+
+func resolveSecretRef(ctx context.Context, raw interface{}, path string) (string, error) {
+	ref, ok := raw.(map[string]interface{})
+	if !ok || len(ref) != 1 {
+		return "", fmt.Errorf("%s: secretRef must have exactly one of the keys %s", path, strings.Join(SecretRefKinds(), ", "))
+	}
+
+	for kind, rawTarget := range ref {
+		target, ok := rawTarget.(string)
+		if !ok || target == "" {
+			return "", fmt.Errorf("%s: secretRef %s must be a non-empty string", path, kind)
+		}
+
+		secretResolversMu.RLock()
+		resolver, ok := secretResolvers[kind]
+		secretResolversMu.RUnlock()
+		if !ok {
+			return "", fmt.Errorf("%s: unknown secretRef kind %s, expected one of %s", path, kind, strings.Join(SecretRefKinds(), ", "))
+		}
+
+		cacheKey := kind + "|" + target
+		secretCacheMu.Lock()
+		cached, ok := secretCache[cacheKey]
+		secretCacheMu.Unlock()
+		if ok && time.Now().Before(cached.expires) {
+			return cached.value, nil
+		}
+
+		// Only the reference goes into errors, never the value
+		value, err := resolver(ctx, target)
+		if err != nil {
+			return "", fmt.Errorf("%s: unable to resolve %s secret %s: %w", path, kind, target, err)
+		}
+
+		secretCacheMu.Lock()
+		secretCache[cacheKey] = cachedSecret{value: value, expires: time.Now().Add(secretCacheTtl)}
+		secretCacheMu.Unlock()
+
+		return value, nil
+	}
+
+	return "", nil
+}
+
+// SecretRefKinds returns the registered kinds of secret references
+func SecretRefKinds() []string {
+	secretResolversMu.RLock()
+	defer secretResolversMu.RUnlock()
+
+	var payload []string
+	for kind := range secretResolvers {
+		payload = append(payload, kind)
+	}
+	sort.Strings(payload)
+	return payload
+}
+
+func resolveEnvSecret(_ context.Context, ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", errors.New("environment variable not set")
+	}
+	return value, nil
+}
+
+func resolveFileSecret(_ context.Context, ref string) (string, error) {
+	buf, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	// Mounted secrets and files written by editors commonly end with a newline that isn't part of the secret
+	return strings.TrimRight(string(buf), "\r\n"), nil
+}
+
+func joinSpecPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
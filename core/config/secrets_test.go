@@ -0,0 +1,62 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type secretSpec struct {
+	Bucket string `json:"bucket"`
+	Key    Secret `json:"key"`
+	Nested struct {
+		Token Secret `json:"token"`
+	} `json:"nested"`
+}
+
+func TestLocationSpecToType_SecretRefs(t *testing.T) {
+	t.Setenv("OOPS_TEST_KEY", "from-env")
+	path := writeConfigFile(t, "token", "from-file\n")
+
+	spec, err := LocationSpecToType[secretSpec](BackupLocation{Name: "primary", Spec: map[string]interface{}{
+		"bucket": "backups",
+		"key":    map[string]interface{}{"secretRef": map[string]interface{}{"env": "OOPS_TEST_KEY"}},
+		"nested": map[string]interface{}{
+			"token": map[string]interface{}{"secretRef": map[string]interface{}{"file": path}},
+		},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "backups", spec.Bucket)
+	assert.Equal(t, "from-env", spec.Key.Value())
+	assert.Equal(t, "from-file", spec.Nested.Token.Value())
+
+	// Resolved values never show up when the spec is logged
+	serialised, err := json.Marshal(spec)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(serialised), "from-env")
+	assert.NotContains(t, string(serialised), "from-file")
+	assert.NotContains(t, fmt.Sprintf("%v %+v %#v", spec, spec, spec), "from-")
+}
+
+func TestLocationSpecToType_InvalidSecretRefs(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  interface{}
+		err  string
+	}{
+		{"unknown kind", map[string]interface{}{"vault": "x"}, "unknown secretRef kind vault"},
+		{"several kinds", map[string]interface{}{"env": "A", "file": "B"}, "exactly one of the keys"},
+		{"missing env", map[string]interface{}{"env": "OOPS_TEST_UNSET"}, "key: unable to resolve env secret OOPS_TEST_UNSET"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LocationSpecToType[secretSpec](BackupLocation{Name: "primary", Spec: map[string]interface{}{
+				"key": map[string]interface{}{"secretRef": tt.ref},
+			}})
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/core/metrics"
+	"go.uber.org/zap"
+)
+
+// Store holds the current config, which may be swapped by Watch while the application is running. Readers should call
+// Current once per unit of work, e.g. a job run or request, so they see a consistent config throughout.
+type Store struct {
+	current   atomic.Pointer[Config]
+	mu        sync.Mutex
+	listeners []func(old Config, new Config)
+	// fingerprint of the config files conf was loaded from, taken early so changes made before Watch starts aren't missed
+	fingerprint [sha256.Size]byte
+}
+
+func NewStore(conf Config) *Store {
+	store := &Store{fingerprint: fingerprintFiles(configPaths(conf.AdditionalConfigPath))}
+	store.current.Store(&conf)
+	return store
+}
+
+func (s *Store) Current() Config {
+	return *s.current.Load()
+}
+
+// OnChange registers a function called with the previous and new config after every swap
+func (s *Store) OnChange(fn func(old Config, new Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Swap replaces the current config and notifies the listeners
+func (s *Store) Swap(conf Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.current.Swap(&conf)
+	for _, fn := range s.listeners {
+		fn(*old, conf)
+	}
+}
+
+// Watch polls the files in AdditionalConfigPath every interval until ctx is done. Once their content changes the config is
+// loaded again and checked by validate, which may be nil. Invalid configs are logged and rejected, keeping the current one.
+// Environment variables are only read at startup, so changes to them still require a restart. ConfigMaps and Secrets
+// mounted as config files are updated by the kubelet and picked up like any other file change, the Kubernetes API
+// isn't watched.
+func (s *Store) Watch(ctx context.Context, interval time.Duration, validate func(conf Config) error) {
+	paths := configPaths(s.Current().AdditionalConfigPath)
+	fingerprint := s.fingerprint
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		next := fingerprintFiles(paths)
+		if next == fingerprint {
+			continue
+		}
+		// Remember the content even if it's rejected, so an invalid file is only reported once
+		fingerprint = next
+
+		err := s.reload(validate)
+		if err != nil {
+			metrics.ConfigReloads.WithLabelValues("rejected").Inc()
+			logging.Logger.Error("Config change rejected, keeping the current config", zap.Error(err))
+			continue
+		}
+
+		metrics.ConfigReloads.WithLabelValues("applied").Inc()
+		logging.Logger.Info("Config change applied")
+	}
+}
+
+func (s *Store) reload(validate func(conf Config) error) error {
+	conf, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	if validate != nil {
+		err = validate(conf)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.Swap(conf)
+
+	return nil
+}
+
+func configPaths(raw string) []string {
+	var payload []string
+	for _, path := range strings.Split(raw, ",") {
+		path = strings.TrimSpace(path)
+		if path != "" {
+			payload = append(payload, path)
+		}
+	}
+	return payload
+}
+
+// fingerprintFiles hashes the content of the files. Missing or unreadable files hash differently from existing ones, so
+// deleting a file also counts as a change.
+func fingerprintFiles(paths []string) [sha256.Size]byte {
+	hash := sha256.New()
+
+	for _, path := range paths {
+		hash.Write([]byte(path))
+
+		file, err := os.Open(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				hash.Write([]byte{0})
+			} else {
+				hash.Write([]byte{1})
+			}
+			continue
+		}
+		hash.Write([]byte{2})
+		io.Copy(hash, file)
+		file.Close()
+	}
+
+	var payload [sha256.Size]byte
+	copy(payload[:], hash.Sum(nil))
+	return payload
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.dfds.cloud/oops/core/logging"
+	"go.uber.org/zap"
+)
+
+func TestStore_Watch(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	path := writeConfigFile(t, "conf.json", `{"logLevel": "info"}`)
+	t.Setenv("SSU_OOPS_ADDITIONALCONFIGPATH", path)
+
+	conf, err := LoadConfig()
+	assert.NoError(t, err)
+
+	store := NewStore(conf)
+	changes := make(chan [2]string, 4)
+	store.OnChange(func(old Config, new Config) {
+		changes <- [2]string{old.LogLevel, new.LogLevel}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	defer func() {
+		cancel()
+		<-done
+	}()
+	go func() {
+		defer close(done)
+		store.Watch(ctx, 10*time.Millisecond, func(conf Config) error {
+			if conf.LogLevel == "rejected" {
+				return errors.New("rejected by validation")
+			}
+			return nil
+		})
+	}()
+
+	waitForChange := func() [2]string {
+		select {
+		case change := <-changes:
+			return change
+		case <-time.After(2 * time.Second):
+			t.Fatal("config change wasn't applied")
+			return [2]string{}
+		}
+	}
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"logLevel": "debug"}`), 0644))
+	assert.Equal(t, [2]string{"info", "debug"}, waitForChange())
+	assert.Equal(t, "debug", store.Current().LogLevel)
+
+	// Neither invalid files nor configs failing validation replace the current config
+	assert.NoError(t, os.WriteFile(path, []byte(`{"logLevel": `), 0644))
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, os.WriteFile(path, []byte(`{"logLevel": "rejected"}`), 0644))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "debug", store.Current().LogLevel)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"logLevel": "warn"}`), 0644))
+	assert.Equal(t, [2]string{"debug", "warn"}, waitForChange())
+}
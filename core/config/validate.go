@@ -0,0 +1,207 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Validate checks the fields required by the configured features. Provider specs of backup locations and notification sinks
+// are validated by their providers, see storage.ValidateLocation and notify.New.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Job.Route53Backup.Accounts != "" && c.Job.Route53Backup.AssumeRole == "" {
+		errs = append(errs, errors.New("job.route53Backup.assumeRole is required when accounts are configured"))
+	}
+	errs = append(errs, c.AwsDefaults().Validate("aws"))
+	errs = append(errs, c.AwsDefaults().Merge(c.Job.Route53Backup.Aws).Validate("job.route53Backup.aws"))
+	if c.Job.Route53Backup.RequestsPerSecond <= 0 {
+		errs = append(errs, errors.New("job.route53Backup.requestsPerSecond must be positive"))
+	}
+	if c.Job.Route53Backup.ZoneConcurrency <= 0 {
+		errs = append(errs, errors.New("job.route53Backup.zoneConcurrency must be positive"))
+	}
+	if c.Job.Route53Backup.Lint.Enabled {
+		if c.Job.Route53Backup.Lint.MaxTtl <= 0 {
+			errs = append(errs, errors.New("job.route53Backup.lint.maxTtl must be positive"))
+		}
+		if c.Job.Route53Backup.Lint.ProbeTargets && c.Job.Route53Backup.Lint.ProbeTimeout <= 0 {
+			errs = append(errs, errors.New("job.route53Backup.lint.probeTimeout must be positive"))
+		}
+	}
+	if c.Job.Route53Backup.Delegations.Enabled && c.Job.Route53Backup.Delegations.QueryServers && c.Job.Route53Backup.Delegations.QueryTimeout <= 0 {
+		errs = append(errs, errors.New("job.route53Backup.delegations.queryTimeout must be positive"))
+	}
+	if err := ValidateSessionDuration(c.Job.Route53Backup.SessionDuration, c.Job.Route53Backup.HubRoleArn != ""); err != nil {
+		errs = append(errs, fmt.Errorf("job.route53Backup.sessionDuration %w", err))
+	}
+
+	if c.Job.TakeoverScan.Probe && c.Job.TakeoverScan.ProbeTimeout <= 0 {
+		errs = append(errs, errors.New("job.takeoverScan.probeTimeout must be positive"))
+	}
+
+	if c.Job.BackupStaleness.Threshold <= 0 {
+		errs = append(errs, errors.New("job.backupStaleness.threshold must be positive"))
+	}
+	errs = append(errs,
+		validateInterval("job.dummy", c.Job.Dummy.Enable, c.Job.Dummy.Interval),
+		validateInterval("job.route53Backup", c.Job.Route53Backup.Enable, c.Job.Route53Backup.Interval),
+		validateInterval("job.takeoverScan", c.Job.TakeoverScan.Enable, c.Job.TakeoverScan.Interval),
+		validateInterval("job.backupStaleness", c.Job.BackupStaleness.Enable, c.Job.BackupStaleness.Interval),
+	)
+
+	if c.Auth.Enabled {
+		if c.Auth.Audience == "" {
+			errs = append(errs, errors.New("auth.audience is required when auth is enabled"))
+		}
+		if c.Auth.TenantId == "" && (c.Auth.Issuer == "" || c.Auth.JwksUrl == "") {
+			errs = append(errs, errors.New("auth.tenantId is required unless both auth.issuer and auth.jwksUrl are set"))
+		}
+		if c.Auth.CapabilityScoping && c.SelfserviceApi.Host == "" {
+			errs = append(errs, errors.New("selfserviceApi.host is required for auth.capabilityScoping"))
+		}
+	}
+
+	switch c.RunHistory.Store {
+	case "", "memory", "configmap":
+	case "file":
+		if c.RunHistory.Path == "" {
+			errs = append(errs, errors.New("runHistory.path is required for the file store"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("runHistory.store must be one of memory, file or configmap, got %s", c.RunHistory.Store))
+	}
+	if c.RunHistory.Retention <= 0 {
+		errs = append(errs, fmt.Errorf("runHistory.retention must be positive, got %d", c.RunHistory.Retention))
+	}
+
+	if c.SecondaryDns.Enabled {
+		errs = append(errs, c.validateSecondaryDns())
+	}
+
+	locations := make(map[string]bool)
+	for i, location := range c.BackupLocations {
+		switch {
+		case location.Name == "":
+			errs = append(errs, fmt.Errorf("backupLocations[%d].name is required", i))
+		case locations[location.Name]:
+			errs = append(errs, fmt.Errorf("backup location %s is defined more than once", location.Name))
+		case strings.Contains(location.Name, ":"):
+			// Names are used in <location>:<backup id> references by the CLI
+			errs = append(errs, fmt.Errorf("backup location %s must not contain a colon", location.Name))
+		}
+		locations[location.Name] = true
+
+		if location.Provider == "" {
+			errs = append(errs, fmt.Errorf("backupLocations[%d].provider is required", i))
+		}
+	}
+
+	sinks := make(map[string]bool)
+	for i, sink := range c.Notifications.Sinks {
+		switch {
+		case sink.Name == "":
+			errs = append(errs, fmt.Errorf("notifications.sinks[%d].name is required", i))
+		case sinks[sink.Name]:
+			errs = append(errs, fmt.Errorf("notification sink %s is defined more than once", sink.Name))
+		}
+		sinks[sink.Name] = true
+
+		if sink.Provider == "" {
+			errs = append(errs, fmt.Errorf("notifications.sinks[%d].provider is required", i))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateSecondaryDns checks the secondary DNS settings, it's only called when the secondary DNS is enabled
+func (c *Config) validateSecondaryDns() error {
+	var errs []error
+
+	if c.SecondaryDns.Listen == "" {
+		errs = append(errs, errors.New("secondaryDns.listen is required when the secondary DNS is enabled"))
+	}
+	if c.SecondaryDns.RefreshInterval <= 0 {
+		errs = append(errs, errors.New("secondaryDns.refreshInterval must be positive"))
+	}
+	if name := c.SecondaryDns.Location; name != "" && !slices.ContainsFunc(c.BackupLocations, func(l BackupLocation) bool { return l.Name == name }) {
+		errs = append(errs, fmt.Errorf("secondaryDns.location %s is not a configured backup location", name))
+	}
+	for _, allowed := range c.SecondaryDns.AllowTransfer {
+		if _, err := netip.ParsePrefix(allowed); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(allowed); err != nil {
+			errs = append(errs, fmt.Errorf("secondaryDns.allowTransfer %s is neither an address nor a network", allowed))
+		}
+	}
+	for _, target := range c.SecondaryDns.Notify {
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			errs = append(errs, fmt.Errorf("secondaryDns.notify %s must be host:port", target))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+var awsPartitions = []string{"aws", "aws-cn", "aws-us-gov", "aws-iso", "aws-iso-b"}
+
+// Validate checks merged settings, path prefixes the errors
+func (s AwsSettings) Validate(path string) error {
+	var errs []error
+	if s.Region == "" {
+		errs = append(errs, fmt.Errorf("%s.region is required", path))
+	}
+	if s.Partition == "" {
+		errs = append(errs, fmt.Errorf("%s.partition is required", path))
+	}
+	if s.StsEndpoint == "" {
+		errs = append(errs, fmt.Errorf("%s.stsEndpoint is required", path))
+	}
+
+	if s.Partition != "" && !slices.Contains(awsPartitions, s.Partition) {
+		errs = append(errs, fmt.Errorf("%s.partition must be one of %s, got %s", path, strings.Join(awsPartitions, ", "), s.Partition))
+	}
+
+	switch {
+	case s.StsEndpoint == "", s.StsEndpoint == "regional":
+	case s.StsEndpoint == "global":
+		// Only the commercial partition has a global STS endpoint
+		if s.Partition != "aws" {
+			errs = append(errs, fmt.Errorf("%s.stsEndpoint global is only available in the aws partition", path))
+		}
+	case strings.HasPrefix(s.StsEndpoint, "https://"):
+	default:
+		errs = append(errs, fmt.Errorf("%s.stsEndpoint must be regional, global or an https URL, got %s", path, s.StsEndpoint))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateInterval requires a positive interval for enabled jobs
+func validateInterval(job string, enable bool, interval time.Duration) error {
+	if enable && interval <= 0 {
+		return fmt.Errorf("%s.interval must be positive when the job is enabled", job)
+	}
+	return nil
+}
+
+// ValidateSessionDuration checks the duration of an assumed role session against the limits of STS, 12h and 1h for
+// chained sessions. Zero selects the default.
+func ValidateSessionDuration(duration time.Duration, chained bool) error {
+	maxDuration := 12 * time.Hour
+	if chained {
+		maxDuration = time.Hour
+	}
+	if duration != 0 && (duration < 15*time.Minute || duration > maxDuration) {
+		return fmt.Errorf("must be between 15m and %s", maxDuration)
+	}
+
+	return nil
+}
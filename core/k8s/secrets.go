@@ -0,0 +1,61 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.dfds.cloud/oops/core/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+func init() {
+	config.RegisterSecretResolver("k8s", ResolveSecret)
+}
+
+// CurrentNamespace returns the namespace of the pod's service account
+func CurrentNamespace() (string, error) {
+	buf, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(buf)), nil
+}
+
+// ResolveSecret reads a key of a Kubernetes secret referenced as "namespace/name#key". The namespace defaults to the pod's.
+func ResolveSecret(ctx context.Context, ref string) (string, error) {
+	nameRef, key, found := strings.Cut(ref, "#")
+	if !found || key == "" {
+		return "", fmt.Errorf("expected [namespace/]name#key")
+	}
+
+	namespace, name, found := strings.Cut(nameRef, "/")
+	if !found {
+		name = namespace
+		current, err := CurrentNamespace()
+		if err != nil {
+			return "", err
+		}
+		namespace = current
+	}
+
+	client, err := GetK8sClient()
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found", key)
+	}
+
+	return string(value), nil
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "oops"
+
+var (
+	JobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "Finished job runs by status and trigger",
+	}, []string{"job", "status", "trigger"})
+
+	JobRunDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_run_duration_seconds",
+		Help:      "Duration of job runs",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 3600},
+	}, []string{"job"})
+
+	BackupAccounts = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "backup_accounts",
+		Help:      "AWS accounts included in the most recent backup, by outcome",
+	}, []string{"job", "outcome"})
+
+	BackupZones = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "backup_zones",
+		Help:      "Hosted zones per AWS account in the most recent backup",
+	}, []string{"job", "account"})
+
+	BackupRecords = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "backup_records",
+		Help:      "Resource record sets per AWS account in the most recent backup",
+	}, []string{"job", "account"})
+
+	LintFindings = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "lint_findings",
+		Help:      "Findings of the record checks in the most recent backup, by AWS account and check",
+	}, []string{"account", "check"})
+
+	TakeoverFindings = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "takeover_findings",
+		Help:      "Records pointing at AWS resources missing from our accounts in the most recent takeover scan, by account and status",
+	}, []string{"account", "status"})
+
+	DelegationIssues = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "delegation_issues",
+		Help:      "Subzones delegated wrongly in the most recent backup, by capability owning the subzone and problem",
+	}, []string{"capability", "problem"})
+
+	AssumeRoleFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "assume_role_failures_total",
+		Help:      "Failed attempts to assume a role in an AWS account",
+	}, []string{"account"})
+
+	UploadedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_uploaded_bytes_total",
+		Help:      "Bytes uploaded to backup locations",
+	}, []string{"location", "provider"})
+
+	UploadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_upload_duration_seconds",
+		Help:      "Latency of uploads to backup locations",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+	}, []string{"location", "provider"})
+
+	UploadFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_upload_failures_total",
+		Help:      "Failed uploads to backup locations",
+	}, []string{"location", "provider"})
+
+	LastSuccessfulBackup = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_successful_backup_timestamp_seconds",
+		Help:      "Unix time of the last backup successfully stored in a location, alert on this to catch stale backups",
+	}, []string{"job", "location"})
+
+	ConfigReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Config reloads after a change of the config files, by result",
+	}, []string{"result"})
+
+	SecondaryDnsQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "secondary_dns_responses_total",
+		Help:      "Responses of the secondary DNS by rcode, zone transfers are counted as TRANSFER",
+	}, []string{"rcode"})
+
+	SecondaryDnsZones = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "secondary_dns_zones",
+		Help:      "Zones served by the secondary DNS",
+	})
+
+	SecondaryDnsSerial = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "secondary_dns_serial",
+		Help:      "SOA serial of the served zones, the Unix time of the backup they were loaded from",
+	})
+)
@@ -0,0 +1,108 @@
+package selfservice_api
+
+import (
+	"sync"
+	"time"
+)
+
+// retryInterval limits how often a failing Self-Service API is asked for the capability list again
+const retryInterval = time.Minute
+
+type capabilityLister interface {
+	GetCapabilities() ([]*GetCapabilitiesResponseContextCapability, error)
+}
+
+// CapabilityCache keeps the capability list in memory for ttl, so lookups don't hit the Self-Service API on every call
+type CapabilityCache struct {
+	client       capabilityLister
+	ttl          time.Duration
+	mu           sync.Mutex
+	capabilities []*GetCapabilitiesResponseContextCapability
+	fetchedAt    time.Time
+	// refreshing is closed once the fetch in progress has finished, it's nil while none is
+	refreshing chan struct{}
+	// fetchErr is the error of the last fetch, it's nil if that one succeeded
+	fetchErr error
+	failedAt time.Time
+}
+
+func NewCapabilityCache(client capabilityLister, ttl time.Duration) *CapabilityCache {
+	return &CapabilityCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (c *CapabilityCache) GetCapabilities() ([]*GetCapabilitiesResponseContextCapability, error) {
+	c.mu.Lock()
+
+	if c.capabilities != nil && time.Since(c.fetchedAt) < c.ttl {
+		defer c.mu.Unlock()
+		return c.capabilities, nil
+	}
+
+	if c.fetchErr != nil && time.Since(c.failedAt) < retryInterval {
+		defer c.mu.Unlock()
+		return c.cachedOrErr()
+	}
+
+	done := c.refreshing
+	if done == nil {
+		done = make(chan struct{})
+		c.refreshing = done
+		c.mu.Unlock()
+
+		// Fetched without holding the lock, so a slow Self-Service API doesn't hold up requests served from the cache
+		capabilities, err := c.client.GetCapabilities()
+
+		c.mu.Lock()
+		if err == nil {
+			c.capabilities = capabilities
+			c.fetchedAt = time.Now()
+		} else {
+			c.failedAt = time.Now()
+		}
+		c.fetchErr = err
+		c.refreshing = nil
+		close(done)
+	} else if c.capabilities == nil {
+		// Another request is fetching the list already, and there's nothing to serve meanwhile
+		c.mu.Unlock()
+		<-done
+		c.mu.Lock()
+	}
+	defer c.mu.Unlock()
+
+	return c.cachedOrErr()
+}
+
+// cachedOrErr serves the stale list rather than failing while the Self-Service API is unavailable. c.mu must be held
+func (c *CapabilityCache) cachedOrErr() ([]*GetCapabilitiesResponseContextCapability, error) {
+	if c.capabilities != nil {
+		return c.capabilities, nil
+	}
+	return nil, c.fetchErr
+}
+
+// AwsAccountsForMember returns the AWS account ids of the capabilities email is a member of
+func (c *CapabilityCache) AwsAccountsForMember(email string) (map[string]bool, error) {
+	capabilities, err := c.GetCapabilities()
+	if err != nil {
+		return nil, err
+	}
+
+	payload := make(map[string]bool)
+	for _, capability := range capabilities {
+		if !capability.HasMember(email) {
+			continue
+		}
+
+		ctx, err := capability.GetContext()
+		if err != nil {
+			continue
+		}
+		payload[ctx.AwsAccountID] = true
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,71 @@
+package selfservice_api
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLister struct {
+	calls   atomic.Int32
+	err     error
+	release chan struct{}
+}
+
+func (f *fakeLister) GetCapabilities() ([]*GetCapabilitiesResponseContextCapability, error) {
+	f.calls.Add(1)
+	if f.release != nil {
+		<-f.release
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []*GetCapabilitiesResponseContextCapability{{ID: "sandbox-dummy"}}, nil
+}
+
+func TestCapabilityCache_RetriesFailuresOncePerInterval(t *testing.T) {
+	lister := &fakeLister{err: errors.New("unavailable")}
+	cache := NewCapabilityCache(lister, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		_, err := cache.GetCapabilities()
+		assert.ErrorIs(t, err, lister.err)
+	}
+	assert.EqualValues(t, 1, lister.calls.Load())
+
+	lister.err = nil
+	cache.failedAt = time.Now().Add(-retryInterval)
+	capabilities, err := cache.GetCapabilities()
+	assert.NoError(t, err)
+	assert.Len(t, capabilities, 1)
+	assert.EqualValues(t, 2, lister.calls.Load())
+}
+
+func TestCapabilityCache_ServesStaleWhileRefreshing(t *testing.T) {
+	lister := &fakeLister{}
+	cache := NewCapabilityCache(lister, time.Hour)
+
+	_, err := cache.GetCapabilities()
+	assert.NoError(t, err)
+
+	cache.fetchedAt = time.Now().Add(-2 * time.Hour)
+	lister.release = make(chan struct{})
+	refreshed := make(chan struct{})
+	go func() {
+		defer close(refreshed)
+		_, _ = cache.GetCapabilities()
+	}()
+	assert.Eventually(t, func() bool { return lister.calls.Load() == 2 }, time.Second, time.Millisecond)
+
+	// The refresh is blocked, yet the stale list is served straight away
+	capabilities, err := cache.GetCapabilities()
+	assert.NoError(t, err)
+	assert.Len(t, capabilities, 1)
+	assert.EqualValues(t, 2, lister.calls.Load())
+
+	close(lister.release)
+	<-refreshed
+}
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"k8s.io/utils/env"
 )
@@ -162,9 +163,12 @@ func (c *Client) getNewToken() (*util.RefreshAuthResponse, error) {
 	return tokenResponse, nil
 }
 
+// requestTimeout bounds each call to the Self-Service API and the token endpoint
+const requestTimeout = 30 * time.Second
+
 func NewClient(conf Config) *Client {
 	payload := &Client{
-		httpClient: http.DefaultClient,
+		httpClient: &http.Client{Timeout: requestTimeout},
 		config:     conf,
 	}
 	payload.tokenClient = util.NewTokenClient(payload.getNewToken)
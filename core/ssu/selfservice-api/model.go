@@ -48,3 +48,22 @@ type GetCapabilitiesResponseContext struct {
 	AwsRoleArn   string `json:"awsRoleArn"`
 	AwsRoleEmail string `json:"awsRoleEmail"`
 }
+
+// AccountOwner is the capability an AWS account belongs to
+type AccountOwner struct {
+	CapabilityId   string `json:"capabilityId"`
+	CapabilityName string `json:"capabilityName"`
+}
+
+// OwnersByAwsAccount maps AWS account ids to the capability they belong to, capabilities without an AWS account are left out
+func OwnersByAwsAccount(capabilities []*GetCapabilitiesResponseContextCapability) map[string]AccountOwner {
+	payload := make(map[string]AccountOwner)
+	for _, capability := range capabilities {
+		ctx, err := capability.GetContext()
+		if err != nil {
+			continue
+		}
+		payload[ctx.AwsAccountID] = AccountOwner{CapabilityId: capability.ID, CapabilityName: capability.Name}
+	}
+	return payload
+}
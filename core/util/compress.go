@@ -62,3 +62,102 @@ func GzipAndTarballDirBuf(source string) ([]byte, error) {
 
 	return buf.Bytes(), nil
 }
+
+type TarballEntry struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// ListGzippedTarball returns the regular files contained in a .tar.gz stream
+func ListGzippedTarball(r io.Reader) ([]TarballEntry, error) {
+	var payload []TarballEntry
+
+	err := walkGzippedTarball(r, func(header *tar.Header, _ io.Reader) (bool, error) {
+		payload = append(payload, TarballEntry{Name: header.Name, Size: header.Size})
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// ReadGzippedTarball returns the content of every regular file in a .tar.gz stream by name
+func ReadGzippedTarball(r io.Reader) (map[string][]byte, error) {
+	payload := make(map[string][]byte)
+
+	err := walkGzippedTarball(r, func(header *tar.Header, content io.Reader) (bool, error) {
+		buf, err := io.ReadAll(content)
+		if err != nil {
+			return false, err
+		}
+		payload[header.Name] = buf
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// ReadFileFromGzippedTarball extracts a single file from a .tar.gz stream. os.ErrNotExist is returned if the tarball doesn't contain it.
+func ReadFileFromGzippedTarball(r io.Reader, name string) ([]byte, error) {
+	var payload []byte
+
+	err := walkGzippedTarball(r, func(header *tar.Header, content io.Reader) (bool, error) {
+		if header.Name != name {
+			return true, nil
+		}
+
+		buf, err := io.ReadAll(content)
+		if err != nil {
+			return false, err
+		}
+		payload = buf
+
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if payload == nil {
+		return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	}
+
+	return payload, nil
+}
+
+func walkGzippedTarball(r io.Reader, fn func(header *tar.Header, content io.Reader) (bool, error)) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to open gzip reader: %w", err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar header: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		cont, err := fn(header, tr)
+		if err != nil {
+			return err
+		}
+		if !cont {
+			return nil
+		}
+	}
+}
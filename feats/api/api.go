@@ -2,9 +2,37 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	selfserviceapi "go.dfds.cloud/oops/core/ssu/selfservice-api"
+	"go.dfds.cloud/oops/feats/api/auth"
 	"go.dfds.cloud/oops/feats/api/controller"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.uber.org/zap"
 )
 
-func Configure(router *gin.Engine) {
-	controller.AddControllers(router)
+// Configure sets up authentication and the routes. Auth settings are read once, backup locations on every request.
+func Configure(router *gin.Engine, store *config.Store, r *runner.Runner) error {
+	conf := store.Current()
+	if conf.Auth.Enabled {
+		validator, err := auth.NewValidator(conf)
+		if err != nil {
+			return err
+		}
+		router.Use(auth.Middleware(validator))
+	} else {
+		permissions := auth.ParsePermissions(conf.Auth.AnonymousPermissions)
+		logging.Logger.Warn("API authentication is disabled, every request is granted the anonymous permissions", zap.Any("permissions", permissions))
+		router.Use(auth.Anonymous(permissions...))
+	}
+
+	var scope *auth.CapabilityScope
+	if conf.Auth.CapabilityScoping {
+		capabilities := selfserviceapi.NewCapabilityCache(selfserviceapi.NewClient(conf.SelfserviceApi), conf.Auth.CapabilityCacheTtl)
+		scope = auth.NewCapabilityScope(capabilities)
+	}
+
+	controller.AddControllers(router, store, r, scope)
+
+	return nil
 }
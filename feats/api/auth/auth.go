@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.dfds.cloud/oops/core/config"
+)
+
+type Permission string
+
+const (
+	PermissionBackupsRead     Permission = "backups:read"
+	PermissionBackupsDownload Permission = "backups:download"
+	PermissionJobsRead        Permission = "jobs:read"
+	PermissionJobsTrigger     Permission = "jobs:trigger"
+	PermissionRestoreExecute  Permission = "restore:execute"
+)
+
+// PermissionAll can be used in role mappings to grant every permission
+const PermissionAll Permission = "*"
+
+const principalKey = "oops.principal"
+
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles  []string `json:"roles"`
+	Groups []string `json:"groups"`
+	// Email is optional and user editable in Azure AD, so it's never used to identify the caller
+	Email             string `json:"email"`
+	Upn               string `json:"upn"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+type Principal struct {
+	Subject string
+	// Email is the caller's user principal name, which Self-Service lists as the capability member's email
+	Email       string
+	Roles       []string
+	Groups      []string
+	Permissions map[Permission]bool
+}
+
+func (p *Principal) Has(permission Permission) bool {
+	return p.Permissions[PermissionAll] || p.Permissions[permission]
+}
+
+type Validator struct {
+	issuer          string
+	audience        string
+	keys            *Jwks
+	rolePermissions map[string][]Permission
+}
+
+func NewValidator(conf config.Config) (*Validator, error) {
+	if conf.Auth.Audience == "" {
+		return nil, errors.New("auth audience must be set")
+	}
+
+	issuer := conf.Auth.Issuer
+	jwksUrl := conf.Auth.JwksUrl
+	if issuer == "" || jwksUrl == "" {
+		if conf.Auth.TenantId == "" {
+			return nil, errors.New("auth tenantId must be set unless both issuer and jwksUrl are configured")
+		}
+		if issuer == "" {
+			issuer = fmt.Sprintf("https://login.microsoftonline.com/%s/v2.0", conf.Auth.TenantId)
+		}
+		if jwksUrl == "" {
+			jwksUrl = fmt.Sprintf("https://login.microsoftonline.com/%s/discovery/v2.0/keys", conf.Auth.TenantId)
+		}
+	}
+
+	rolePermissions, err := ParseRolePermissions(conf.Auth.RolePermissions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Validator{
+		issuer:          issuer,
+		audience:        conf.Auth.Audience,
+		keys:            NewJwks(jwksUrl, &http.Client{Timeout: 10 * time.Second}),
+		rolePermissions: rolePermissions,
+	}, nil
+}
+
+// ParseRolePermissions parses mappings of the form "Role=perm perm,Group=perm"
+func ParseRolePermissions(raw string) (map[string][]Permission, error) {
+	payload := make(map[string][]Permission)
+
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		role, perms, found := strings.Cut(entry, "=")
+		if !found || strings.TrimSpace(role) == "" {
+			return nil, fmt.Errorf("invalid role permission mapping %q", entry)
+		}
+
+		for _, perm := range strings.Fields(perms) {
+			payload[strings.TrimSpace(role)] = append(payload[strings.TrimSpace(role)], Permission(perm))
+		}
+	}
+
+	return payload, nil
+}
+
+func (v *Validator) Validate(raw string) (*Principal, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(raw, claims, v.keys.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	principal := &Principal{
+		Subject:     claims.Subject,
+		Email:       firstNonEmpty(claims.Upn, claims.PreferredUsername),
+		Roles:       claims.Roles,
+		Groups:      claims.Groups,
+		Permissions: make(map[Permission]bool),
+	}
+
+	for _, name := range append(append([]string{}, claims.Roles...), claims.Groups...) {
+		for _, perm := range v.rolePermissions[name] {
+			principal.Permissions[perm] = true
+		}
+	}
+
+	return principal, nil
+}
+
+// Middleware authenticates requests carrying an Azure AD bearer token. Requests without a valid token are rejected.
+func Middleware(v *Validator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		raw, found := strings.CutPrefix(header, "Bearer ")
+		if !found || raw == "" {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+
+		principal, err := v.Validate(raw)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("invalid bearer token: %s", err)})
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+// Anonymous grants every request the permissions, used when authentication is disabled
+func Anonymous(permissions ...Permission) gin.HandlerFunc {
+	granted := make(map[Permission]bool)
+	for _, permission := range permissions {
+		granted[permission] = true
+	}
+
+	return func(c *gin.Context) {
+		c.Set(principalKey, &Principal{Subject: "anonymous", Permissions: granted})
+		c.Next()
+	}
+}
+
+// ParsePermissions parses space separated permissions, e.g. "backups:read jobs:read"
+func ParsePermissions(raw string) []Permission {
+	var payload []Permission
+	for _, perm := range strings.Fields(raw) {
+		payload = append(payload, Permission(perm))
+	}
+	return payload
+}
+
+// Require aborts the request unless the authenticated principal has the permission
+func Require(permission Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := GetPrincipal(c)
+		if principal == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+			return
+		}
+
+		if !principal.Has(permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("missing permission %s", permission)})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func GetPrincipal(c *gin.Context) *Principal {
+	val, ok := c.Get(principalKey)
+	if !ok {
+		return nil
+	}
+
+	principal, _ := val.(*Principal)
+	return principal
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, val := range values {
+		if val != "" {
+			return val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"go.dfds.cloud/oops/core/config"
+)
+
+const testIssuer = "https://login.microsoftonline.com/tenant/v2.0"
+const testAudience = "api://oops"
+
+func newJwksServer(t *testing.T, keys map[string]*rsa.PrivateKey) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		set := jsonWebKeySet{}
+		for kid, key := range keys {
+			set.Keys = append(set.Keys, jsonWebKey{
+				Kid: kid,
+				Kty: "RSA",
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		err := json.NewEncoder(w).Encode(set)
+		assert.NoError(t, err)
+	}))
+}
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	return key
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return signed
+}
+
+func validClaims() Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			Subject:   "user",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		Roles:             []string{"Oops.Reader"},
+		Groups:            []string{"b9d3c5e0-operators"},
+		PreferredUsername: "dummy@dfds.cloud",
+	}
+}
+
+func newTestValidator(t *testing.T, jwksUrl string) *Validator {
+	var conf config.Config
+	conf.Auth.Enabled = true
+	conf.Auth.Issuer = testIssuer
+	conf.Auth.Audience = testAudience
+	conf.Auth.JwksUrl = jwksUrl
+	conf.Auth.RolePermissions = "Oops.Reader=backups:read,b9d3c5e0-operators=jobs:read jobs:trigger,Oops.Admin=*"
+
+	v, err := NewValidator(conf)
+	assert.NoError(t, err)
+	return v
+}
+
+func TestValidator_Validate(t *testing.T) {
+	key := newKey(t)
+	otherKey := newKey(t)
+	srv := newJwksServer(t, map[string]*rsa.PrivateKey{"key1": key})
+	defer srv.Close()
+
+	v := newTestValidator(t, srv.URL)
+
+	principal, err := v.Validate(signToken(t, key, "key1", validClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, "dummy@dfds.cloud", principal.Email)
+	assert.True(t, principal.Has(PermissionBackupsRead))
+	assert.True(t, principal.Has(PermissionJobsTrigger))
+	assert.False(t, principal.Has(PermissionBackupsDownload))
+	assert.False(t, principal.Has(PermissionRestoreExecute))
+
+	spoofed := validClaims()
+	spoofed.Upn = "guest@dfds.cloud"
+	spoofed.Email = "dummy@dfds.cloud"
+	principal, err = v.Validate(signToken(t, key, "key1", spoofed))
+	assert.NoError(t, err)
+	assert.Equal(t, "guest@dfds.cloud", principal.Email)
+
+	emailOnly := validClaims()
+	emailOnly.PreferredUsername = ""
+	emailOnly.Email = "dummy@dfds.cloud"
+	principal, err = v.Validate(signToken(t, key, "key1", emailOnly))
+	assert.NoError(t, err)
+	assert.Empty(t, principal.Email)
+
+	admin := validClaims()
+	admin.Roles = []string{"Oops.Admin"}
+	principal, err = v.Validate(signToken(t, key, "key1", admin))
+	assert.NoError(t, err)
+	assert.True(t, principal.Has(PermissionRestoreExecute))
+
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	_, err = v.Validate(signToken(t, key, "key1", expired))
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+
+	noExpiry := validClaims()
+	noExpiry.ExpiresAt = nil
+	_, err = v.Validate(signToken(t, key, "key1", noExpiry))
+	assert.Error(t, err)
+
+	wrongAudience := validClaims()
+	wrongAudience.Audience = jwt.ClaimStrings{"api://someone-else"}
+	_, err = v.Validate(signToken(t, key, "key1", wrongAudience))
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+
+	wrongIssuer := validClaims()
+	wrongIssuer.Issuer = "https://sts.windows.net/other/"
+	_, err = v.Validate(signToken(t, key, "key1", wrongIssuer))
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+	// Signed by a key that isn't in the key set, but claiming to be
+	_, err = v.Validate(signToken(t, otherKey, "key1", validClaims()))
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+
+	_, err = v.Validate(signToken(t, otherKey, "unknown", validClaims()))
+	assert.Error(t, err)
+
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	hmac.Header["kid"] = "key1"
+	signed, err := hmac.SignedString([]byte("secret"))
+	assert.NoError(t, err)
+	_, err = v.Validate(signed)
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	key := newKey(t)
+	srv := newJwksServer(t, map[string]*rsa.PrivateKey{"key1": key})
+	defer srv.Close()
+
+	router := gin.New()
+	router.Use(Middleware(newTestValidator(t, srv.URL)))
+	router.GET("/read", Require(PermissionBackupsRead), func(c *gin.Context) {
+		c.String(http.StatusOK, GetPrincipal(c).Email)
+	})
+	router.GET("/download", Require(PermissionBackupsDownload), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{name: "no token", path: "/read", token: "", want: http.StatusUnauthorized},
+		{name: "garbage token", path: "/read", token: "Bearer weee", want: http.StatusUnauthorized},
+		{name: "permitted", path: "/read", token: "Bearer " + signToken(t, key, "key1", validClaims()), want: http.StatusOK},
+		{name: "missing permission", path: "/download", token: "Bearer " + signToken(t, key, "key1", validClaims()), want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestParseRolePermissions(t *testing.T) {
+	perms, err := ParseRolePermissions("A=backups:read, B = jobs:read jobs:trigger ,")
+	assert.NoError(t, err)
+	assert.Equal(t, []Permission{PermissionBackupsRead}, perms["A"])
+	assert.Equal(t, []Permission{PermissionJobsRead, PermissionJobsTrigger}, perms["B"])
+
+	_, err = ParseRolePermissions("nope")
+	assert.Error(t, err)
+}
+
+func TestAnonymous(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Anonymous(ParsePermissions("backups:read  jobs:read")...))
+	router.GET("/read", Require(PermissionBackupsRead), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.POST("/trigger", Require(PermissionJobsTrigger), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/read", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/trigger", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestJwks_SlowRefresh(t *testing.T) {
+	key1 := newKey(t)
+	key2 := newKey(t)
+	before := newJwksServer(t, map[string]*rsa.PrivateKey{"key1": key1})
+	defer before.Close()
+	after := newJwksServer(t, map[string]*rsa.PrivateKey{"key1": key1, "key2": key2})
+	defer after.Close()
+
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every fetch but the first hangs until released, and includes the rotated in key
+		if fetches.Add(1) == 1 {
+			before.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		<-release
+		after.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	j := NewJwks(srv.URL, srv.Client())
+	token := func(kid string) *jwt.Token { return &jwt.Token{Header: map[string]any{"kid": kid}} }
+
+	_, err := j.Keyfunc(token("key1"))
+	assert.NoError(t, err)
+
+	// Pretend the keys were fetched long enough ago to refetch for an unknown key
+	j.mu.Lock()
+	j.fetchedAt = time.Now().Add(-time.Hour)
+	j.mu.Unlock()
+
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := j.Keyfunc(token("key2"))
+			results <- err
+		}()
+	}
+	assert.Eventually(t, func() bool { return fetches.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	// Known keys resolve while the refresh hangs
+	resolved := make(chan error, 1)
+	go func() {
+		_, err := j.Keyfunc(token("key1"))
+		resolved <- err
+	}()
+	select {
+	case err := <-resolved:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("known key blocked behind the refresh")
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, <-results)
+	}
+	// Both lookups of the unknown key shared one fetch
+	assert.Equal(t, int32(2), fetches.Load())
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.dfds.cloud/oops/core/logging"
+	selfserviceapi "go.dfds.cloud/oops/core/ssu/selfservice-api"
+	"go.uber.org/zap"
+)
+
+const accountScopeKey = "oops.accountScope"
+
+// CapabilityScope grants members of a capability access to data of the AWS account belonging to the capability
+type CapabilityScope struct {
+	capabilities *selfserviceapi.CapabilityCache
+}
+
+func NewCapabilityScope(capabilities *selfserviceapi.CapabilityCache) *CapabilityScope {
+	return &CapabilityScope{capabilities: capabilities}
+}
+
+// RequireOrCapabilityScope lets principals with the permission through unrestricted. Others are let through restricted to the
+// AWS accounts of the capabilities they're a member of, if scope is enabled and they're a member of at least one.
+func RequireOrCapabilityScope(permission Permission, scope *CapabilityScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := GetPrincipal(c)
+		if principal == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+			return
+		}
+
+		if principal.Has(permission) {
+			c.Next()
+			return
+		}
+
+		if scope == nil || principal.Email == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("missing permission %s", permission)})
+			return
+		}
+
+		accounts, err := scope.capabilities.AwsAccountsForMember(principal.Email)
+		if err != nil {
+			logging.Logger.Error("Unable to resolve capability membership", zap.String("email", principal.Email), zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "unable to resolve capability membership"})
+			return
+		}
+
+		if len(accounts) == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("missing permission %s and not a member of any capability with an AWS account", permission)})
+			return
+		}
+
+		c.Set(accountScopeKey, accounts)
+		c.Next()
+	}
+}
+
+// AccountScope returns the AWS accounts the request is restricted to. restricted is false if the principal may access all accounts.
+func AccountScope(c *gin.Context) (accounts map[string]bool, restricted bool) {
+	val, ok := c.Get(accountScopeKey)
+	if !ok {
+		return nil, false
+	}
+
+	accounts, _ = val.(map[string]bool)
+	return accounts, true
+}
+
+// AccountAllowed reports whether the request may access data of the AWS account
+func AccountAllowed(c *gin.Context, account string) bool {
+	accounts, restricted := AccountScope(c)
+	return !restricted || accounts[account]
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	selfserviceapi "go.dfds.cloud/oops/core/ssu/selfservice-api"
+)
+
+type fakeCapabilities struct {
+	calls int
+}
+
+func (f *fakeCapabilities) GetCapabilities() ([]*selfserviceapi.GetCapabilitiesResponseContextCapability, error) {
+	f.calls++
+
+	capability := &selfserviceapi.GetCapabilitiesResponseContextCapability{
+		ID:       "sandbox-dummy",
+		Contexts: []*selfserviceapi.GetCapabilitiesResponseContext{{AwsAccountID: "111111111111"}},
+	}
+	capability.Members = append(capability.Members, struct {
+		Email string `json:"email"`
+	}{Email: "Member@dfds.cloud"})
+
+	return []*selfserviceapi.GetCapabilitiesResponseContextCapability{capability}, nil
+}
+
+func TestRequireOrCapabilityScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	lister := &fakeCapabilities{}
+	scope := NewCapabilityScope(selfserviceapi.NewCapabilityCache(lister, time.Hour))
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		account   string
+		want      int
+	}{
+		{name: "permitted", principal: &Principal{Email: "admin@dfds.cloud", Permissions: map[Permission]bool{PermissionBackupsRead: true}}, account: "222222222222", want: http.StatusOK},
+		{name: "member of owning capability", principal: &Principal{Email: "member@dfds.cloud"}, account: "111111111111", want: http.StatusOK},
+		{name: "member of other capability", principal: &Principal{Email: "member@dfds.cloud"}, account: "222222222222", want: http.StatusForbidden},
+		{name: "not a member", principal: &Principal{Email: "stranger@dfds.cloud"}, account: "111111111111", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set(principalKey, tt.principal)
+			})
+			router.GET("/:account", RequireOrCapabilityScope(PermissionBackupsRead, scope), func(c *gin.Context) {
+				if !AccountAllowed(c, c.Param("account")) {
+					c.Status(http.StatusForbidden)
+					return
+				}
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+tt.account, nil))
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+
+	assert.Equal(t, 1, lister.calls)
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRefreshInterval limits how often an unknown key id can trigger a refetch of the key set
+const minRefreshInterval = 5 * time.Minute
+const maxKeyAge = 24 * time.Hour
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// Jwks caches the signing keys published at a JWKS endpoint, refetching them when a token refers to an unknown key
+type Jwks struct {
+	url        string
+	httpClient *http.Client
+	mu         sync.Mutex
+	keys       map[string]*rsa.PublicKey
+	fetchedAt  time.Time
+	// refreshing is closed once the fetch in progress has finished, it's nil while none is
+	refreshing chan struct{}
+	// refreshErr is the error of the last fetch
+	refreshErr error
+}
+
+func NewJwks(url string, httpClient *http.Client) *Jwks {
+	return &Jwks{
+		url:        url,
+		httpClient: httpClient,
+		keys:       make(map[string]*rsa.PublicKey),
+	}
+}
+
+// Keyfunc is a jwt.Keyfunc resolving the "kid" header of a token to a public key
+func (j *Jwks) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, errors.New("token is missing the kid header")
+	}
+
+	j.mu.Lock()
+	key, ok := j.keys[kid]
+	stale := time.Since(j.fetchedAt) > maxKeyAge
+	if ok && !stale {
+		j.mu.Unlock()
+		return key, nil
+	}
+
+	done := j.refreshing
+	if done == nil {
+		if time.Since(j.fetchedAt) < minRefreshInterval {
+			j.mu.Unlock()
+			return nil, fmt.Errorf("unknown signing key %s", kid)
+		}
+		done = make(chan struct{})
+		j.refreshing = done
+		j.fetchedAt = time.Now()
+		j.mu.Unlock()
+
+		// Fetched without holding the lock, so tokens signed with known keys aren't held up by a slow endpoint
+		keys, err := j.fetch()
+
+		j.mu.Lock()
+		if err == nil {
+			j.keys = keys
+		}
+		j.refreshErr = err
+		j.refreshing = nil
+		close(done)
+		j.mu.Unlock()
+	} else {
+		// Another request is fetching the keys already
+		j.mu.Unlock()
+		<-done
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	refreshed, found := j.keys[kid]
+	if found {
+		return refreshed, nil
+	}
+	if j.refreshErr != nil {
+		if ok {
+			// Keep accepting the previously fetched key while the endpoint is unavailable
+			return key, nil
+		}
+		return nil, j.refreshErr
+	}
+
+	return nil, fmt.Errorf("unknown signing key %s", kid)
+}
+
+// fetch downloads the key set
+func (j *Jwks) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := j.httpClient.Get(j.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("response returned unexpected status code: %d", resp.StatusCode)
+	}
+
+	var set jsonWebKeySet
+	err = json.NewDecoder(resp.Body).Decode(&set)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus for key %s: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent for key %s: %w", k.Kid, err)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
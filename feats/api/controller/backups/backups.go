@@ -0,0 +1,344 @@
+package backups
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/core/util"
+	"go.dfds.cloud/oops/feats/api/auth"
+	"go.dfds.cloud/oops/feats/export"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+	"go.dfds.cloud/oops/feats/storage"
+	_ "go.dfds.cloud/oops/feats/storage/s3"
+	"go.uber.org/zap"
+)
+
+type LocationBackups struct {
+	Location string           `json:"location"`
+	Provider string           `json:"provider"`
+	Job      string           `json:"job"`
+	Backups  []storage.Backup `json:"backups"`
+}
+
+type BackupDetails struct {
+	Location string                   `json:"location"`
+	Backup   storage.Backup           `json:"backup"`
+	Manifest *handlers.BackupManifest `json:"manifest,omitempty"`
+	Files    []util.TarballEntry      `json:"files"`
+}
+
+type backupsController struct {
+	store *config.Store
+}
+
+// BackupsController registers the backup routes. If scope is set, capability members without the backup permissions get
+// access restricted to their capability's AWS accounts. Whole tarballs contain every account and always need the download permission.
+func BackupsController(router *gin.Engine, store *config.Store, scope *auth.CapabilityScope) {
+	ctrl := &backupsController{store: store}
+	routes := router.Group("/backups")
+
+	routes.GET("", auth.RequireOrCapabilityScope(auth.PermissionBackupsRead, scope), ctrl.listBackups)
+	routes.GET("/:location/:id", auth.RequireOrCapabilityScope(auth.PermissionBackupsRead, scope), ctrl.getBackup)
+	routes.GET("/:location/:id/download", auth.Require(auth.PermissionBackupsDownload), ctrl.downloadBackup)
+	routes.GET("/:location/:id/zones/:account/:zone", auth.RequireOrCapabilityScope(auth.PermissionBackupsDownload, scope), ctrl.downloadZoneFile)
+	routes.GET("/:location/:id/zones/:account/:zone/:format", auth.RequireOrCapabilityScope(auth.PermissionBackupsDownload, scope), ctrl.exportZone)
+}
+
+// defaultListWindow is how far back backups are listed without a "from" query parameter
+const defaultListWindow = 30 * 24 * time.Hour
+
+// listBackups lists backups per job and location. Both can be narrowed down with the "job" and "location" query parameters.
+// Backups are listed between the "from" and "to" query parameters, dates or RFC 3339 timestamps, by default of the last 30 days.
+func (ctrl *backupsController) listBackups(c *gin.Context) {
+	to, err := parseTime(c.Query("to"), time.Now(), true)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Errorf("invalid to: %w", err))
+		return
+	}
+	from, err := parseTime(c.Query("from"), to.Add(-defaultListWindow), false)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Errorf("invalid from: %w", err))
+		return
+	}
+	if from.After(to) {
+		respondError(c, http.StatusBadRequest, errors.New("from must not be after to"))
+		return
+	}
+
+	payload := []LocationBackups{}
+	for _, location := range ctrl.store.Current().BackupLocations {
+		if !location.Enabled || !storage.IsRegistered(location.Provider) {
+			continue
+		}
+		if name := c.Query("location"); name != "" && name != location.Name {
+			continue
+		}
+
+		store, err := storage.Open(c, location)
+		if err != nil {
+			respondError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		for job, artifact := range handlers.Artifacts {
+			if name := c.Query("job"); name != "" && name != job {
+				continue
+			}
+
+			backups, err := storage.ListBackupsBetween(c, store, artifact, from, to)
+			if err != nil {
+				respondError(c, http.StatusBadGateway, err)
+				return
+			}
+
+			payload = append(payload, LocationBackups{
+				Location: location.Name,
+				Provider: location.Provider,
+				Job:      job,
+				Backups:  backups,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, payload)
+}
+
+func (ctrl *backupsController) getBackup(c *gin.Context) {
+	location, backup, content, ok := ctrl.fetchBackup(c)
+	if !ok {
+		return
+	}
+
+	files, err := util.ListGzippedTarball(bytes.NewReader(content))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	details := BackupDetails{
+		Location: location.Name,
+		Backup:   backup,
+		Files:    files,
+	}
+
+	// Backups taken before manifests were introduced only have the file listing
+	rawManifest, err := util.ReadFileFromGzippedTarball(bytes.NewReader(content), handlers.ManifestFile)
+	if err == nil {
+		err = json.Unmarshal(rawManifest, &details.Manifest)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if accounts, restricted := auth.AccountScope(c); restricted {
+		filterBackupDetails(&details, accounts)
+	}
+
+	c.JSON(http.StatusOK, details)
+}
+
+// filterBackupDetails strips everything not belonging to the accounts, including files like records.json spanning all accounts
+func filterBackupDetails(details *BackupDetails, accounts map[string]bool) {
+	files := []util.TarballEntry{}
+	for _, file := range details.Files {
+		account, _, nested := strings.Cut(file.Name, "/")
+		if nested && accounts[account] {
+			files = append(files, file)
+		}
+	}
+	details.Files = files
+
+	if details.Manifest != nil {
+		for account := range details.Manifest.Accounts {
+			if !accounts[account] {
+				delete(details.Manifest.Accounts, account)
+			}
+		}
+	}
+}
+
+func (ctrl *backupsController) downloadBackup(c *gin.Context) {
+	location, store, backup, ok := ctrl.resolveBackup(c)
+	if !ok {
+		return
+	}
+
+	body, size, err := store.Get(c, backup.Key)
+	if err != nil {
+		respondStorageError(c, err)
+		return
+	}
+	defer body.Close()
+
+	logging.Logger.Info("Backup downloaded", zap.String("location", location.Name), zap.String("backup", backup.Id))
+
+	c.DataFromReader(http.StatusOK, size, "application/gzip", body, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s-%s"`, location.Name, downloadName(backup)),
+	})
+}
+
+func (ctrl *backupsController) downloadZoneFile(c *gin.Context) {
+	if !auth.AccountAllowed(c, c.Param("account")) {
+		respondError(c, http.StatusForbidden, fmt.Errorf("not a member of a capability owning account %s", c.Param("account")))
+		return
+	}
+
+	location, backup, content, ok := ctrl.fetchBackup(c)
+	if !ok {
+		return
+	}
+
+	zoneFile := handlers.ZoneFilePath(c.Param("account"), c.Param("zone"))
+	buf, err := util.ReadFileFromGzippedTarball(bytes.NewReader(content), zoneFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			respondError(c, http.StatusNotFound, fmt.Errorf("zone file %s not found in backup", zoneFile))
+			return
+		}
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	logging.Logger.Info("Zone file downloaded", zap.String("location", location.Name), zap.String("backup", backup.Id), zap.String("zoneFile", zoneFile))
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, c.Param("zone")+".zone"))
+	c.Data(http.StatusOK, "text/dns", buf)
+}
+
+// exportZone renders a zone of the backup in an export format, e.g. terraform. Backups don't need to have been taken with
+// the format configured, the export is rendered from the backed up records.
+func (ctrl *backupsController) exportZone(c *gin.Context) {
+	if !auth.AccountAllowed(c, c.Param("account")) {
+		respondError(c, http.StatusForbidden, fmt.Errorf("not a member of a capability owning account %s", c.Param("account")))
+		return
+	}
+
+	format, ok := export.Lookup(c.Param("format"))
+	if !ok {
+		respondError(c, http.StatusNotFound, fmt.Errorf("unknown export format %s, expected one of %s", c.Param("format"), strings.Join(export.Names(), ", ")))
+		return
+	}
+
+	location, backup, content, ok := ctrl.fetchBackup(c)
+	if !ok {
+		return
+	}
+
+	zone, err := handlers.ReadBackupZone(content, c.Param("account"), c.Param("zone"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			respondError(c, http.StatusNotFound, err)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	rendered, err := format.Render(zone)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	logging.Logger.Info("Zone exported", zap.String("location", location.Name), zap.String("backup", backup.Id), zap.String("account", zone.Account), zap.String("zone", zone.Name), zap.String("format", c.Param("format")))
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, strings.TrimSuffix(zone.Name, "."), format.Extension))
+	c.Data(http.StatusOK, format.ContentType, []byte(rendered))
+}
+
+// resolveBackup looks up the location and backup referenced in the request path. On failure the response has already been written.
+func (ctrl *backupsController) resolveBackup(c *gin.Context) (config.BackupLocation, storage.Storage, storage.Backup, bool) {
+	for _, location := range ctrl.store.Current().BackupLocations {
+		if location.Name != c.Param("location") || !location.Enabled {
+			continue
+		}
+
+		store, err := storage.Open(c, location)
+		if err != nil {
+			respondError(c, http.StatusBadGateway, err)
+			return location, nil, storage.Backup{}, false
+		}
+
+		backup, err := storage.FindBackup(c, store, c.Param("id"))
+		if err != nil {
+			respondStorageError(c, err)
+			return location, store, backup, false
+		}
+
+		return location, store, backup, true
+	}
+
+	respondError(c, http.StatusNotFound, fmt.Errorf("backup location %s not found", c.Param("location")))
+	return config.BackupLocation{}, nil, storage.Backup{}, false
+}
+
+// fetchBackup resolves and downloads the backup tarball referenced in the request path into memory
+func (ctrl *backupsController) fetchBackup(c *gin.Context) (config.BackupLocation, storage.Backup, []byte, bool) {
+	location, store, backup, ok := ctrl.resolveBackup(c)
+	if !ok {
+		return location, backup, nil, false
+	}
+
+	body, _, err := store.Get(c, backup.Key)
+	if err != nil {
+		respondStorageError(c, err)
+		return location, backup, nil, false
+	}
+	defer body.Close()
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, err)
+		return location, backup, nil, false
+	}
+
+	return location, backup, content, true
+}
+
+// parseTime parses a date or RFC 3339 timestamp. Dates are read as the start of the day in UTC, or its end if endOfDay is
+// set. The fallback is returned for the empty string.
+func parseTime(value string, fallback time.Time, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		if endOfDay {
+			return date.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+		}
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func downloadName(backup storage.Backup) string {
+	if backup.Id == storage.LatestId {
+		return storage.LatestKey
+	}
+	return backup.Id
+}
+
+func respondStorageError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrBackupNotFound) {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	respondError(c, http.StatusBadGateway, err)
+}
+
+func respondError(c *gin.Context, code int, err error) {
+	c.JSON(code, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,109 @@
+package backups
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/core/util"
+	"go.dfds.cloud/oops/feats/api/auth"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+	"go.dfds.cloud/oops/feats/storage"
+	"go.dfds.cloud/oops/feats/storage/memory"
+	"go.uber.org/zap"
+)
+
+func newRouter(t *testing.T) (*gin.Engine, *memory.Storage) {
+	logging.Logger = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+
+	store := memory.New()
+	storage.Register("memory-backups", func(_ context.Context, _ config.BackupLocation) (storage.Storage, error) {
+		return store, nil
+	})
+
+	var conf config.Config
+	conf.BackupLocations = []config.BackupLocation{{Name: "memory", Provider: "memory-backups", Enabled: true}}
+
+	router := gin.New()
+	router.Use(auth.Anonymous(auth.PermissionBackupsRead))
+	BackupsController(router, config.NewStore(conf), nil)
+	return router, store
+}
+
+func get(router *gin.Engine, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestListBackups(t *testing.T) {
+	router, store := newRouter(t)
+
+	now := time.Now().UTC()
+	for _, ts := range []time.Time{now.Add(-time.Hour), now.AddDate(0, 0, -10), now.AddDate(0, -3, 0)} {
+		require.NoError(t, store.Put(context.Background(), storage.BackupKey(ts, handlers.Route53BackupArtifact), []byte("content")))
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		code   int
+		counts []int
+	}{
+		{name: "last 30 days by default", code: http.StatusOK, counts: []int{2}},
+		{name: "from date", query: "?from=" + now.AddDate(0, -4, 0).Format(time.DateOnly), code: http.StatusOK, counts: []int{3}},
+		{name: "to date inclusive", query: "?from=" + now.AddDate(0, -4, 0).Format(time.DateOnly) + "&to=" + now.AddDate(0, 0, -10).Format(time.DateOnly), code: http.StatusOK, counts: []int{2}},
+		{name: "timestamps", query: "?from=" + now.AddDate(0, 0, -2).Format(time.RFC3339) + "&to=" + now.Format(time.RFC3339), code: http.StatusOK, counts: []int{1}},
+		{name: "other location", query: "?location=other", code: http.StatusOK, counts: []int{}},
+		{name: "invalid from", query: "?from=yesterday", code: http.StatusBadRequest},
+		{name: "from after to", query: "?from=2025-02-01&to=2025-01-01", code: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := get(router, "/backups"+test.query)
+			require.Equal(t, test.code, w.Code, w.Body.String())
+			if test.code != http.StatusOK {
+				return
+			}
+
+			var payload []LocationBackups
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &payload))
+			counts := []int{}
+			for _, location := range payload {
+				counts = append(counts, len(location.Backups))
+			}
+			assert.Equal(t, test.counts, counts)
+		})
+	}
+}
+
+func TestGetBackup(t *testing.T) {
+	router, store := newRouter(t)
+
+	dir := t.TempDir()
+	tarball, err := util.GzipAndTarballDirBuf(dir)
+	require.NoError(t, err)
+	ts := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, store.Put(context.Background(), storage.BackupKey(ts, handlers.Route53BackupArtifact), tarball))
+	require.NoError(t, store.Put(context.Background(), storage.LatestKey, tarball))
+
+	w := get(router, "/backups/memory/1738324800-zones.tar.gz")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var details BackupDetails
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &details))
+	assert.Equal(t, "2025/1/31/1738324800-zones.tar.gz", details.Backup.Key)
+	assert.Nil(t, details.Manifest)
+
+	assert.Equal(t, http.StatusOK, get(router, "/backups/memory/latest").Code)
+	assert.Equal(t, http.StatusNotFound, get(router, "/backups/memory/1738324801-zones.tar.gz").Code)
+	assert.Equal(t, http.StatusNotFound, get(router, "/backups/other/latest").Code)
+}
@@ -2,9 +2,18 @@ package controller
 
 import (
 	"github.com/gin-gonic/gin"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/feats/api/auth"
+	"go.dfds.cloud/oops/feats/api/controller/backups"
+	"go.dfds.cloud/oops/feats/api/controller/jobs"
 	"go.dfds.cloud/oops/feats/api/controller/misc"
+	"go.dfds.cloud/oops/feats/api/controller/schemas"
+	"go.dfds.cloud/oops/feats/jobs/runner"
 )
 
-func AddControllers(router *gin.Engine) {
+func AddControllers(router *gin.Engine, store *config.Store, r *runner.Runner, scope *auth.CapabilityScope) {
 	misc.MiscController(router)
+	backups.BackupsController(router, store, scope)
+	jobs.JobsController(router, r)
+	schemas.SchemasController(router)
 }
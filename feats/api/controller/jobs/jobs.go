@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.dfds.cloud/oops/feats/api/auth"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+)
+
+func JobsController(router *gin.Engine, r *runner.Runner) {
+	routes := router.Group("/jobs")
+
+	routes.GET("", auth.Require(auth.PermissionJobsRead), func(c *gin.Context) {
+		overview, err := r.Overview(c)
+		if err != nil {
+			respondRunnerError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, overview)
+	})
+
+	routes.GET("/:name/runs", auth.Require(auth.PermissionJobsRead), func(c *gin.Context) {
+		runs, err := r.Runs(c, c.Param("name"))
+		if err != nil {
+			respondRunnerError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, runs)
+	})
+
+	routes.POST("/:name/runs", auth.Require(auth.PermissionJobsTrigger), func(c *gin.Context) {
+		run, err := r.Trigger(c.Param("name"))
+		if err != nil {
+			respondRunnerError(c, err)
+			return
+		}
+
+		c.Header("Location", c.Request.URL.Path+"/"+run.Id)
+		c.JSON(http.StatusAccepted, run)
+	})
+
+	routes.GET("/:name/runs/:id", auth.Require(auth.PermissionJobsRead), func(c *gin.Context) {
+		run, err := r.Get(c, c.Param("name"), c.Param("id"))
+		if err != nil {
+			respondRunnerError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, run)
+	})
+
+	// Server-sent events, a "progress" event is emitted on every update and a final "finished" event once the run has completed
+	routes.GET("/:name/runs/:id/events", auth.Require(auth.PermissionJobsRead), func(c *gin.Context) {
+		updates, unsubscribe, err := r.Subscribe(c, c.Param("name"), c.Param("id"))
+		if err != nil {
+			respondRunnerError(c, err)
+			return
+		}
+		defer unsubscribe()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case run, ok := <-updates:
+				if !ok {
+					// The final update may have been dropped for a slow client
+					if run, err := r.Get(c, c.Param("name"), c.Param("id")); err == nil && run.Status != runner.StatusRunning {
+						c.SSEvent("finished", run)
+					}
+					return false
+				}
+				if run.Status != runner.StatusRunning {
+					c.SSEvent("finished", run)
+					return false
+				}
+				c.SSEvent("progress", run)
+				return true
+			}
+		})
+	})
+}
+
+func respondRunnerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, runner.ErrJobNotFound), errors.Is(err, runner.ErrRunNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, runner.ErrJobAlreadyRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
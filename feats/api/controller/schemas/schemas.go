@@ -0,0 +1,20 @@
+package schemas
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.dfds.cloud/oops/feats/schema"
+)
+
+// SchemasController serves the JSON Schema of the config file and of every registered provider's spec
+func SchemasController(router *gin.Engine) {
+	routes := router.Group("/schemas")
+
+	routes.GET("", func(c *gin.Context) {
+		c.JSON(http.StatusOK, schema.All())
+	})
+	routes.GET("/config", func(c *gin.Context) {
+		c.JSON(http.StatusOK, schema.All().Config)
+	})
+}
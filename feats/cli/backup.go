@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+)
+
+// diffBackups prints the record changes from the old to the new backup, failing if there are any
+func diffBackups(ctx context.Context, conf config.Config, args []string) int {
+	fs := newFlagSet("diff")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		if isHelp(err) {
+			return ExitOk
+		}
+		return ExitUsage
+	}
+	if len(positional) != 2 {
+		return usageError(fs, "expected an old and a new source")
+	}
+
+	var records [2]map[string]map[string][]route53Types.ResourceRecordSet
+	for i, source := range positional {
+		content, err := loadSource(ctx, conf, source)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return ExitFailure
+		}
+		records[i], err = handlers.ReadBackupRecords(bytes.NewReader(content))
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", source, err)
+			return ExitFailure
+		}
+	}
+
+	changes := oopsAws.DiffRecords(records[0], records[1])
+	for _, change := range changes {
+		fmt.Fprintln(stdout, change)
+	}
+
+	if len(changes) > 0 {
+		return ExitFailure
+	}
+	return ExitOk
+}
+
+// verifyBackup prints the problems found in a backup, failing if there are any
+func verifyBackup(ctx context.Context, conf config.Config, args []string) int {
+	fs := newFlagSet("verify")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		if isHelp(err) {
+			return ExitOk
+		}
+		return ExitUsage
+	}
+	if len(positional) != 1 {
+		return usageError(fs, "expected exactly one source")
+	}
+
+	content, err := loadSource(ctx, conf, positional[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+
+	problems, err := handlers.VerifyBackup(content)
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to read backup: %s\n", err)
+		return ExitFailure
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(stdout, problem)
+	}
+
+	if len(problems) > 0 {
+		return ExitFailure
+	}
+	fmt.Fprintln(stdout, "backup is consistent")
+	return ExitOk
+}
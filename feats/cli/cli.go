@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/feats/storage"
+	_ "go.dfds.cloud/oops/feats/storage/s3"
+	"go.uber.org/zap"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOk = 0
+	// ExitFailure is returned when a command fails, or finds differences or problems in case of diff and verify
+	ExitFailure = 1
+	ExitUsage   = 2
+	// ExitPartialFailure is returned when a job run succeeded, but some of its items failed, e.g. accounts that couldn't be backed up
+	ExitPartialFailure = 3
+)
+
+type command struct {
+	usage       string
+	description string
+	run         func(ctx context.Context, conf config.Config, args []string) int
+}
+
+var commands map[string]command
+
+// Populated in init, as the commands refer back to it for their usage
+func init() {
+	commands = map[string]command{
+		"run": {
+			usage:       "run <job> [--once] [--interval 1h] [--dry-run]",
+			description: "run a job in the foreground, without the HTTP server",
+			run:         runJob,
+		},
+		"restore": {
+			usage:       "restore <source> --account <id> [--zone <name>] [--prune] [--dry-run]",
+			description: "restore the records of an account, or a single zone, from a backup",
+			run:         restoreBackup,
+		},
+		"import": {
+			usage:       "import <zone file|-> --account <id> --zone <name> [--overwrite] [--dry-run]",
+			description: "import a BIND zone file into a hosted zone, creating it if needed",
+			run:         importZoneFile,
+		},
+		"diff": {
+			usage:       "diff <old source> <new source>",
+			description: "list the record changes between two backups",
+			run:         diffBackups,
+		},
+		"verify": {
+			usage:       "verify <source>",
+			description: "check a backup for missing or inconsistent files",
+			run:         verifyBackup,
+		},
+		"config": {
+			usage:       "config validate | config schema",
+			description: "validate the configuration, or print the JSON Schema of the config file and provider specs",
+			run:         configCommand,
+		},
+	}
+}
+
+var commandOrder = []string{"run", "restore", "import", "diff", "verify", "config"}
+
+// Command output goes to stdout, errors and usage to stderr along with the logs
+var stdout io.Writer = os.Stdout
+var stderr io.Writer = os.Stderr
+
+// IsCommand reports whether the arguments invoke a CLI command rather than the server
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	_, ok := commands[args[0]]
+	return ok || args[0] == "help" || args[0] == "-h" || args[0] == "--help"
+}
+
+// Run executes the command in args, e.g. []string{"run", "route53Backup", "--once"}, and returns the process exit code.
+// A source is either the path of a local backup tarball, or "<location>:<backup id>" of a configured backup location.
+func Run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage()
+		return ExitUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %s\n\n", args[0])
+		printUsage()
+		return ExitUsage
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load config: %s\n", err)
+		return ExitFailure
+	}
+
+	logger, err := newLogger(conf)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to set up logging: %s\n", err)
+		return ExitFailure
+	}
+	defer logger.Sync()
+	logging.Logger = logger
+	oopsAws.SetDefaults(conf.AwsDefaults())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return cmd.run(ctx, conf, args[1:])
+}
+
+func printUsage() {
+	fmt.Fprintln(stderr, "usage: oops [command]")
+	fmt.Fprintln(stderr, "\nWithout a command the HTTP server and job scheduler are started.\n\nCommands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(stderr, "  %-75s %s\n", commands[name].usage, commands[name].description)
+	}
+	fmt.Fprintln(stderr, "\nA source is a local backup tarball, or <location>:<backup id> of a configured backup location, e.g. primary:latest")
+}
+
+// newLogger logs to stderr, keeping stdout for command output
+func newLogger(conf config.Config) (*zap.Logger, error) {
+	zapConf := zap.NewProductionConfig()
+	if conf.LogDebug {
+		zapConf = zap.NewDevelopmentConfig()
+	}
+	zapConf.OutputPaths = []string{"stderr"}
+
+	if conf.LogLevel != "" {
+		level, err := zap.ParseAtomicLevel(conf.LogLevel)
+		if err != nil {
+			return nil, err
+		}
+		zapConf.Level = level
+	}
+
+	return zapConf.Build()
+}
+
+// newFlagSet returns a flag set reporting parse errors to stderr. Flags are accepted before and after positional arguments.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: oops %s\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func usageError(fs *flag.FlagSet, format string, args ...any) int {
+	fmt.Fprintf(stderr, format+"\n", args...)
+	fs.Usage()
+	return ExitUsage
+}
+
+// loadSource reads a backup tarball from a local path or from a configured backup location
+func loadSource(ctx context.Context, conf config.Config, source string) ([]byte, error) {
+	if _, err := os.Stat(source); err == nil {
+		return os.ReadFile(source)
+	}
+
+	name, id, found := strings.Cut(source, ":")
+	if !found {
+		return nil, fmt.Errorf("%s is neither a file nor a <location>:<backup id> reference", source)
+	}
+
+	for _, location := range conf.BackupLocations {
+		if location.Name != name {
+			continue
+		}
+
+		store, err := storage.Open(ctx, location)
+		if err != nil {
+			return nil, err
+		}
+
+		backup, err := storage.FindBackup(ctx, store, id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+
+		body, _, err := store.Get(ctx, backup.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		defer body.Close()
+
+		return io.ReadAll(body)
+	}
+
+	return nil, fmt.Errorf("backup location %s not found", name)
+}
+
+func isHelp(err error) bool {
+	return errors.Is(err, flag.ErrHelp)
+}
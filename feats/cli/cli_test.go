@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/core/util"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+	"go.dfds.cloud/oops/feats/storage"
+	"go.dfds.cloud/oops/feats/storage/memory"
+	"go.uber.org/zap"
+)
+
+// capture redirects the command output for the duration of the test
+func capture(t *testing.T) (*bytes.Buffer, *bytes.Buffer) {
+	logging.Logger = zap.NewNop()
+
+	var out, errOut bytes.Buffer
+	prevOut, prevErr := stdout, stderr
+	stdout, stderr = &out, &errOut
+	t.Cleanup(func() { stdout, stderr = prevOut, prevErr })
+	return &out, &errOut
+}
+
+// writeBackup writes a backup tarball of the records with a zone file per zone, returning its path
+func writeBackup(t *testing.T, records map[string]map[string][]route53Types.ResourceRecordSet) string {
+	dir := t.TempDir()
+	serialised, err := json.Marshal(records)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, handlers.RecordsFile), serialised, 0644))
+
+	for acc, zones := range records {
+		for zone, recs := range zones {
+			content, _, err := oopsAws.GenerateZoneFile(recs, zone)
+			require.NoError(t, err)
+			path := filepath.Join(dir, handlers.ZoneFilePath(acc, zone))
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+			require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		}
+	}
+
+	tarball, err := util.GzipAndTarballDirBuf(dir)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "zones.tar.gz")
+	require.NoError(t, os.WriteFile(path, tarball, 0644))
+	return path
+}
+
+func zoneRecords(address string) map[string]map[string][]route53Types.ResourceRecordSet {
+	return map[string]map[string][]route53Types.ResourceRecordSet{
+		"111111111111": {"example.com.": {
+			{Name: aws.String("example.com."), Type: route53Types.RRTypeSoa, TTL: aws.Int64(900), ResourceRecords: []route53Types.ResourceRecord{
+				{Value: aws.String("ns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400")},
+			}},
+			{Name: aws.String("www.example.com."), Type: route53Types.RRTypeA, TTL: aws.Int64(300), ResourceRecords: []route53Types.ResourceRecord{
+				{Value: aws.String(address)},
+			}},
+		}},
+	}
+}
+
+func TestIsCommand(t *testing.T) {
+	assert.True(t, IsCommand([]string{"verify", "backup.tar.gz"}))
+	assert.True(t, IsCommand([]string{"--help"}))
+	assert.False(t, IsCommand(nil))
+	assert.False(t, IsCommand([]string{"serve"}))
+}
+
+func TestParseFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	account := fs.String("account", "", "")
+	dryRun := fs.Bool("dry-run", false, "")
+
+	positional, err := parseFlags(fs, []string{"primary:latest", "--account", "111111111111", "extra", "--dry-run"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"primary:latest", "extra"}, positional)
+	assert.Equal(t, "111111111111", *account)
+	assert.True(t, *dryRun)
+}
+
+func TestVerifyCommand(t *testing.T) {
+	out, errOut := capture(t)
+	path := writeBackup(t, zoneRecords("192.0.2.1"))
+	assert.Equal(t, ExitOk, verifyBackup(context.Background(), config.Config{}, []string{path}))
+	assert.Equal(t, "backup is consistent\n", out.String())
+
+	// A backup whose zone file is missing
+	dir := t.TempDir()
+	serialised, err := json.Marshal(zoneRecords("192.0.2.1"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, handlers.RecordsFile), serialised, 0644))
+	tarball, err := util.GzipAndTarballDirBuf(dir)
+	require.NoError(t, err)
+	broken := filepath.Join(t.TempDir(), "broken.tar.gz")
+	require.NoError(t, os.WriteFile(broken, tarball, 0644))
+
+	out.Reset()
+	assert.Equal(t, ExitFailure, verifyBackup(context.Background(), config.Config{}, []string{broken}))
+	assert.Contains(t, out.String(), "is missing")
+
+	assert.Equal(t, ExitUsage, verifyBackup(context.Background(), config.Config{}, nil))
+	assert.Contains(t, errOut.String(), "expected exactly one source")
+
+	assert.Equal(t, ExitFailure, verifyBackup(context.Background(), config.Config{}, []string{"no-such-file"}))
+	assert.Contains(t, errOut.String(), "is neither a file nor a <location>:<backup id> reference")
+}
+
+func TestDiffCommand(t *testing.T) {
+	out, _ := capture(t)
+	old := writeBackup(t, zoneRecords("192.0.2.1"))
+	changed := writeBackup(t, zoneRecords("192.0.2.2"))
+
+	assert.Equal(t, ExitOk, diffBackups(context.Background(), config.Config{}, []string{old, old}))
+	assert.Empty(t, out.String())
+
+	assert.Equal(t, ExitFailure, diffBackups(context.Background(), config.Config{}, []string{old, changed}))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "www.example.com. A")
+}
+
+func TestLoadSource(t *testing.T) {
+	store := memory.New()
+	storage.Register("memory-cli", func(_ context.Context, _ config.BackupLocation) (storage.Storage, error) {
+		return store, nil
+	})
+	key := storage.BackupKey(time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC), handlers.Route53BackupArtifact)
+	require.NoError(t, store.Put(context.Background(), key, []byte("tarball")))
+
+	var conf config.Config
+	conf.BackupLocations = []config.BackupLocation{{Name: "primary", Provider: "memory-cli", Enabled: true}}
+
+	content, err := loadSource(context.Background(), conf, "primary:1738324800-zones.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, "tarball", string(content))
+
+	_, err = loadSource(context.Background(), conf, "primary:1738324801-zones.tar.gz")
+	assert.ErrorIs(t, err, storage.ErrBackupNotFound)
+
+	_, err = loadSource(context.Background(), conf, "secondary:latest")
+	assert.EqualError(t, err, "backup location secondary not found")
+}
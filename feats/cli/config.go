@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/feats/api/auth"
+	"go.dfds.cloud/oops/feats/export"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.dfds.cloud/oops/feats/notify"
+	"go.dfds.cloud/oops/feats/schema"
+	"go.dfds.cloud/oops/feats/storage"
+)
+
+func configCommand(ctx context.Context, conf config.Config, args []string) int {
+	fs := newFlagSet("config")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		if isHelp(err) {
+			return ExitOk
+		}
+		return ExitUsage
+	}
+	if len(positional) != 1 {
+		return usageError(fs, "expected validate or schema")
+	}
+
+	switch positional[0] {
+	case "validate":
+	case "schema":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(schema.All())
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return ExitFailure
+		}
+		return ExitOk
+	default:
+		return usageError(fs, "expected validate or schema")
+	}
+
+	problems := validateConfig(ctx, conf)
+	for _, problem := range problems {
+		fmt.Fprintln(stdout, problem)
+	}
+
+	if len(problems) > 0 {
+		return ExitFailure
+	}
+	fmt.Fprintln(stdout, "config is valid")
+	return ExitOk
+}
+
+// validateConfig builds everything the server would build from the config, which LoadConfig already validated, and opens
+// the backup locations to check their credentials
+func validateConfig(ctx context.Context, conf config.Config) []string {
+	var problems []string
+
+	if conf.Auth.Enabled {
+		_, err := auth.NewValidator(conf)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("auth: %s", err))
+		}
+	}
+
+	_, err := runner.NewHistoryStore(conf)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("runHistory: %s", err))
+	}
+
+	for _, location := range conf.BackupLocations {
+		err := storage.ValidateLocation(conf, location)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if !location.Enabled {
+			continue
+		}
+
+		_, err = storage.Open(ctx, location)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("backup location %s: %s", location.Name, err))
+		}
+	}
+
+	_, err = notify.New(conf.Notifications)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("notifications: %s", err))
+	}
+
+	err = export.Validate(conf.Job.Route53Backup.Exports)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	return problems
+}
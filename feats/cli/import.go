@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+	"go.dfds.cloud/oops/feats/restore"
+	"go.uber.org/zap"
+)
+
+// importZoneFile upserts the records of a BIND zone file into a hosted zone of an account, creating the hosted zone if it
+// doesn't exist. Existing records with different values are only overwritten with --overwrite.
+func importZoneFile(ctx context.Context, conf config.Config, args []string) int {
+	fs := newFlagSet("import")
+	account := fs.String("account", "", "AWS account to import into")
+	zone := fs.String("zone", "", "name of the zone, used as origin of the zone file")
+	overwrite := fs.Bool("overwrite", false, "replace existing records that conflict with the zone file")
+	dryRun := fs.Bool("dry-run", false, "only print the planned changes")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		if isHelp(err) {
+			return ExitOk
+		}
+		return ExitUsage
+	}
+	if len(positional) != 1 {
+		return usageError(fs, "expected exactly one zone file")
+	}
+	if *account == "" {
+		return usageError(fs, "--account is required")
+	}
+	if *zone == "" {
+		return usageError(fs, "--zone is required")
+	}
+	name := normaliseZone(*zone)
+
+	var content io.Reader = os.Stdin
+	if positional[0] != "-" {
+		f, err := os.Open(positional[0])
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return ExitFailure
+		}
+		defer f.Close()
+		content = f
+	}
+
+	records, warnings, err := oopsAws.ParseZoneFile(content, name)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to parse zone file %s: %s\n", positional[0], err)
+		return ExitFailure
+	}
+
+	ctx = oopsAws.WithSessionName(ctx, oopsAws.SessionName("import", ""))
+	sessions, err := handlers.AssumeRoleForAccounts(ctx, oopsAws.NewConfigFactory(conf.Job.Route53Backup.Aws), []string{*account}, conf.Job.Route53Backup.AssumeRole, handlers.Route53BackupRoleOptions(conf))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	session, ok := sessions[*account]
+	if !ok {
+		fmt.Fprintf(stderr, "unable to assume role %s in account %s\n", conf.Job.Route53Backup.AssumeRole, *account)
+		return ExitFailure
+	}
+	client := oopsAws.NewRoute53Client(session.SessionConfig, oopsAws.NewRoute53Limiter(conf.Job.Route53Backup.RequestsPerSecond))
+
+	plan, err := restore.PlanImport(ctx, client, name, records)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	plan.Warnings = append(warnings, plan.Warnings...)
+
+	hostedZone := plan.HostedZoneId
+	if hostedZone == "" {
+		hostedZone = "will be created"
+	}
+	fmt.Fprintf(stdout, "%s (%s): %d changes, %d conflicts\n", plan.Zone, hostedZone, len(plan.Changes), len(plan.Conflicts))
+	for _, change := range plan.Changes {
+		fmt.Fprintf(stdout, "  %s\n", restore.DescribeChange(change))
+	}
+	for _, change := range plan.Conflicts {
+		fmt.Fprintf(stdout, "  conflict: %s replaces existing records\n", restore.DescribeChange(change))
+	}
+	for _, warning := range plan.Warnings {
+		fmt.Fprintf(stdout, "  warning: %s\n", warning)
+	}
+
+	if *dryRun || (len(plan.Changes) == 0 && plan.HostedZoneId != "") {
+		return ExitOk
+	}
+
+	hostedZoneId, nameServers, err := restore.ApplyImport(ctx, client, plan, *overwrite)
+	if err != nil {
+		if errors.Is(err, restore.ErrImportConflicts) {
+			fmt.Fprintf(stderr, "%s, use --overwrite to replace them\n", err)
+		} else {
+			fmt.Fprintf(stderr, "failed to import zone %s: %s\n", plan.Zone, err)
+		}
+		return ExitFailure
+	}
+	if len(nameServers) > 0 {
+		fmt.Fprintf(stdout, "created hosted zone %s, delegate %s to:\n", hostedZoneId, plan.Zone)
+		for _, ns := range nameServers {
+			fmt.Fprintf(stdout, "  %s\n", ns)
+		}
+	}
+	logging.Logger.Info("Zone imported", zap.String("account", *account), zap.String("zone", plan.Zone), zap.String("hostedZoneId", hostedZoneId), zap.Int("changes", len(plan.Changes)))
+
+	return ExitOk
+}
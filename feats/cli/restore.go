@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+	"go.dfds.cloud/oops/feats/restore"
+	"go.uber.org/zap"
+)
+
+// restoreBackup upserts the records of the backup into the live hosted zones of an account. Records missing from the backup
+// are only deleted with --prune.
+func restoreBackup(ctx context.Context, conf config.Config, args []string) int {
+	fs := newFlagSet("restore")
+	account := fs.String("account", "", "AWS account to restore")
+	zone := fs.String("zone", "", "only restore this zone")
+	prune := fs.Bool("prune", false, "delete records not present in the backup")
+	dryRun := fs.Bool("dry-run", false, "only print the planned changes")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		if isHelp(err) {
+			return ExitOk
+		}
+		return ExitUsage
+	}
+	if len(positional) != 1 {
+		return usageError(fs, "expected exactly one source")
+	}
+	if *account == "" {
+		return usageError(fs, "--account is required")
+	}
+
+	content, err := loadSource(ctx, conf, positional[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+
+	records, err := handlers.ReadBackupRecords(bytes.NewReader(content))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+
+	zones, ok := records[*account]
+	if !ok {
+		fmt.Fprintf(stderr, "account %s is not part of the backup\n", *account)
+		return ExitFailure
+	}
+
+	var names []string
+	for name := range zones {
+		if *zone == "" || normaliseZone(*zone) == name {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Fprintf(stderr, "zone %s of account %s is not part of the backup\n", *zone, *account)
+		return ExitFailure
+	}
+	sort.Strings(names)
+
+	ctx = oopsAws.WithSessionName(ctx, oopsAws.SessionName("restore", ""))
+	sessions, err := handlers.AssumeRoleForAccounts(ctx, oopsAws.NewConfigFactory(conf.Job.Route53Backup.Aws), []string{*account}, conf.Job.Route53Backup.AssumeRole, handlers.Route53BackupRoleOptions(conf))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	session, ok := sessions[*account]
+	if !ok {
+		fmt.Fprintf(stderr, "unable to assume role %s in account %s\n", conf.Job.Route53Backup.AssumeRole, *account)
+		return ExitFailure
+	}
+	client := oopsAws.NewRoute53Client(session.SessionConfig, oopsAws.NewRoute53Limiter(conf.Job.Route53Backup.RequestsPerSecond))
+
+	code := ExitOk
+	for _, name := range names {
+		hostedZoneId, err := restore.FindHostedZoneId(ctx, client, name)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			code = ExitFailure
+			continue
+		}
+
+		current, err := restore.FetchZoneRecords(ctx, client, hostedZoneId)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to fetch records of zone %s: %s\n", name, err)
+			code = ExitFailure
+			continue
+		}
+
+		changes := restore.PlanZone(name, current, zones[name], *prune)
+		fmt.Fprintf(stdout, "%s (%s): %d changes\n", name, hostedZoneId, len(changes))
+		for _, change := range changes {
+			fmt.Fprintf(stdout, "  %s\n", restore.DescribeChange(change))
+		}
+
+		if *dryRun || len(changes) == 0 {
+			continue
+		}
+
+		err = restore.Apply(ctx, client, hostedZoneId, changes)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to restore zone %s: %s\n", name, err)
+			code = ExitFailure
+			continue
+		}
+		logging.Logger.Info("Zone restored", zap.String("account", *account), zap.String("zone", name), zap.Int("changes", len(changes)))
+	}
+
+	return code
+}
+
+func normaliseZone(zone string) string {
+	if zone != "" && zone[len(zone)-1] != '.' {
+		return zone + "."
+	}
+	return zone
+}
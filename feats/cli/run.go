@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.dfds.cloud/oops/feats/notify"
+	"go.uber.org/zap"
+)
+
+// runJob runs a job once, as used by a Kubernetes CronJob, or repeatedly until interrupted
+func runJob(ctx context.Context, conf config.Config, args []string) int {
+	fs := newFlagSet("run")
+	once := fs.Bool("once", false, "run the job a single time and exit with its status")
+	interval := fs.Duration("interval", time.Hour, "time between runs unless --once is set")
+	dryRun := fs.Bool("dry-run", false, "skip uploads, notifications and other side effects")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		if isHelp(err) {
+			return ExitOk
+		}
+		return ExitUsage
+	}
+	if len(positional) != 1 {
+		return usageError(fs, "expected exactly one job")
+	}
+	if *interval <= 0 {
+		return usageError(fs, "interval must be positive")
+	}
+	job := positional[0]
+
+	// Runs are still logged, but not persisted, as the CLI may run alongside the server sharing its history store
+	r := runner.New(ctx, runner.NewMemoryHistory(1))
+	handlers.Register(r, config.NewStore(conf))
+
+	if err := notify.Init(conf.Notifications); err != nil {
+		fmt.Fprintf(stderr, "failed to set up notifications: %s\n", err)
+		return ExitFailure
+	}
+	r.OnFinish(notify.RunFinished)
+
+	if *dryRun {
+		ctx = runner.WithDryRun(ctx)
+	}
+
+	for {
+		code := runOnce(ctx, r, job)
+		if *once || code == ExitUsage {
+			return code
+		}
+
+		select {
+		case <-ctx.Done():
+			return code
+		case <-time.After(*interval):
+		}
+	}
+}
+
+func runOnce(ctx context.Context, r *runner.Runner, job string) int {
+	run, err := r.Run(ctx, job, runner.TriggerCli)
+	if errors.Is(err, runner.ErrJobNotFound) {
+		fmt.Fprintf(stderr, "unknown job %s, available jobs: %v\n", job, r.Jobs())
+		return ExitUsage
+	}
+	if err != nil {
+		return ExitFailure
+	}
+
+	if report, ok := run.Report.(runner.PartialFailure); ok && len(report.FailedItems()) > 0 {
+		logging.Logger.Warn("Job run partially failed", zap.String("job", job), zap.Strings("failed", report.FailedItems()))
+		return ExitPartialFailure
+	}
+
+	return ExitOk
+}
@@ -0,0 +1,347 @@
+// Package delegation checks that the NS records delegating a subzone, often kept in another account than the subzone
+// itself, match the name servers of the subzone
+package delegation
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"go.dfds.cloud/oops/core/logging"
+	selfserviceapi "go.dfds.cloud/oops/core/ssu/selfservice-api"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// queryConcurrency limits the name servers queried at once
+const queryConcurrency = 8
+
+const (
+	// ProblemMismatch is a delegation whose name servers differ from the apex NS records of the subzone
+	ProblemMismatch = "ns-mismatch"
+	// ProblemMissing is a subzone without NS records in the closest backed up parent zone, it doesn't resolve
+	ProblemMissing = "missing-delegation"
+	// ProblemLame is a delegation to name servers that don't answer authoritatively for the subzone
+	ProblemLame = "lame-delegation"
+)
+
+// Problems are all the problems, in the order they're reported in metrics
+var Problems = []string{ProblemMismatch, ProblemMissing, ProblemLame}
+
+// Zone is a public hosted zone with its records
+type Zone struct {
+	Account string
+	Name    string
+	Records []route53Types.ResourceRecordSet
+}
+
+// Issue is a problem with the delegation of a subzone
+type Issue struct {
+	Problem string `json:"problem"`
+	Zone    string `json:"zone"`
+	// Account is the account of the subzone, empty for delegations to zones outside the backup
+	Account       string `json:"account,omitempty"`
+	ParentZone    string `json:"parentZone"`
+	ParentAccount string `json:"parentAccount"`
+	// Missing are name servers of the subzone the parent doesn't delegate to, Extra the ones it delegates to in addition
+	Missing []string `json:"missing,omitempty"`
+	Extra   []string `json:"extra,omitempty"`
+	// Lame are the delegated name servers not answering authoritatively
+	Lame    []string `json:"lame,omitempty"`
+	Message string   `json:"message"`
+}
+
+// CapabilityIssues are the issues of the subzones in the accounts of a capability. Issues of accounts without a known
+// capability are grouped with empty capability fields.
+type CapabilityIssues struct {
+	CapabilityId   string  `json:"capabilityId,omitempty"`
+	CapabilityName string  `json:"capabilityName,omitempty"`
+	Issues         []Issue `json:"issues"`
+}
+
+// Report is stored as delegations.json in the backup tarball
+type Report struct {
+	CreatedAt    time.Time          `json:"createdAt"`
+	Capabilities []CapabilityIssues `json:"capabilities"`
+}
+
+type Options struct {
+	// Querier asks the delegated name servers about the subzones to find lame delegations, they aren't looked for without one
+	Querier Querier
+	// Partial is set when accounts or zones are missing from the backup. Missing delegations aren't reported then, as the
+	// closest parent zone of a subzone might be among the missing ones.
+	Partial bool
+}
+
+// Check compares the delegations in the zones with the subzones among them
+func Check(ctx context.Context, zones []Zone, opts Options) []Issue {
+	byName := make(map[string][]Zone)
+	for _, zone := range zones {
+		name := normaliseName(zone.Name)
+		byName[name] = append(byName[name], zone)
+	}
+	for _, candidates := range byName {
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Account < candidates[j].Account })
+	}
+
+	var issues []Issue
+	var delegations []delegationRef
+	delegated := make(map[string]bool)
+
+	for _, parent := range zones {
+		parentName := normaliseName(parent.Name)
+		for _, rec := range parent.Records {
+			name := normaliseName(aws.ToString(rec.Name))
+			if rec.Type != route53Types.RRTypeNs || name == parentName {
+				continue
+			}
+			delegated[parentName+"|"+name] = true
+
+			servers := nameServers(rec)
+			ref := delegationRef{zone: name, parent: parent, servers: servers}
+			if children := byName[name]; len(children) > 0 {
+				ref.account = children[0].Account
+				if issue, ok := compare(parent, children, servers); ok {
+					issues = append(issues, issue)
+				}
+			}
+			delegations = append(delegations, ref)
+		}
+	}
+
+	for _, zone := range zones {
+		name := normaliseName(zone.Name)
+		parentName := closestParent(name, byName)
+		if parentName == "" || delegated[parentName+"|"+name] || opts.Partial {
+			continue
+		}
+		if delegatedBetween(name, parentName, delegated) {
+			// A zone outside the backup sits between them, that one is in charge of delegating the subzone
+			continue
+		}
+		parent := byName[parentName][0]
+		issues = append(issues, Issue{
+			Problem:       ProblemMissing,
+			Zone:          zone.Name,
+			Account:       zone.Account,
+			ParentZone:    parent.Name,
+			ParentAccount: parent.Account,
+			Message:       fmt.Sprintf("%s has no NS records for %s, the zone doesn't resolve", parent.Name, zone.Name),
+		})
+	}
+
+	if opts.Querier != nil {
+		issues = append(issues, findLame(ctx, delegations, opts.Querier)...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Zone != issues[j].Zone {
+			return issues[i].Zone < issues[j].Zone
+		}
+		return issues[i].Problem < issues[j].Problem
+	})
+
+	return issues
+}
+
+// Group sorts the issues into the capabilities owning the accounts of the subzones, or of the parent zones for
+// delegations to zones outside the backup. Capabilities are sorted by name, the group without capability comes last.
+func Group(issues []Issue, owners map[string]selfserviceapi.AccountOwner) []CapabilityIssues {
+	groups := make(map[string]*CapabilityIssues)
+	var payload []*CapabilityIssues
+
+	for _, issue := range issues {
+		account := issue.Account
+		if account == "" {
+			account = issue.ParentAccount
+		}
+		owner := owners[account]
+
+		group, ok := groups[owner.CapabilityId]
+		if !ok {
+			group = &CapabilityIssues{CapabilityId: owner.CapabilityId, CapabilityName: owner.CapabilityName}
+			groups[owner.CapabilityId] = group
+			payload = append(payload, group)
+		}
+		group.Issues = append(group.Issues, issue)
+	}
+
+	sort.SliceStable(payload, func(i, j int) bool {
+		a, b := payload[i], payload[j]
+		if (a.CapabilityId == "") != (b.CapabilityId == "") {
+			return b.CapabilityId == ""
+		}
+		return a.CapabilityName < b.CapabilityName
+	})
+
+	result := make([]CapabilityIssues, 0, len(payload))
+	for _, group := range payload {
+		result = append(result, *group)
+	}
+	return result
+}
+
+// delegationRef is a delegation found in a parent zone
+type delegationRef struct {
+	zone    string
+	account string
+	parent  Zone
+	servers []string
+}
+
+// compare returns a mismatch unless the delegation matches one of the zones named like the subzone. Hosted zones can
+// share a name, only the one delegated to is live.
+func compare(parent Zone, children []Zone, servers []string) (Issue, bool) {
+	var apex []string
+	for _, child := range children {
+		apex = apexNameServers(child)
+		if slices.Equal(apex, servers) {
+			return Issue{}, false
+		}
+	}
+
+	child := children[0]
+	apex = apexNameServers(child)
+	missing := difference(apex, servers)
+	extra := difference(servers, apex)
+
+	return Issue{
+		Problem:       ProblemMismatch,
+		Zone:          child.Name,
+		Account:       child.Account,
+		ParentZone:    parent.Name,
+		ParentAccount: parent.Account,
+		Missing:       missing,
+		Extra:         extra,
+		Message: fmt.Sprintf("%s delegates %s to %s, but the zone is served by %s", parent.Name, child.Name,
+			strings.Join(servers, ", "), strings.Join(apex, ", ")),
+	}, true
+}
+
+// findLame queries every distinct name server of the delegations once for the SOA of the delegated zone
+func findLame(ctx context.Context, delegations []delegationRef, querier Querier) []Issue {
+	authoritative := make(map[string]bool)
+	mu := &sync.Mutex{}
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(queryConcurrency)
+
+	seen := make(map[string]bool)
+	for _, ref := range delegations {
+		for _, server := range ref.servers {
+			key := server + "|" + ref.zone
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			group.Go(func() error {
+				ok, err := querier.Authoritative(groupCtx, server, ref.zone)
+				if err != nil {
+					logging.Logger.Debug("Unable to query name server", zap.String("server", server), zap.String("zone", ref.zone), zap.Error(err))
+					return nil
+				}
+				mu.Lock()
+				authoritative[key] = ok
+				mu.Unlock()
+				return nil
+			})
+		}
+	}
+	group.Wait()
+
+	var issues []Issue
+	for _, ref := range delegations {
+		var lame []string
+		for _, server := range ref.servers {
+			if ok, queried := authoritative[server+"|"+ref.zone]; queried && !ok {
+				lame = append(lame, server)
+			}
+		}
+		if len(lame) == 0 {
+			continue
+		}
+		issues = append(issues, Issue{
+			Problem:       ProblemLame,
+			Zone:          ref.zone,
+			Account:       ref.account,
+			ParentZone:    ref.parent.Name,
+			ParentAccount: ref.parent.Account,
+			Lame:          lame,
+			Message:       fmt.Sprintf("%s delegates %s to %s, which don't answer for it", ref.parent.Name, ref.zone, strings.Join(lame, ", ")),
+		})
+	}
+
+	return issues
+}
+
+// closestParent returns the longest zone name among byName the name is a subdomain of, or the empty string
+func closestParent(name string, byName map[string][]Zone) string {
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	for i := 1; i < len(labels); i++ {
+		candidate := strings.Join(labels[i:], ".") + "."
+		if _, ok := byName[candidate]; ok {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// delegatedBetween tells whether the parent zone delegates a name between it and the subzone, i.e. whether the subzone
+// is below a zone delegated elsewhere
+func delegatedBetween(name string, parentName string, delegated map[string]bool) bool {
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	for i := 1; i < len(labels); i++ {
+		candidate := strings.Join(labels[i:], ".") + "."
+		if candidate == parentName {
+			return false
+		}
+		if delegated[parentName+"|"+candidate] {
+			return true
+		}
+	}
+	return false
+}
+
+func apexNameServers(zone Zone) []string {
+	name := normaliseName(zone.Name)
+	for _, rec := range zone.Records {
+		if rec.Type == route53Types.RRTypeNs && normaliseName(aws.ToString(rec.Name)) == name {
+			return nameServers(rec)
+		}
+	}
+	return nil
+}
+
+// nameServers returns the sorted, lower case values of an NS record, with trailing dot
+func nameServers(rec route53Types.ResourceRecordSet) []string {
+	var payload []string
+	for _, value := range rec.ResourceRecords {
+		payload = append(payload, normaliseName(aws.ToString(value.Value)))
+	}
+	sort.Strings(payload)
+	return slices.Compact(payload)
+}
+
+func normaliseName(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+func difference(a []string, b []string) []string {
+	var payload []string
+	for _, value := range a {
+		if !slices.Contains(b, value) {
+			payload = append(payload, value)
+		}
+	}
+	return payload
+}
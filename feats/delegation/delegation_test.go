@@ -0,0 +1,108 @@
+package delegation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dfds.cloud/oops/core/aws/fake"
+	"go.dfds.cloud/oops/core/logging"
+	selfserviceapi "go.dfds.cloud/oops/core/ssu/selfservice-api"
+	"go.uber.org/zap"
+)
+
+type fakeQuerier struct {
+	// authoritative maps server|zone to the answer, missing entries time out
+	authoritative map[string]bool
+}
+
+func (q *fakeQuerier) Authoritative(_ context.Context, server string, zone string) (bool, error) {
+	ok, found := q.authoritative[server+"|"+zone]
+	if !found {
+		return false, errors.New("timeout")
+	}
+	return ok, nil
+}
+
+func TestCheck(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	zones := []Zone{
+		{Account: "111111111111", Name: "example.com.", Records: []route53Types.ResourceRecordSet{
+			fake.Record("example.com.", route53Types.RRTypeNs, 172800, "ns-1.awsdns-01.org.", "ns-2.awsdns-02.com."),
+			// Matches the subzone, in another order and case
+			fake.Record("team.example.com.", route53Types.RRTypeNs, 172800, "NS-4.awsdns-04.com.", "ns-3.awsdns-03.org."),
+			// The subzone was recreated with new name servers
+			fake.Record("app.example.com.", route53Types.RRTypeNs, 172800, "ns-5.awsdns-05.org.", "ns-6.awsdns-06.com."),
+			// Delegated outside the backup
+			fake.Record("vendor.example.com.", route53Types.RRTypeNs, 172800, "ns1.vendor.example.net."),
+		}},
+		{Account: "222222222222", Name: "team.example.com.", Records: []route53Types.ResourceRecordSet{
+			fake.Record("team.example.com.", route53Types.RRTypeNs, 172800, "ns-3.awsdns-03.org.", "ns-4.awsdns-04.com."),
+		}},
+		{Account: "222222222222", Name: "app.example.com.", Records: []route53Types.ResourceRecordSet{
+			fake.Record("app.example.com.", route53Types.RRTypeNs, 172800, "ns-5.awsdns-05.org.", "ns-7.awsdns-07.com."),
+		}},
+		// Nested below team.example.com. which doesn't delegate it
+		{Account: "333333333333", Name: "dev.team.example.com.", Records: []route53Types.ResourceRecordSet{
+			fake.Record("dev.team.example.com.", route53Types.RRTypeNs, 172800, "ns-8.awsdns-08.org."),
+		}},
+		// Nested below vendor.example.com. which example.com. delegates outside the backup
+		{Account: "333333333333", Name: "api.vendor.example.com.", Records: []route53Types.ResourceRecordSet{
+			fake.Record("api.vendor.example.com.", route53Types.RRTypeNs, 172800, "ns-10.awsdns-10.org."),
+		}},
+		// No parent in the backup
+		{Account: "333333333333", Name: "example.org.", Records: []route53Types.ResourceRecordSet{
+			fake.Record("example.org.", route53Types.RRTypeNs, 172800, "ns-9.awsdns-09.org."),
+		}},
+	}
+
+	issues := Check(context.Background(), zones, Options{})
+	require.Len(t, issues, 2)
+	assert.Equal(t, ProblemMismatch, issues[0].Problem)
+	assert.Equal(t, "app.example.com.", issues[0].Zone)
+	assert.Equal(t, "222222222222", issues[0].Account)
+	assert.Equal(t, "111111111111", issues[0].ParentAccount)
+	assert.Equal(t, []string{"ns-7.awsdns-07.com."}, issues[0].Missing)
+	assert.Equal(t, []string{"ns-6.awsdns-06.com."}, issues[0].Extra)
+	assert.Equal(t, ProblemMissing, issues[1].Problem)
+	assert.Equal(t, "dev.team.example.com.", issues[1].Zone)
+	assert.Equal(t, "team.example.com.", issues[1].ParentZone)
+	assert.Equal(t, "team.example.com. has no NS records for dev.team.example.com., the zone doesn't resolve", issues[1].Message)
+
+	// The closest parent might be missing from a partial backup, so missing delegations aren't reported then
+	issues = Check(context.Background(), zones, Options{Partial: true})
+	require.Len(t, issues, 1)
+	assert.Equal(t, ProblemMismatch, issues[0].Problem)
+
+	// Servers not answering are lame, ones that can't be reached are left out
+	issues = Check(context.Background(), zones, Options{Querier: &fakeQuerier{authoritative: map[string]bool{
+		"ns-3.awsdns-03.org.|team.example.com.":       true,
+		"ns-4.awsdns-04.com.|team.example.com.":       true,
+		"ns-5.awsdns-05.org.|app.example.com.":        true,
+		"ns-6.awsdns-06.com.|app.example.com.":        false,
+		"ns1.vendor.example.net.|vendor.example.com.": false,
+	}}})
+	require.Len(t, issues, 4)
+	assert.Equal(t, ProblemLame, issues[0].Problem)
+	assert.Equal(t, []string{"ns-6.awsdns-06.com."}, issues[0].Lame)
+	assert.Equal(t, ProblemLame, issues[3].Problem)
+	assert.Equal(t, "vendor.example.com.", issues[3].Zone)
+	assert.Empty(t, issues[3].Account)
+
+	grouped := Group(issues, map[string]selfserviceapi.AccountOwner{
+		"111111111111": {CapabilityId: "web-abcd", CapabilityName: "web"},
+		"222222222222": {CapabilityId: "apps-efgh", CapabilityName: "apps"},
+	})
+	require.Len(t, grouped, 3)
+	assert.Equal(t, "apps", grouped[0].CapabilityName)
+	assert.Len(t, grouped[0].Issues, 2)
+	// Delegations outside the backup belong to the owner of the parent zone
+	assert.Equal(t, "web", grouped[1].CapabilityName)
+	assert.Equal(t, "vendor.example.com.", grouped[1].Issues[0].Zone)
+	assert.Empty(t, grouped[2].CapabilityId)
+	assert.Equal(t, "dev.team.example.com.", grouped[2].Issues[0].Zone)
+}
@@ -0,0 +1,76 @@
+package delegation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Querier asks name servers about zones. Queries answer false only when the server definitely doesn't serve the zone,
+// failures to find out are returned as errors.
+type Querier interface {
+	// Authoritative reports whether the name server answers authoritatively for the SOA of the zone
+	Authoritative(ctx context.Context, server string, zone string) (bool, error)
+}
+
+type dnsQuerier struct {
+	resolver *net.Resolver
+	// timeout limits the lookup of the name server's addresses, the queries are limited by the client
+	timeout time.Duration
+	client  *dns.Client
+}
+
+// NewQuerier returns a querier resolving name servers with the system resolver, each query limited to timeout
+func NewQuerier(timeout time.Duration) Querier {
+	return &dnsQuerier{
+		resolver: net.DefaultResolver,
+		timeout:  timeout,
+		client:   &dns.Client{Timeout: timeout},
+	}
+}
+
+// Authoritative asks the addresses of the name server in turn, until one answers
+func (q *dnsQuerier) Authoritative(ctx context.Context, server string, zone string) (bool, error) {
+	lookupCtx, cancel := context.WithTimeout(ctx, q.timeout)
+	addrs, err := q.resolver.LookupHost(lookupCtx, server)
+	cancel()
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		// A name server that doesn't resolve can't answer for anything
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if len(addrs) == 0 {
+		return false, nil
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(zone), dns.TypeSOA)
+	msg.RecursionDesired = false
+
+	var errs []error
+	for _, addr := range addrs {
+		resp, _, err := q.client.ExchangeContext(ctx, msg, net.JoinHostPort(addr, "53"))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if resp.Rcode != dns.RcodeSuccess || !resp.Authoritative {
+			return false, nil
+		}
+		for _, rr := range resp.Answer {
+			if _, ok := rr.(*dns.SOA); ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	return false, fmt.Errorf("querying %s for %s: %w", server, zone, errors.Join(errs...))
+}
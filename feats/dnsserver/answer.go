@@ -0,0 +1,139 @@
+package dnsserver
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// maxCnameChain bounds the CNAMEs followed within the served zones
+const maxCnameChain = 8
+
+// answer fills the reply to a query for a name of the zone, following CNAMEs within the zone and answering for
+// wildcards and delegations like an authoritative server does
+func (z *zone) answer(m *dns.Msg, qname string, qtype uint16) {
+	for hop := 0; hop < maxCnameChain; hop++ {
+		if ns := z.delegation(qname, qtype); ns != nil {
+			// A referral, unless the chain left the zone's authority part way
+			if len(m.Answer) == 0 {
+				m.Authoritative = false
+			}
+			m.Ns = append(m.Ns, ns...)
+			m.Extra = append(m.Extra, z.glue(ns)...)
+			return
+		}
+
+		rrsets, ok := z.rrsets[qname]
+		if !ok && !z.names[qname] {
+			rrsets, ok = z.wildcard(qname)
+		}
+		if !ok {
+			if !z.names[qname] {
+				m.Rcode = dns.RcodeNameError
+			}
+			m.Ns = append(m.Ns, z.negativeSoa())
+			return
+		}
+
+		if qtype == dns.TypeANY {
+			for _, rrs := range rrsets {
+				m.Answer = append(m.Answer, rrs...)
+			}
+			return
+		}
+		if rrs, ok := rrsets[qtype]; ok {
+			m.Answer = append(m.Answer, rrs...)
+			return
+		}
+
+		cname, ok := rrsets[dns.TypeCNAME]
+		if !ok {
+			m.Ns = append(m.Ns, z.negativeSoa())
+			return
+		}
+		m.Answer = append(m.Answer, cname...)
+		target := dns.CanonicalName(cname[0].(*dns.CNAME).Target)
+		if !dns.IsSubDomain(z.name, target) {
+			return
+		}
+		qname = target
+	}
+}
+
+// delegation returns the NS records of the zone cut above or at the name, DS records are answered by the parent side
+func (z *zone) delegation(qname string, qtype uint16) []dns.RR {
+	labels := dns.SplitDomainName(qname)
+	apexLabels := dns.CountLabel(z.name)
+
+	for i := len(labels) - apexLabels - 1; i >= 0; i-- {
+		name := dns.Fqdn(strings.Join(labels[i:], "."))
+		if name == qname && qtype == dns.TypeDS {
+			return nil
+		}
+		if ns, ok := z.rrsets[name][dns.TypeNS]; ok {
+			return ns
+		}
+	}
+	return nil
+}
+
+// glue returns the addresses of name servers within the zone
+func (z *zone) glue(ns []dns.RR) []dns.RR {
+	var payload []dns.RR
+	for _, rr := range ns {
+		host := dns.CanonicalName(rr.(*dns.NS).Ns)
+		if !dns.IsSubDomain(z.name, host) {
+			continue
+		}
+		payload = append(payload, z.rrsets[host][dns.TypeA]...)
+		payload = append(payload, z.rrsets[host][dns.TypeAAAA]...)
+	}
+	return payload
+}
+
+// wildcard synthesises the records of the name from the wildcard at its closest encloser, if there is one
+func (z *zone) wildcard(qname string) (map[uint16][]dns.RR, bool) {
+	encloser := qname
+	for encloser != z.name {
+		i, _ := dns.NextLabel(encloser, 0)
+		encloser = encloser[i:]
+		if z.names[encloser] {
+			break
+		}
+	}
+
+	rrsets, ok := z.rrsets["*."+encloser]
+	if !ok {
+		return nil, false
+	}
+
+	payload := make(map[uint16][]dns.RR, len(rrsets))
+	for rrtype, rrs := range rrsets {
+		for _, rr := range rrs {
+			synthesised := dns.Copy(rr)
+			synthesised.Header().Name = qname
+			payload[rrtype] = append(payload[rrtype], synthesised)
+		}
+	}
+	return payload, true
+}
+
+// negativeSoa returns the SOA for negative answers, with the TTL negative answers are cached for
+func (z *zone) negativeSoa() dns.RR {
+	soa := dns.Copy(z.soa).(*dns.SOA)
+	soa.Hdr.Ttl = min(soa.Hdr.Ttl, soa.Minttl)
+	return soa
+}
+
+// transferRecords returns the zone framed by its SOA, as sent in AXFR responses
+func (z *zone) transferRecords() []dns.RR {
+	payload := []dns.RR{z.soa}
+	for _, rrsets := range z.rrsets {
+		for rrtype, rrs := range rrsets {
+			if rrtype != dns.TypeSOA {
+				payload = append(payload, rrs...)
+			}
+		}
+	}
+	return append(payload, z.soa)
+}
@@ -0,0 +1,312 @@
+// Package dnsserver serves the zones of the latest Route53 backup authoritatively over UDP and TCP, so there are answers
+// when Route53 or the AWS organisation is unavailable. External secondaries can transfer the zones with AXFR and IXFR.
+package dnsserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/core/metrics"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.dfds.cloud/oops/feats/storage"
+	"go.uber.org/zap"
+)
+
+// udpSize is the largest UDP response, the size recommended to avoid fragmentation
+const udpSize = 1232
+
+// transferBatch is the number of records per AXFR message
+const transferBatch = 500
+
+type Server struct {
+	store *config.Store
+	// Resolver resolves alias targets outside the backup, nil leaves them out
+	Resolver Resolver
+
+	zones   atomic.Pointer[zoneSet]
+	mu      sync.Mutex
+	loaded  string
+	refresh chan struct{}
+}
+
+func New(store *config.Store) *Server {
+	return &Server{
+		store:    store,
+		Resolver: net.DefaultResolver,
+		refresh:  make(chan struct{}, 1),
+	}
+}
+
+// Run serves DNS until ctx is done. The zones are reloaded whenever a new backup shows up in the location, checked every
+// refresh interval and after every backup run.
+func (s *Server) Run(ctx context.Context) error {
+	settings := s.store.Current().SecondaryDns
+	servers := []*dns.Server{
+		{Addr: settings.Listen, Net: "udp", Handler: s, UDPSize: udpSize},
+		{Addr: settings.Listen, Net: "tcp", Handler: s},
+	}
+
+	errs := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			errs <- server.ListenAndServe()
+		}()
+	}
+	logging.Logger.Info("Serving secondary DNS", zap.String("listen", settings.Listen))
+
+	go s.watch(ctx, settings.RefreshInterval)
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+	for _, server := range servers {
+		_ = server.Shutdown()
+	}
+	return err
+}
+
+// RunFinished checks for a new backup after a backup run, it's meant for runner.OnFinish
+func (s *Server) RunFinished(_ context.Context, run runner.Run) {
+	if run.Job != handlers.Route53BackupJob || run.Status != runner.StatusSucceeded {
+		return
+	}
+	select {
+	case s.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Server) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.Refresh(ctx)
+		if err != nil {
+			logging.Logger.Error("Failed to reload the zones of the secondary DNS, serving the previous ones", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.refresh:
+		}
+	}
+}
+
+// Refresh loads the newest backup of the configured location, unless it's already being served
+func (s *Server) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conf := s.store.Current()
+	location, ok := sourceLocation(conf)
+	if !ok {
+		return errors.New("no enabled backup location to load zones from")
+	}
+
+	store, err := storage.Open(ctx, location)
+	if err != nil {
+		return err
+	}
+	backup, found, err := storage.NewestBackup(ctx, store, handlers.Route53BackupArtifact)
+	if err != nil {
+		return err
+	}
+	if !found {
+		logging.Logger.Info("No backup to serve zones from yet", zap.String("location", location.Name))
+		return nil
+	}
+	if backup.Key == s.loaded {
+		return nil
+	}
+
+	body, _, err := store.Get(ctx, backup.Key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	var content bytes.Buffer
+	_, err = content.ReadFrom(body)
+	if err != nil {
+		return err
+	}
+
+	err = s.Load(ctx, content.Bytes(), uint32(backup.Timestamp.Unix()))
+	if err != nil {
+		return fmt.Errorf("backup %s: %w", backup.Id, err)
+	}
+	s.loaded = backup.Key
+	logging.Logger.Info("Secondary DNS serves zones of backup", zap.String("location", location.Name), zap.String("backup", backup.Id), zap.Int("zones", len(s.zones.Load().zones)))
+
+	go s.notify(s.zones.Load(), conf.SecondaryDns.Notify)
+	return nil
+}
+
+// Load replaces the served zones with the ones of the backup tarball, serial is the SOA serial of this version of the zones
+func (s *Server) Load(ctx context.Context, content []byte, serial uint32) error {
+	records, err := handlers.ReadBackupRecords(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	manifest, err := handlers.ReadBackupManifest(content)
+	if err != nil {
+		return err
+	}
+
+	set := loadZones(ctx, records, manifest, loadOptions{
+		serial:      serial,
+		nameServers: s.store.Current().SecondaryDns.NameServers,
+		resolver:    s.Resolver,
+	})
+
+	s.zones.Store(set)
+	metrics.SecondaryDnsZones.Set(float64(len(set.zones)))
+	metrics.SecondaryDnsSerial.Set(float64(serial))
+	return nil
+}
+
+// ServeDNS answers a query from the loaded zones
+func (s *Server) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(req)
+	m.Compress = true
+
+	set := s.zones.Load()
+	switch {
+	case len(req.Question) != 1:
+		m.Rcode = dns.RcodeFormatError
+	case req.Opcode != dns.OpcodeQuery:
+		m.Rcode = dns.RcodeNotImplemented
+	case set == nil:
+		m.Rcode = dns.RcodeServerFailure
+	default:
+		q := req.Question[0]
+		qname := dns.CanonicalName(q.Name)
+		z := set.find(qname)
+		switch {
+		case z == nil || q.Qclass != dns.ClassINET:
+			m.Rcode = dns.RcodeRefused
+		case q.Qtype == dns.TypeAXFR || q.Qtype == dns.TypeIXFR:
+			if qname == z.name && s.transferAllowed(w) {
+				s.transfer(w, req, z)
+				return
+			}
+			m.Rcode = dns.RcodeRefused
+		default:
+			m.Authoritative = true
+			z.answer(m, qname, q.Qtype)
+		}
+	}
+
+	size := dns.MaxMsgSize
+	if _, udp := w.RemoteAddr().(*net.UDPAddr); udp {
+		size = dns.MinMsgSize
+		if opt := req.IsEdns0(); opt != nil {
+			size = int(min(max(opt.UDPSize(), dns.MinMsgSize), udpSize))
+		}
+	}
+	if req.IsEdns0() != nil {
+		m.SetEdns0(udpSize, false)
+	}
+	m.Truncate(size)
+
+	metrics.SecondaryDnsQueries.WithLabelValues(dns.RcodeToString[m.Rcode]).Inc()
+	err := w.WriteMsg(m)
+	if err != nil {
+		logging.Logger.Debug("Failed to write DNS response", zap.Error(err))
+	}
+}
+
+// transferAllowed only allows zone transfers over TCP from the configured networks
+func (s *Server) transferAllowed(w dns.ResponseWriter) bool {
+	addr, ok := w.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	ip, ok := netip.AddrFromSlice(addr.IP)
+	if !ok {
+		return false
+	}
+	ip = ip.Unmap()
+
+	for _, allowed := range s.store.Current().SecondaryDns.AllowTransfer {
+		if prefix, err := netip.ParsePrefix(allowed); err == nil && prefix.Contains(ip) {
+			return true
+		}
+		if addr, err := netip.ParseAddr(allowed); err == nil && addr == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// transfer sends the zone with AXFR. IXFR is only supported as the AXFR fallback of RFC 1995: IXFR requests get the
+// whole zone, or only the SOA if the secondary is up to date, never the differences between serials.
+func (s *Server) transfer(w dns.ResponseWriter, req *dns.Msg, z *zone) {
+	records := z.transferRecords()
+	if req.Question[0].Qtype == dns.TypeIXFR && len(req.Ns) > 0 {
+		if soa, ok := req.Ns[0].(*dns.SOA); ok && soa.Serial == z.soa.Serial {
+			records = []dns.RR{z.soa}
+		}
+	}
+
+	// The channel holds every envelope, so nothing is left blocked on it when the secondary goes away mid transfer
+	ch := make(chan *dns.Envelope, (len(records)+transferBatch-1)/transferBatch)
+	for start := 0; start < len(records); start += transferBatch {
+		ch <- &dns.Envelope{RR: records[start:min(start+transferBatch, len(records))]}
+	}
+	close(ch)
+
+	tr := new(dns.Transfer)
+	err := tr.Out(w, req, ch)
+	if err != nil {
+		logging.Logger.Warn("Zone transfer failed", zap.String("zone", z.name), zap.String("remote", w.RemoteAddr().String()), zap.Error(err))
+		return
+	}
+	metrics.SecondaryDnsQueries.WithLabelValues("TRANSFER").Inc()
+	logging.Logger.Info("Zone transferred", zap.String("zone", z.name), zap.String("remote", w.RemoteAddr().String()), zap.Int("records", len(records)))
+}
+
+// notify tells the secondaries about the new serial of every zone, so they transfer it without waiting for the refresh
+func (s *Server) notify(set *zoneSet, targets []string) {
+	client := &dns.Client{Timeout: 5 * time.Second}
+	for _, target := range targets {
+		for name, z := range set.zones {
+			m := new(dns.Msg)
+			m.SetNotify(name)
+			m.Answer = []dns.RR{z.soa}
+			_, _, err := client.Exchange(m, target)
+			if err != nil {
+				logging.Logger.Warn("Failed to notify secondary", zap.String("secondary", target), zap.String("zone", name), zap.Error(err))
+			}
+		}
+	}
+}
+
+// sourceLocation returns the configured location, or the first enabled one
+func sourceLocation(conf config.Config) (config.BackupLocation, bool) {
+	for _, location := range conf.BackupLocations {
+		if !location.Enabled || !storage.IsRegistered(location.Provider) {
+			continue
+		}
+		if conf.SecondaryDns.Location == "" || conf.SecondaryDns.Location == location.Name {
+			return location, true
+		}
+	}
+	return config.BackupLocation{}, false
+}
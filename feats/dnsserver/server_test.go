@@ -0,0 +1,248 @@
+package dnsserver
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dfds.cloud/oops/core/aws/fake"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/core/util"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+	"go.dfds.cloud/oops/feats/storage"
+	"go.dfds.cloud/oops/feats/storage/memory"
+	"go.uber.org/zap"
+)
+
+type fakeResolver map[string][]netip.Addr
+
+func (f fakeResolver) LookupNetIP(_ context.Context, _ string, host string) ([]netip.Addr, error) {
+	addrs, ok := f[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+// backupTarball builds a backup like the Route53 backup job does
+func backupTarball(t *testing.T) []byte {
+	apex := func(zone string) []route53Types.ResourceRecordSet {
+		return []route53Types.ResourceRecordSet{
+			fake.Record(zone, route53Types.RRTypeSoa, 300, "ns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400"),
+			fake.Record(zone, route53Types.RRTypeNs, 300, "ns-1.awsdns-01.org.", "ns-2.awsdns-02.com."),
+		}
+	}
+
+	primary := fake.Record("app.example.com.", route53Types.RRTypeA, 300, "192.0.2.10")
+	primary.SetIdentifier = aws.String("primary")
+	primary.Failover = route53Types.ResourceRecordSetFailoverPrimary
+	secondary := fake.Record("app.example.com.", route53Types.RRTypeA, 300, "192.0.2.11")
+	secondary.SetIdentifier = aws.String("secondary")
+	secondary.Failover = route53Types.ResourceRecordSetFailoverSecondary
+
+	records := map[string]map[string][]route53Types.ResourceRecordSet{
+		"111111111111": {
+			"example.com.": append(apex("example.com."),
+				fake.Record("www.example.com.", route53Types.RRTypeA, 300, "192.0.2.1"),
+				fake.Record("api.example.com.", route53Types.RRTypeCname, 300, "www.example.com"),
+				fake.Record(`\052.dev.example.com.`, route53Types.RRTypeA, 300, "192.0.2.2"),
+				fake.Record("sub.example.com.", route53Types.RRTypeNs, 300, "ns1.sub.example.com."),
+				fake.Record("ns1.sub.example.com.", route53Types.RRTypeA, 300, "192.0.2.53"),
+				fake.Record("example.com.", route53Types.RRTypeTxt, 300, `"v=spf1 -all"`),
+				fake.Alias("example.com.", route53Types.RRTypeA, "www.example.com."),
+				fake.Alias("cdn.example.com.", route53Types.RRTypeA, "d111111abcdef8.cloudfront.net."),
+				primary, secondary,
+			),
+			"internal.example.": append(apex("internal.example."), fake.Record("db.internal.example.", route53Types.RRTypeA, 300, "10.0.0.1")),
+		},
+	}
+	manifest := handlers.BackupManifest{
+		Job: handlers.Route53BackupJob,
+		Accounts: map[string]handlers.BackupManifestAccount{
+			"111111111111": {Zones: map[string]handlers.BackupManifestZone{
+				"example.com.":      {Id: "/hostedzone/Z1"},
+				"internal.example.": {Id: "/hostedzone/Z2", Private: true},
+			}},
+		},
+	}
+
+	dir := t.TempDir()
+	for name, value := range map[string]any{handlers.RecordsFile: records, handlers.ManifestFile: manifest} {
+		content, err := json.Marshal(value)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0644))
+	}
+	content, err := util.GzipAndTarballDirBuf(dir)
+	require.NoError(t, err)
+	return content
+}
+
+func startServer(t *testing.T) (*Server, string, string) {
+	logging.Logger = zap.NewNop()
+
+	store := memory.New()
+	storage.Register("memory-dns-test", func(_ context.Context, _ config.BackupLocation) (storage.Storage, error) {
+		return store, nil
+	})
+	require.NoError(t, storage.PutBackup(context.Background(), store, handlers.Route53BackupArtifact, backupTarball(t)))
+
+	var conf config.Config
+	conf.BackupLocations = []config.BackupLocation{{Name: "memory", Provider: "memory-dns-test", Enabled: true}}
+	conf.SecondaryDns.AllowTransfer = []string{"127.0.0.0/8"}
+
+	s := New(config.NewStore(conf))
+	s.Resolver = fakeResolver{"d111111abcdef8.cloudfront.net.": {netip.MustParseAddr("198.51.100.7")}}
+	require.NoError(t, s.Refresh(context.Background()))
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	for _, server := range []*dns.Server{{PacketConn: pc, Handler: s}, {Listener: l, Handler: s}} {
+		go server.ActivateAndServe()
+		t.Cleanup(func() { _ = server.Shutdown() })
+	}
+
+	return s, pc.LocalAddr().String(), l.Addr().String()
+}
+
+func query(t *testing.T, addr string, name string, qtype uint16) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	client := &dns.Client{Timeout: 2 * time.Second}
+	resp, _, err := client.Exchange(m, addr)
+	require.NoError(t, err)
+	return resp
+}
+
+func answers(resp *dns.Msg) []string {
+	var payload []string
+	for _, rr := range resp.Answer {
+		payload = append(payload, rr.String())
+	}
+	return payload
+}
+
+func TestServeDNS(t *testing.T) {
+	_, udp, _ := startServer(t)
+
+	resp := query(t, udp, "WWW.example.com.", dns.TypeA)
+	assert.True(t, resp.Authoritative)
+	assert.Equal(t, []string{"www.example.com.\t300\tIN\tA\t192.0.2.1"}, answers(resp))
+
+	// CNAMEs are followed within the zone
+	resp = query(t, udp, "api.example.com.", dns.TypeA)
+	assert.Equal(t, []string{"api.example.com.\t300\tIN\tCNAME\twww.example.com.", "www.example.com.\t300\tIN\tA\t192.0.2.1"}, answers(resp))
+
+	resp = query(t, udp, "anything.dev.example.com.", dns.TypeA)
+	assert.Equal(t, []string{"anything.dev.example.com.\t300\tIN\tA\t192.0.2.2"}, answers(resp))
+
+	// Aliases are resolved from the backup, or through DNS for targets outside it
+	resp = query(t, udp, "example.com.", dns.TypeA)
+	assert.Equal(t, []string{"example.com.\t300\tIN\tA\t192.0.2.1"}, answers(resp))
+	resp = query(t, udp, "cdn.example.com.", dns.TypeA)
+	assert.Equal(t, []string{"cdn.example.com.\t60\tIN\tA\t198.51.100.7"}, answers(resp))
+
+	// Only the failover primary answers
+	resp = query(t, udp, "app.example.com.", dns.TypeA)
+	assert.Equal(t, []string{"app.example.com.\t300\tIN\tA\t192.0.2.10"}, answers(resp))
+
+	resp = query(t, udp, "host.sub.example.com.", dns.TypeA)
+	assert.False(t, resp.Authoritative)
+	assert.Empty(t, resp.Answer)
+	require.Len(t, resp.Ns, 1)
+	assert.Equal(t, "sub.example.com.", resp.Ns[0].Header().Name)
+	require.Len(t, resp.Extra, 1)
+	assert.Equal(t, "ns1.sub.example.com.\t300\tIN\tA\t192.0.2.53", resp.Extra[0].String())
+
+	resp = query(t, udp, "missing.example.com.", dns.TypeA)
+	assert.Equal(t, dns.RcodeNameError, resp.Rcode)
+	require.Len(t, resp.Ns, 1)
+	soa := resp.Ns[0].(*dns.SOA)
+	assert.Greater(t, soa.Serial, uint32(1))
+
+	// NODATA, also for the empty non-terminal above the wildcard
+	for _, name := range []string{"www.example.com.", "dev.example.com."} {
+		resp = query(t, udp, name, dns.TypeMX)
+		assert.Equal(t, dns.RcodeSuccess, resp.Rcode, name)
+		assert.Empty(t, resp.Answer, name)
+		assert.Len(t, resp.Ns, 1, name)
+	}
+
+	// Private zones aren't served
+	assert.Equal(t, dns.RcodeRefused, query(t, udp, "db.internal.example.", dns.TypeA).Rcode)
+	assert.Equal(t, dns.RcodeRefused, query(t, udp, "example.org.", dns.TypeA).Rcode)
+}
+
+func TestTransfer(t *testing.T) {
+	s, udp, tcp := startServer(t)
+
+	// Zones are only transferred over TCP
+	assert.Equal(t, dns.RcodeRefused, query(t, udp, "example.com.", dns.TypeAXFR).Rcode)
+
+	m := new(dns.Msg)
+	m.SetAxfr("example.com.")
+	envelopes, err := new(dns.Transfer).In(m, tcp)
+	require.NoError(t, err)
+
+	var records []dns.RR
+	for envelope := range envelopes {
+		require.NoError(t, envelope.Error)
+		records = append(records, envelope.RR...)
+	}
+	require.NotEmpty(t, records)
+	assert.Equal(t, dns.TypeSOA, records[0].Header().Rrtype)
+	assert.Equal(t, dns.TypeSOA, records[len(records)-1].Header().Rrtype)
+	// SOA twice, apex NS, TXT and alias, www, api, wildcard, delegation, glue, cdn and app
+	assert.Len(t, records, 13)
+
+	// Secondaries that are up to date only get the SOA
+	serial := s.zones.Load().serial
+	m = new(dns.Msg)
+	m.SetIxfr("example.com.", serial, "ns-1.awsdns-01.org.", "awsdns-hostmaster.amazon.com.")
+	envelopes, err = new(dns.Transfer).In(m, tcp)
+	require.NoError(t, err)
+	records = nil
+	for envelope := range envelopes {
+		require.NoError(t, envelope.Error)
+		records = append(records, envelope.RR...)
+	}
+	assert.Len(t, records, 1)
+}
+
+func TestLoadZones_SkipsBadRecords(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	soa := fake.Record("example.com.", route53Types.RRTypeSoa, 300, "ns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400")
+	records := map[string]map[string][]route53Types.ResourceRecordSet{
+		"111111111111": {
+			"example.com.": {soa,
+				fake.Record("www.example.com.", route53Types.RRTypeA, 300, "192.0.2.1"),
+				fake.Record("example.com.", route53Types.RRTypeMx, 300, "mail.example.com.", "10 mx.example.com."),
+				fake.Record("www.example.org.", route53Types.RRTypeA, 300, "192.0.2.2"),
+			},
+			"example.net.": {fake.Record("www.example.net.", route53Types.RRTypeA, 300, "192.0.2.3")},
+		},
+	}
+
+	set := loadZones(context.Background(), records, nil, loadOptions{serial: 1})
+
+	// The zone without a SOA record is left out, the bad records of the other zone are skipped
+	require.Len(t, set.zones, 1)
+	z := set.zones["example.com."]
+	require.NotNil(t, z)
+	assert.Len(t, z.rrsets["www.example.com."][dns.TypeA], 1)
+	assert.Len(t, z.rrsets["example.com."][dns.TypeMX], 1)
+	assert.NotContains(t, z.rrsets, "www.example.org.")
+}
@@ -0,0 +1,305 @@
+package dnsserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+	"go.uber.org/zap"
+)
+
+// resolvedAliasTtl is the TTL of aliases resolved through DNS, as their targets like load balancers change addresses
+const resolvedAliasTtl = 60
+
+// aliasPasses bounds how deep aliases of aliases are resolved
+const aliasPasses = 4
+
+// Resolver looks up the addresses of alias targets outside the backup, like CloudFront distributions and load balancers
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network string, host string) ([]netip.Addr, error)
+}
+
+// zone is a hosted zone ready to answer queries from
+type zone struct {
+	name string
+	soa  *dns.SOA
+	// rrsets by lower case owner and type
+	rrsets map[string]map[uint16][]dns.RR
+	// names holds the owners along with the empty non-terminals between them and the apex
+	names map[string]bool
+}
+
+// zoneSet is the zones of one backup
+type zoneSet struct {
+	zones  map[string]*zone
+	serial uint32
+}
+
+type alias struct {
+	owner  string
+	rrtype uint16
+	target string
+}
+
+// loadOptions are the settings applied while loading a backup
+type loadOptions struct {
+	serial      uint32
+	nameServers []string
+	resolver    Resolver
+}
+
+// loadZones converts the public zones of a backup. A zone backed up in several accounts is served from the first account.
+// Record sets of routing policies are merged, answering with all values, or those of the primary in case of failover.
+// Records that can't be served are logged and left out, as are zones without a SOA record, so one bad record doesn't
+// take down the other zones.
+func loadZones(ctx context.Context, records map[string]map[string][]route53Types.ResourceRecordSet, manifest *handlers.BackupManifest, opts loadOptions) *zoneSet {
+	set := &zoneSet{zones: make(map[string]*zone), serial: opts.serial}
+	var aliases []alias
+
+	accounts := make([]string, 0, len(records))
+	for acc := range records {
+		accounts = append(accounts, acc)
+	}
+	sort.Strings(accounts)
+
+	for _, acc := range accounts {
+		names := make([]string, 0, len(records[acc]))
+		for name := range records[acc] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			// Private zones answer differently within their VPCs, serving them publicly would leak them
+			if manifest != nil && manifest.Accounts[acc].Zones[name].Private {
+				continue
+			}
+			zoneName := dns.CanonicalName(name)
+			if _, ok := set.zones[zoneName]; ok {
+				logging.Logger.Warn("Zone is backed up in several accounts, serving the first one", zap.String("zone", zoneName), zap.String("account", acc))
+				continue
+			}
+
+			z, zoneAliases, err := newZone(zoneName, records[acc][name], opts)
+			if err != nil {
+				logging.Logger.Warn("Unable to serve zone, leaving it out", zap.String("zone", zoneName), zap.String("account", acc), zap.Error(err))
+				continue
+			}
+			set.zones[zoneName] = z
+			aliases = append(aliases, zoneAliases...)
+		}
+	}
+
+	set.resolveAliases(ctx, aliases, opts.resolver)
+	for _, z := range set.zones {
+		z.normaliseTtls()
+	}
+
+	return set
+}
+
+func newZone(name string, records []route53Types.ResourceRecordSet, opts loadOptions) (*zone, []alias, error) {
+	z := &zone{name: name, rrsets: make(map[string]map[uint16][]dns.RR), names: map[string]bool{name: true}}
+	var aliases []alias
+
+	primaries := make(map[string]bool)
+	for _, rec := range records {
+		if rec.Failover == route53Types.ResourceRecordSetFailoverPrimary {
+			primaries[aws.ToString(rec.Name)+"|"+string(rec.Type)] = true
+		}
+	}
+
+	for _, rec := range records {
+		if rec.Failover == route53Types.ResourceRecordSetFailoverSecondary && primaries[aws.ToString(rec.Name)+"|"+string(rec.Type)] {
+			continue
+		}
+
+		owner := ownerName(aws.ToString(rec.Name))
+		if !dns.IsSubDomain(name, owner) {
+			skipRecord(name, owner, rec.Type, errors.New("record is outside of the zone"))
+			continue
+		}
+		rrtype, ok := dns.StringToType[string(rec.Type)]
+		if !ok {
+			skipRecord(name, owner, rec.Type, errors.New("unknown type"))
+			continue
+		}
+
+		if rec.AliasTarget != nil {
+			aliases = append(aliases, alias{owner: owner, rrtype: rrtype, target: ownerName(aws.ToString(rec.AliasTarget.DNSName))})
+			z.addName(owner)
+			continue
+		}
+
+		for _, val := range rec.ResourceRecords {
+			rdata, err := oopsAws.FormatRecordValue(rec.Type, aws.ToString(val.Value))
+			if err != nil {
+				skipRecord(name, owner, rec.Type, err)
+				continue
+			}
+			rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", owner, aws.ToInt64(rec.TTL), rec.Type, rdata))
+			if err != nil {
+				skipRecord(name, owner, rec.Type, err)
+				continue
+			}
+			z.add(rr)
+		}
+	}
+
+	soa, ok := z.rrsets[name][dns.TypeSOA]
+	if !ok {
+		return nil, nil, fmt.Errorf("SOA record for zone %s not found", name)
+	}
+	z.soa = soa[0].(*dns.SOA)
+	// Route53 keeps the serial at 1, secondaries need it to change with every backup
+	z.soa.Serial = opts.serial
+
+	if len(opts.nameServers) > 0 {
+		ttl := uint32(172800)
+		if ns, ok := z.rrsets[name][dns.TypeNS]; ok {
+			ttl = ns[0].Header().Ttl
+		}
+		z.rrsets[name][dns.TypeNS] = nil
+		for _, host := range opts.nameServers {
+			z.add(&dns.NS{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: ttl}, Ns: dns.Fqdn(host)})
+		}
+		z.soa.Ns = dns.Fqdn(opts.nameServers[0])
+	}
+
+	return z, aliases, nil
+}
+
+// skipRecord logs a record value newZone leaves out, the rest of the zone is still served
+func skipRecord(zone string, owner string, recordType route53Types.RRType, err error) {
+	logging.Logger.Warn("Unable to serve record, leaving it out", zap.String("zone", zone), zap.String("record", owner), zap.String("type", string(recordType)), zap.Error(err))
+}
+
+func (z *zone) add(rr dns.RR) {
+	hdr := rr.Header()
+	hdr.Name = dns.CanonicalName(hdr.Name)
+	if z.rrsets[hdr.Name] == nil {
+		z.rrsets[hdr.Name] = make(map[uint16][]dns.RR)
+	}
+	for _, existing := range z.rrsets[hdr.Name][hdr.Rrtype] {
+		if dns.IsDuplicate(existing, rr) {
+			return
+		}
+	}
+	z.rrsets[hdr.Name][hdr.Rrtype] = append(z.rrsets[hdr.Name][hdr.Rrtype], rr)
+	z.addName(hdr.Name)
+}
+
+// addName marks the owner and the names between it and the apex as existing
+func (z *zone) addName(owner string) {
+	for name := owner; name != z.name && !z.names[name]; {
+		z.names[name] = true
+		i, end := dns.NextLabel(name, 0)
+		if end {
+			break
+		}
+		name = name[i:]
+	}
+}
+
+// normaliseTtls gives every record of a set the lowest TTL within it, merged routing policies may differ
+func (z *zone) normaliseTtls() {
+	for _, rrsets := range z.rrsets {
+		for _, rrs := range rrsets {
+			ttl := rrs[0].Header().Ttl
+			for _, rr := range rrs {
+				ttl = min(ttl, rr.Header().Ttl)
+			}
+			for _, rr := range rrs {
+				rr.Header().Ttl = ttl
+			}
+		}
+	}
+}
+
+// find returns the zone the name belongs to, the most specific one for delegations between served zones
+func (s *zoneSet) find(name string) *zone {
+	for {
+		if z, ok := s.zones[name]; ok {
+			return z
+		}
+		i, end := dns.NextLabel(name, 0)
+		if end {
+			return nil
+		}
+		name = name[i:]
+	}
+}
+
+// resolveAliases copies the records alias targets point to. Targets within the backup are resolved from it, including
+// aliases of aliases, others through DNS for A and AAAA records. Aliases that can't be resolved are left out.
+func (s *zoneSet) resolveAliases(ctx context.Context, aliases []alias, resolver Resolver) {
+	for pass := 0; pass < aliasPasses && len(aliases) > 0; pass++ {
+		var pending []alias
+		for _, a := range aliases {
+			target := s.find(a.target)
+			if target == nil {
+				pending = append(pending, a)
+				continue
+			}
+			rrs, ok := target.rrsets[a.target][a.rrtype]
+			if !ok {
+				pending = append(pending, a)
+				continue
+			}
+			z := s.find(a.owner)
+			for _, rr := range rrs {
+				copied := dns.Copy(rr)
+				copied.Header().Name = a.owner
+				z.add(copied)
+			}
+		}
+		if len(pending) == len(aliases) {
+			break
+		}
+		aliases = pending
+	}
+
+	for _, a := range aliases {
+		if s.find(a.target) != nil || resolver == nil || (a.rrtype != dns.TypeA && a.rrtype != dns.TypeAAAA) {
+			logging.Logger.Warn("Unable to resolve alias, leaving it out", zap.String("name", a.owner), zap.String("type", dns.TypeToString[a.rrtype]), zap.String("target", a.target))
+			continue
+		}
+
+		network := "ip4"
+		if a.rrtype == dns.TypeAAAA {
+			network = "ip6"
+		}
+		lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		addrs, err := resolver.LookupNetIP(lookupCtx, network, a.target)
+		cancel()
+		if err != nil {
+			logging.Logger.Warn("Unable to resolve alias, leaving it out", zap.String("name", a.owner), zap.String("target", a.target), zap.Error(err))
+			continue
+		}
+
+		z := s.find(a.owner)
+		hdr := dns.RR_Header{Name: a.owner, Rrtype: a.rrtype, Class: dns.ClassINET, Ttl: resolvedAliasTtl}
+		for _, addr := range addrs {
+			if a.rrtype == dns.TypeA {
+				z.add(&dns.A{Hdr: hdr, A: addr.AsSlice()})
+			} else {
+				z.add(&dns.AAAA{Hdr: hdr, AAAA: addr.AsSlice()})
+			}
+		}
+	}
+}
+
+// ownerName converts a Route53 name, which escapes the wildcard label as \052
+func ownerName(name string) string {
+	return dns.CanonicalName(strings.ReplaceAll(name, `\052`, "*"))
+}
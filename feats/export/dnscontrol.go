@@ -0,0 +1,132 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("dnscontrol", Format{Extension: "js", ContentType: "text/javascript; charset=utf-8", Render: DnsControl})
+}
+
+// dnsControlNumbers are the positions of numeric RDATA fields, which DNSControl takes as numbers rather than strings
+var dnsControlNumbers = map[string][]int{
+	"MX":    {0},
+	"SRV":   {0, 1, 2},
+	"NAPTR": {0, 1},
+	"SSHFP": {0, 1},
+	"TLSA":  {0, 1, 2},
+	"DS":    {0, 1, 2},
+	"HTTPS": {0},
+	"SVCB":  {0},
+}
+
+// DnsControl renders the zone as a DNSControl domain. The file declares its registrar and provider itself unless already
+// declared, so it works on its own as dnsconfig.js as well as require()d along with the other zones. The provider is the
+// "export" entry of creds.json.
+func DnsControl(zone Zone) (string, error) {
+	records, notes := portableRecords(zone)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// Hosted zone %s of account %s, exported by oops\n", displayName(zone.Name), zone.Account)
+	for _, note := range notes {
+		fmt.Fprintf(&sb, "// %s\n", note)
+	}
+	sb.WriteString("var REG_NONE = REG_NONE || NewRegistrar(\"none\");\n")
+	sb.WriteString("var DSP_EXPORT = DSP_EXPORT || NewDnsProvider(\"export\");\n\n")
+	fmt.Fprintf(&sb, "D(%s, REG_NONE, DnsProvider(DSP_EXPORT),\n", jsString(displayName(zone.Name)))
+
+	for _, rec := range records {
+		name := rec.name
+		if name == "" {
+			name = "@"
+		}
+
+		for _, value := range rec.values {
+			args, err := dnsControlArgs(rec, value)
+			if err != nil {
+				return "", fmt.Errorf("%s %s: %w", displayName(rec.fqdn(zone.Name)), rec.recordType, err)
+			}
+			if args == nil {
+				fmt.Fprintf(&sb, "\t// %s %s %s isn't supported by DNSControl\n", name, rec.recordType, value)
+				continue
+			}
+			fmt.Fprintf(&sb, "\t%s(%s, %s, TTL(%d)),\n", rec.recordType, jsString(name), strings.Join(args, ", "), rec.ttl)
+		}
+	}
+
+	sb.WriteString("END);\n")
+	return sb.String(), nil
+}
+
+// dnsControlArgs returns the arguments following the name of the record function, nil for types DNSControl doesn't know
+func dnsControlArgs(rec portableRecord, value string) ([]string, error) {
+	switch rec.recordType {
+	case "A", "AAAA", "CNAME", "ALIAS", "NS", "PTR":
+		return []string{jsString(value)}, nil
+	case "TXT":
+		var texts []string
+		for _, text := range rec.fields(value) {
+			texts = append(texts, jsString(text))
+		}
+		if len(texts) == 1 {
+			return texts, nil
+		}
+		return []string{"[" + strings.Join(texts, ", ") + "]"}, nil
+	case "CAA":
+		fields := rec.fields(value)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("expected 3 fields in %s", value)
+		}
+		args := []string{jsString(fields[1]), jsString(fields[2])}
+		if fields[0] == "128" {
+			args = append(args, "CAA_CRITICAL")
+		}
+		return args, nil
+	case "HTTPS", "SVCB":
+		fields := strings.Fields(value)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid value %s", value)
+		}
+		if _, err := strconv.Atoi(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid priority in %s", value)
+		}
+		return []string{fields[0], jsString(fields[1]), jsString(strings.Join(fields[2:], " "))}, nil
+	}
+
+	numbers, ok := dnsControlNumbers[rec.recordType]
+	if !ok {
+		return nil, nil
+	}
+	// The types with numeric fields have the layouts octoDNS has keys for
+	fields := rec.fields(value)
+	if len(fields) != len(octoDnsFields[rec.recordType]) {
+		return nil, fmt.Errorf("expected %d fields in %s", len(octoDnsFields[rec.recordType]), value)
+	}
+
+	var args []string
+	for i, field := range fields {
+		if !slices.Contains(numbers, i) {
+			args = append(args, jsString(field))
+			continue
+		}
+		if _, err := strconv.Atoi(field); err != nil {
+			return nil, fmt.Errorf("field %d of %s isn't a number", i+1, value)
+		}
+		args = append(args, field)
+	}
+	return args, nil
+}
+
+// jsString quotes s as a JavaScript string literal
+func jsString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
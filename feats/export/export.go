@@ -0,0 +1,111 @@
+// Package export renders backed up hosted zones as configuration of other DNS tooling, so zones managed by hand can be
+// brought under infrastructure as code.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// Zone is a backed up hosted zone along with its records
+type Zone struct {
+	Account string
+	Name    string
+	// Id is the hosted zone id, e.g. /hostedzone/Z123. It's empty for backups taken before ids were part of the manifest.
+	Id      string
+	Private bool
+	Records []route53Types.ResourceRecordSet
+}
+
+// Format renders a zone into a file of the configuration language of a tool
+type Format struct {
+	// Extension of the rendered files, e.g. "tf"
+	Extension   string
+	ContentType string
+	Render      func(zone Zone) (string, error)
+}
+
+var formats = map[string]Format{}
+
+// Register makes a format available under the name used in job.route53Backup.exports and the API
+func Register(name string, format Format) {
+	formats[name] = format
+}
+
+func Lookup(name string) (Format, bool) {
+	format, ok := formats[name]
+	return format, ok
+}
+
+// Names returns the registered formats, sorted
+func Names() []string {
+	var payload []string
+	for name := range formats {
+		payload = append(payload, name)
+	}
+	sort.Strings(payload)
+	return payload
+}
+
+// Validate checks that every format is registered
+func Validate(names []string) error {
+	var errs []error
+	for _, name := range names {
+		if _, ok := formats[name]; !ok {
+			errs = append(errs, fmt.Errorf("job.route53Backup.exports must only contain %s, got %s", strings.Join(Names(), ", "), name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FilePath returns the path of a rendered zone within a backup tarball. Exports live in the directory of their account,
+// like the zone files.
+func FilePath(format string, account string, zone string) string {
+	ext := ""
+	if f, ok := formats[format]; ok {
+		ext = f.Extension
+	}
+	return fmt.Sprintf("%s/%s/%s.%s", account, format, strings.TrimSuffix(zone, "."), ext)
+}
+
+// hostedZoneId strips the /hostedzone/ prefix the Route53 API returns ids with
+func hostedZoneId(id string) string {
+	return strings.TrimPrefix(id, "/hostedzone/")
+}
+
+// displayName returns a name without the trailing dot and with the wildcard label Route53 escapes as \052 unescaped
+func displayName(name string) string {
+	return strings.ReplaceAll(strings.TrimSuffix(name, "."), `\052`, "*")
+}
+
+// sortedRecords orders records by name, type and set identifier, so exports of unchanged zones are identical
+func sortedRecords(records []route53Types.ResourceRecordSet) []route53Types.ResourceRecordSet {
+	payload := append([]route53Types.ResourceRecordSet(nil), records...)
+	sort.SliceStable(payload, func(i, j int) bool {
+		a, b := payload[i], payload[j]
+		if *a.Name != *b.Name {
+			return *a.Name < *b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return setIdentifier(a) < setIdentifier(b)
+	})
+	return payload
+}
+
+func setIdentifier(rec route53Types.ResourceRecordSet) string {
+	if rec.SetIdentifier == nil {
+		return ""
+	}
+	return *rec.SetIdentifier
+}
+
+// isApex reports whether the record is one of the SOA and NS records Route53 creates along with the zone
+func isApex(zone string, rec route53Types.ResourceRecordSet) bool {
+	return *rec.Name == zone && (rec.Type == route53Types.RRTypeSoa || rec.Type == route53Types.RRTypeNs)
+}
@@ -0,0 +1,13 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(nil))
+	assert.NoError(t, Validate([]string{"terraform", "octodns", "dnscontrol"}))
+	assert.EqualError(t, Validate([]string{"terraform", "pulumi"}), "job.route53Backup.exports must only contain dnscontrol, octodns, terraform, got pulumi")
+}
@@ -0,0 +1,216 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+func init() {
+	Register("octodns", Format{Extension: "yaml", ContentType: "application/yaml", Render: OctoDns})
+}
+
+// octoDnsFields are the keys of the values of structured types, in RDATA order
+var octoDnsFields = map[string][]string{
+	"MX":    {"preference", "exchange"},
+	"SRV":   {"priority", "weight", "port", "target"},
+	"CAA":   {"flags", "tag", "value"},
+	"NAPTR": {"order", "preference", "flags", "service", "regexp", "replacement"},
+	"SSHFP": {"algorithm", "fingerprint_type", "fingerprint"},
+	"TLSA":  {"certificate_usage", "selector", "matching_type", "certificate_association_data"},
+	"DS":    {"key_tag", "algorithm", "digest_type", "digest"},
+}
+
+// OctoDns renders the zone as the config of octoDNS' YamlProvider, named after the zone like the provider expects
+func OctoDns(zone Zone) (string, error) {
+	records, notes := portableRecords(zone)
+
+	byName := make(map[string][]portableRecord)
+	var names []string
+	for _, rec := range records {
+		if _, ok := byName[rec.name]; !ok {
+			names = append(names, rec.name)
+		}
+		byName[rec.name] = append(byName[rec.name], rec)
+	}
+	// The YamlProvider insists on keys in natural sort order
+	sort.SliceStable(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Hosted zone %s of account %s, exported by oops\n", displayName(zone.Name), zone.Account)
+	for _, note := range notes {
+		fmt.Fprintf(&sb, "# %s\n", note)
+	}
+	sb.WriteString("---\n")
+
+	for _, name := range names {
+		fmt.Fprintf(&sb, "%s:\n", yamlString(name))
+		recs := byName[name]
+		for _, rec := range recs {
+			indent := "  "
+			if len(recs) > 1 {
+				sb.WriteString("  - ")
+				indent = "    "
+			} else {
+				sb.WriteString(indent)
+			}
+
+			fmt.Fprintf(&sb, "ttl: %d\n", rec.ttl)
+			fmt.Fprintf(&sb, "%stype: %s\n", indent, rec.recordType)
+
+			var values []any
+			for _, value := range rec.values {
+				converted, err := octoDnsValue(rec, value)
+				if err != nil {
+					return "", fmt.Errorf("%s %s: %w", displayName(rec.fqdn(zone.Name)), rec.recordType, err)
+				}
+				values = append(values, converted)
+			}
+
+			if len(values) == 1 {
+				fmt.Fprintf(&sb, "%svalue:", indent)
+				writeYamlValue(&sb, indent+"  ", values[0])
+				continue
+			}
+			fmt.Fprintf(&sb, "%svalues:\n", indent)
+			for _, value := range values {
+				if fields, ok := value.([]yamlField); ok {
+					writeYamlFields(&sb, indent+"- ", indent+"  ", fields)
+					continue
+				}
+				fmt.Fprintf(&sb, "%s-", indent)
+				writeYamlValue(&sb, indent+"  ", value)
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// yamlField is a key of a structured value, values are strings or ints
+type yamlField struct {
+	key   string
+	value any
+}
+
+func octoDnsValue(rec portableRecord, value string) (any, error) {
+	switch rec.recordType {
+	case "TXT", "SPF":
+		// octoDNS takes the text as one string and escapes semicolons
+		return strings.ReplaceAll(strings.Join(rec.fields(value), ""), ";", `\;`), nil
+	case "HTTPS", "SVCB":
+		fields := strings.Fields(value)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid value %s", value)
+		}
+		payload := []yamlField{{"svcpriority", fieldValue(fields[0])}, {"targetname", fields[1]}}
+		var params []yamlField
+		for _, param := range fields[2:] {
+			key, val, _ := strings.Cut(param, "=")
+			params = append(params, yamlField{key, strings.Trim(val, `"`)})
+		}
+		if len(params) > 0 {
+			payload = append(payload, yamlField{"svcparams", params})
+		}
+		return payload, nil
+	}
+
+	keys, ok := octoDnsFields[rec.recordType]
+	if !ok {
+		return value, nil
+	}
+	fields := rec.fields(value)
+	if len(fields) != len(keys) {
+		return nil, fmt.Errorf("expected %d fields in %s", len(keys), value)
+	}
+
+	var payload []yamlField
+	for i, key := range keys {
+		payload = append(payload, yamlField{key, fieldValue(fields[i])})
+	}
+	// octoDNS sorts the keys of values when writing them itself
+	sort.Slice(payload, func(i, j int) bool { return payload[i].key < payload[j].key })
+	return payload, nil
+}
+
+func fieldValue(field string) any {
+	if n, err := strconv.Atoi(field); err == nil {
+		return n
+	}
+	return field
+}
+
+// writeYamlValue writes a value following its key on the same line, mappings are nested at indent
+func writeYamlValue(sb *strings.Builder, indent string, value any) {
+	switch v := value.(type) {
+	case []yamlField:
+		sb.WriteString("\n")
+		writeYamlFields(sb, indent, indent, v)
+	case int:
+		fmt.Fprintf(sb, " %d\n", v)
+	case string:
+		fmt.Fprintf(sb, " %s\n", yamlString(v))
+	}
+}
+
+// writeYamlFields writes a mapping, the first key prefixed with first to start it as a list entry
+func writeYamlFields(sb *strings.Builder, first string, indent string, fields []yamlField) {
+	for i, field := range fields {
+		prefix := indent
+		if i == 0 {
+			prefix = first
+		}
+		fmt.Fprintf(sb, "%s%s:", prefix, field.key)
+		writeYamlValue(sb, indent+"  ", field.value)
+	}
+}
+
+// yamlString quotes s the way sigs.k8s.io/yaml does when it isn't safe to leave plain. Multi-line strings, which yaml
+// would write as a block, are double quoted as JSON instead, so they stay on the line of their key.
+func yamlString(s string) string {
+	out, err := yaml.Marshal(s)
+	payload := strings.TrimSuffix(string(out), "\n")
+	if err != nil || strings.Contains(payload, "\n") {
+		quoted, _ := json.Marshal(s)
+		return string(quoted)
+	}
+	return payload
+}
+
+// naturalLess compares strings with runs of digits compared by their numeric value, like the natsort octoDNS uses
+func naturalLess(a string, b string) bool {
+	for a != "" && b != "" {
+		ca, cb := a[0], b[0]
+		if isDigit(ca) && isDigit(cb) {
+			na, restA := digitRun(a)
+			nb, restB := digitRun(b)
+			if na != nb {
+				return na < nb
+			}
+			a, b = restA, restB
+			continue
+		}
+		if ca != cb {
+			return ca < cb
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func digitRun(s string) (int, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	n, _ := strconv.Atoi(s[:i])
+	return n, s[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,162 @@
+package export
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+)
+
+// aliasTtl is used for alias records rendered as ALIAS or CNAME, Route53 aliases take the TTL of their target
+const aliasTtl = 300
+
+// portableRecord is a record set without Route53 specifics, for formats targeting other DNS providers
+type portableRecord struct {
+	// name is relative to the zone, empty at the apex
+	name       string
+	recordType string
+	ttl        int64
+	values     []string
+}
+
+// portableRecords converts the records of a zone for other DNS providers. The apex SOA and NS records are left to the
+// provider. Alias records become ALIAS records at the apex and CNAME records elsewhere, and record sets of routing
+// policies are merged into one answering with all values, or those of the primary in case of failover. Whatever can't be
+// converted is described in the returned notes.
+func portableRecords(zone Zone) ([]portableRecord, []string) {
+	name := zone.Name
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	var payload []portableRecord
+	var notes []string
+	index := make(map[string]int)
+	aliased := make(map[string]bool)
+	failover := make(map[string]bool)
+
+	records := sortedRecords(zone.Records)
+	for _, rec := range records {
+		if rec.Failover == route53Types.ResourceRecordSetFailoverPrimary {
+			failover[*rec.Name+"|"+string(rec.Type)] = true
+		}
+	}
+
+	for _, rec := range records {
+		display := fmt.Sprintf("%s %s", displayName(*rec.Name), rec.Type)
+		if rec.SetIdentifier != nil {
+			display += fmt.Sprintf(" (%s)", *rec.SetIdentifier)
+		}
+
+		switch {
+		case isApex(name, rec):
+			continue
+		case rec.TrafficPolicyInstanceId != nil:
+			notes = append(notes, fmt.Sprintf("%s is managed by traffic policy instance %s and left out", display, *rec.TrafficPolicyInstanceId))
+			continue
+		case rec.Failover == route53Types.ResourceRecordSetFailoverSecondary && failover[*rec.Name+"|"+string(rec.Type)]:
+			notes = append(notes, fmt.Sprintf("%s is a failover secondary and left out", display))
+			continue
+		}
+
+		portable := portableRecord{name: portableName(*rec.Name, name), recordType: string(rec.Type), ttl: aws.ToInt64(rec.TTL)}
+		if rec.AliasTarget != nil {
+			// An alias of both A and AAAA becomes a single record
+			if aliased[*rec.Name] {
+				continue
+			}
+			aliased[*rec.Name] = true
+
+			portable.recordType = "CNAME"
+			if portable.name == "" {
+				portable.recordType = "ALIAS"
+			}
+			portable.ttl = aliasTtl
+			portable.values = []string{strings.ToLower(aws.ToString(rec.AliasTarget.DNSName))}
+			notes = append(notes, fmt.Sprintf("%s is an alias of %s and exported as %s", display, aws.ToString(rec.AliasTarget.DNSName), portable.recordType))
+		} else {
+			for _, val := range rec.ResourceRecords {
+				portable.values = append(portable.values, aws.ToString(val.Value))
+			}
+		}
+
+		key := portable.name + "|" + portable.recordType
+		if i, ok := index[key]; ok {
+			// Merge the sets of a routing policy
+			payload[i].ttl = min(payload[i].ttl, portable.ttl)
+			for _, value := range portable.values {
+				if !slices.Contains(payload[i].values, value) {
+					payload[i].values = append(payload[i].values, value)
+				}
+			}
+			continue
+		}
+		if rec.SetIdentifier != nil {
+			notes = append(notes, fmt.Sprintf("%s %s uses a routing policy, its record sets are merged", displayName(*rec.Name), rec.Type))
+		}
+		index[key] = len(payload)
+		payload = append(payload, portable)
+	}
+
+	// A CNAME can't share its name with other records
+	var filtered []portableRecord
+	for _, rec := range payload {
+		if rec.recordType == "CNAME" && aliased[rec.fqdn(name)] && hasOtherTypes(payload, rec) {
+			notes = append(notes, fmt.Sprintf("%s CNAME can't coexist with the other records of the name and is left out", displayName(rec.fqdn(name))))
+			continue
+		}
+		filtered = append(filtered, rec)
+	}
+
+	// Apex first, like the zone files
+	sort.SliceStable(filtered, func(i, j int) bool { return naturalLess(filtered[i].name, filtered[j].name) })
+
+	return filtered, dedupe(notes)
+}
+
+func (r portableRecord) fqdn(zone string) string {
+	if r.name == "" {
+		return zone
+	}
+	return strings.ReplaceAll(r.name, "*", `\052`) + "." + zone
+}
+
+// fields splits the value of the record into its RDATA fields, resolving quoted character strings
+func (r portableRecord) fields(value string) []string {
+	fields, err := oopsAws.SplitCharacterStrings(value)
+	if err != nil {
+		return strings.Fields(value)
+	}
+	return fields
+}
+
+// portableName returns the name relative to the zone, empty at the apex
+func portableName(name string, zone string) string {
+	if name == zone {
+		return ""
+	}
+	return displayName(strings.TrimSuffix(name, "."+zone))
+}
+
+func hasOtherTypes(records []portableRecord, rec portableRecord) bool {
+	for _, other := range records {
+		if other.name == rec.name && other.recordType != rec.recordType {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(values []string) []string {
+	var payload []string
+	for _, value := range values {
+		if !slices.Contains(payload, value) {
+			payload = append(payload, value)
+		}
+	}
+	return payload
+}
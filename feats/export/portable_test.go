@@ -0,0 +1,171 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dfds.cloud/oops/core/aws/fake"
+)
+
+func portableZone() Zone {
+	primary := fake.Record("api.example.com.", route53Types.RRTypeA, 300, "192.0.2.1")
+	primary.SetIdentifier = aws.String("primary")
+	primary.Failover = route53Types.ResourceRecordSetFailoverPrimary
+	secondary := fake.Record("api.example.com.", route53Types.RRTypeA, 300, "192.0.2.2")
+	secondary.SetIdentifier = aws.String("secondary")
+	secondary.Failover = route53Types.ResourceRecordSetFailoverSecondary
+
+	blue := fake.Record("www.example.com.", route53Types.RRTypeA, 300, "192.0.2.10")
+	blue.SetIdentifier = aws.String("blue")
+	blue.Weight = aws.Int64(90)
+	green := fake.Record("www.example.com.", route53Types.RRTypeA, 300, "192.0.2.11")
+	green.SetIdentifier = aws.String("green")
+	green.Weight = aws.Int64(10)
+	green.TTL = aws.Int64(60)
+
+	alias := func(recordType route53Types.RRType) route53Types.ResourceRecordSet {
+		return route53Types.ResourceRecordSet{
+			Name:        aws.String("example.com."),
+			Type:        recordType,
+			AliasTarget: &route53Types.AliasTarget{DNSName: aws.String("D123.cloudfront.net."), HostedZoneId: aws.String("Z2FDTNDATAQYW2")},
+		}
+	}
+
+	return Zone{
+		Account: "111111111111",
+		Name:    "example.com.",
+		Records: []route53Types.ResourceRecordSet{
+			fake.Record("example.com.", route53Types.RRTypeSoa, 300, "ns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400"),
+			fake.Record("example.com.", route53Types.RRTypeNs, 300, "ns-1.awsdns-01.org."),
+			alias(route53Types.RRTypeA),
+			alias(route53Types.RRTypeAaaa),
+			fake.Record("example.com.", route53Types.RRTypeMx, 300, "10 mx1.example.com.", "20 mx2.example.com."),
+			fake.Record("example.com.", route53Types.RRTypeTxt, 300, `"v=spf1 -all"`, `"part one;" "part two"`),
+			fake.Record("example.com.", route53Types.RRTypeCaa, 300, `0 issue "letsencrypt.org"`),
+			fake.Record("_sip._tcp.example.com.", route53Types.RRTypeSrv, 300, "10 60 5060 sip.example.com."),
+			fake.Record("host10.example.com.", route53Types.RRTypeA, 300, "192.0.2.20"),
+			fake.Record("host9.example.com.", route53Types.RRTypeA, 300, "192.0.2.19"),
+			primary, secondary, blue, green,
+		},
+	}
+}
+
+func TestOctoDns(t *testing.T) {
+	content, err := OctoDns(portableZone())
+	require.NoError(t, err)
+	assert.Equal(t, `# Hosted zone example.com of account 111111111111, exported by oops
+# api.example.com A uses a routing policy, its record sets are merged
+# api.example.com A (secondary) is a failover secondary and left out
+# example.com A is an alias of D123.cloudfront.net. and exported as ALIAS
+# www.example.com A uses a routing policy, its record sets are merged
+---
+"":
+  - ttl: 300
+    type: ALIAS
+    value: d123.cloudfront.net.
+  - ttl: 300
+    type: CAA
+    value:
+      flags: 0
+      tag: issue
+      value: letsencrypt.org
+  - ttl: 300
+    type: MX
+    values:
+    - exchange: mx1.example.com.
+      preference: 10
+    - exchange: mx2.example.com.
+      preference: 20
+  - ttl: 300
+    type: TXT
+    values:
+    - v=spf1 -all
+    - part one\;part two
+_sip._tcp:
+  ttl: 300
+  type: SRV
+  value:
+    port: 5060
+    priority: 10
+    target: sip.example.com.
+    weight: 60
+api:
+  ttl: 300
+  type: A
+  value: 192.0.2.1
+host9:
+  ttl: 300
+  type: A
+  value: 192.0.2.19
+host10:
+  ttl: 300
+  type: A
+  value: 192.0.2.20
+www:
+  ttl: 60
+  type: A
+  values:
+  - 192.0.2.10
+  - 192.0.2.11
+`, content)
+}
+
+func TestDnsControl(t *testing.T) {
+	content, err := DnsControl(portableZone())
+	require.NoError(t, err)
+	assert.Equal(t, `// Hosted zone example.com of account 111111111111, exported by oops
+// api.example.com A uses a routing policy, its record sets are merged
+// api.example.com A (secondary) is a failover secondary and left out
+// example.com A is an alias of D123.cloudfront.net. and exported as ALIAS
+// www.example.com A uses a routing policy, its record sets are merged
+var REG_NONE = REG_NONE || NewRegistrar("none");
+var DSP_EXPORT = DSP_EXPORT || NewDnsProvider("export");
+
+D("example.com", REG_NONE, DnsProvider(DSP_EXPORT),
+	ALIAS("@", "d123.cloudfront.net.", TTL(300)),
+	CAA("@", "issue", "letsencrypt.org", TTL(300)),
+	MX("@", 10, "mx1.example.com.", TTL(300)),
+	MX("@", 20, "mx2.example.com.", TTL(300)),
+	TXT("@", "v=spf1 -all", TTL(300)),
+	TXT("@", ["part one;", "part two"], TTL(300)),
+	SRV("_sip._tcp", 10, 60, 5060, "sip.example.com.", TTL(300)),
+	A("api", "192.0.2.1", TTL(300)),
+	A("host9", "192.0.2.19", TTL(300)),
+	A("host10", "192.0.2.20", TTL(300)),
+	A("www", "192.0.2.10", TTL(60)),
+	A("www", "192.0.2.11", TTL(60)),
+END);
+`, content)
+}
+
+func TestNaturalLess(t *testing.T) {
+	assert.True(t, naturalLess("host9", "host10"))
+	assert.True(t, naturalLess("", "a"))
+	assert.True(t, naturalLess("a", "b"))
+	assert.False(t, naturalLess("host10", "host10"))
+}
+
+func TestYamlString(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"www", "www"},
+		{"", `""`},
+		{"yes", `"yes"`},
+		{"1.5", `"1.5"`},
+		{"key: value", `'key: value'`},
+		{"#comment", `'#comment'`},
+		{"it's", "it's"},
+		{"two\nlines", `"two\nlines"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.expected, yamlString(tt.value))
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package export
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+func init() {
+	Register("terraform", Format{Extension: "tf", ContentType: "text/plain; charset=utf-8", Render: Terraform})
+}
+
+// Terraform renders the zone as aws_route53_zone and aws_route53_record resources, along with import blocks adopting the
+// existing zone and records, as understood by Terraform and OpenTofu 1.5 and later. The apex SOA and NS records are left
+// to the zone resource.
+func Terraform(zone Zone) (string, error) {
+	if !strings.HasSuffix(zone.Name, ".") {
+		zone.Name += "."
+	}
+	id := hostedZoneId(zone.Id)
+	names := newResourceNames()
+	zoneResource := names.unique(zone.Name)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Hosted zone %s of account %s, exported by oops\n", displayName(zone.Name), zone.Account)
+	if id == "" {
+		sb.WriteString("# The backup predates hosted zone ids, so import blocks are left out\n")
+	}
+	sb.WriteString("\n")
+
+	fmt.Fprintf(&sb, "resource \"aws_route53_zone\" %q {\n", zoneResource)
+	fmt.Fprintf(&sb, "  name = %s\n", hclString(displayName(zone.Name)))
+	if zone.Private {
+		sb.WriteString("\n  # VPC associations aren't part of the backup, add vpc blocks before removing this\n")
+		sb.WriteString("  lifecycle {\n    ignore_changes = [vpc]\n  }\n")
+	}
+	sb.WriteString("}\n")
+	writeImport(&sb, "aws_route53_zone."+zoneResource, id)
+
+	for _, rec := range sortedRecords(zone.Records) {
+		if isApex(zone.Name, rec) {
+			continue
+		}
+		if rec.TrafficPolicyInstanceId != nil {
+			fmt.Fprintf(&sb, "\n# %s %s is managed by traffic policy instance %s and left out\n", displayName(*rec.Name), rec.Type, *rec.TrafficPolicyInstanceId)
+			continue
+		}
+
+		resource := names.unique(zone.Name + "_" + relativeName(*rec.Name, zone.Name) + "_" + string(rec.Type) + "_" + setIdentifier(rec))
+		sb.WriteString("\n")
+		err := writeTerraformRecord(&sb, resource, zoneResource, id, rec)
+		if err != nil {
+			return "", err
+		}
+
+		importId := ""
+		if id != "" {
+			importId = fmt.Sprintf("%s_%s_%s", id, displayName(*rec.Name), rec.Type)
+			if rec.SetIdentifier != nil {
+				importId += "_" + *rec.SetIdentifier
+			}
+		}
+		writeImport(&sb, "aws_route53_record."+resource, importId)
+	}
+
+	return sb.String(), nil
+}
+
+func writeTerraformRecord(sb *strings.Builder, resource string, zoneResource string, zoneId string, rec route53Types.ResourceRecordSet) error {
+	fmt.Fprintf(sb, "resource \"aws_route53_record\" %q {\n", resource)
+	attributes := hclAttributes{
+		{"zone_id", fmt.Sprintf("aws_route53_zone.%s.zone_id", zoneResource)},
+		{"name", hclString(displayName(*rec.Name))},
+		{"type", hclString(string(rec.Type))},
+	}
+	attributes.optional("set_identifier", rec.SetIdentifier)
+	attributes.optional("health_check_id", rec.HealthCheckId)
+
+	if alias := rec.AliasTarget; alias != nil {
+		attributes.write(sb, "  ")
+
+		target := hclString(aws.ToString(alias.HostedZoneId))
+		// Aliases within the zone refer to the zone resource, so they keep working if the zone is recreated
+		if zoneId != "" && aws.ToString(alias.HostedZoneId) == zoneId {
+			target = fmt.Sprintf("aws_route53_zone.%s.zone_id", zoneResource)
+		}
+		sb.WriteString("\n  alias {\n")
+		hclAttributes{
+			{"name", hclString(strings.ToLower(displayName(aws.ToString(alias.DNSName))))},
+			{"zone_id", target},
+			{"evaluate_target_health", strconv.FormatBool(alias.EvaluateTargetHealth)},
+		}.write(sb, "    ")
+		sb.WriteString("  }\n")
+	} else {
+		if rec.TTL == nil {
+			return fmt.Errorf("record %s %s has neither a TTL nor an alias target", *rec.Name, rec.Type)
+		}
+		attributes = append(attributes, hclAttribute{"ttl", strconv.FormatInt(*rec.TTL, 10)})
+
+		var values []string
+		for _, val := range rec.ResourceRecords {
+			values = append(values, hclString(terraformRecordValue(rec.Type, aws.ToString(val.Value))))
+		}
+		if len(values) == 1 {
+			attributes = append(attributes, hclAttribute{"records", "[" + values[0] + "]"})
+		} else {
+			var list strings.Builder
+			list.WriteString("[\n")
+			for _, value := range values {
+				fmt.Fprintf(&list, "    %s,\n", value)
+			}
+			list.WriteString("  ]")
+			attributes = append(attributes, hclAttribute{"records", list.String()})
+		}
+		attributes.write(sb, "  ")
+	}
+
+	writeRoutingPolicy(sb, rec)
+	sb.WriteString("}\n")
+	return nil
+}
+
+func writeRoutingPolicy(sb *strings.Builder, rec route53Types.ResourceRecordSet) {
+	switch {
+	case rec.Weight != nil:
+		fmt.Fprintf(sb, "\n  weighted_routing_policy {\n    weight = %d\n  }\n", *rec.Weight)
+	case rec.Failover != "":
+		fmt.Fprintf(sb, "\n  failover_routing_policy {\n    type = %s\n  }\n", hclString(string(rec.Failover)))
+	case rec.Region != "":
+		fmt.Fprintf(sb, "\n  latency_routing_policy {\n    region = %s\n  }\n", hclString(string(rec.Region)))
+	case rec.GeoLocation != nil:
+		sb.WriteString("\n  geolocation_routing_policy {\n")
+		var attributes hclAttributes
+		attributes.optional("continent", rec.GeoLocation.ContinentCode)
+		attributes.optional("country", rec.GeoLocation.CountryCode)
+		attributes.optional("subdivision", rec.GeoLocation.SubdivisionCode)
+		attributes.write(sb, "    ")
+		sb.WriteString("  }\n")
+	case rec.GeoProximityLocation != nil:
+		location := rec.GeoProximityLocation
+		sb.WriteString("\n  geoproximity_routing_policy {\n")
+		var attributes hclAttributes
+		attributes.optional("aws_region", location.AWSRegion)
+		attributes.optional("local_zone_group", location.LocalZoneGroup)
+		if location.Bias != nil {
+			attributes = append(attributes, hclAttribute{"bias", strconv.Itoa(int(*location.Bias))})
+		}
+		attributes.write(sb, "    ")
+		if location.Coordinates != nil {
+			if len(attributes) > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString("    coordinates {\n")
+			hclAttributes{
+				{"latitude", hclString(aws.ToString(location.Coordinates.Latitude))},
+				{"longitude", hclString(aws.ToString(location.Coordinates.Longitude))},
+			}.write(sb, "      ")
+			sb.WriteString("    }\n")
+		}
+		sb.WriteString("  }\n")
+	case rec.CidrRoutingConfig != nil:
+		sb.WriteString("\n  cidr_routing_policy {\n")
+		var attributes hclAttributes
+		attributes.optional("collection_id", rec.CidrRoutingConfig.CollectionId)
+		attributes.optional("location_name", rec.CidrRoutingConfig.LocationName)
+		attributes.write(sb, "    ")
+		sb.WriteString("  }\n")
+	case aws.ToBool(rec.MultiValueAnswer):
+		sb.WriteString("\n  multivalue_answer_routing_policy = true\n")
+	}
+}
+
+type hclAttribute struct {
+	key   string
+	value string
+}
+
+// hclAttributes are consecutive attributes of a block, written with their equals signs aligned like terraform fmt does
+type hclAttributes []hclAttribute
+
+func (a *hclAttributes) optional(key string, value *string) {
+	if value != nil {
+		*a = append(*a, hclAttribute{key, hclString(*value)})
+	}
+}
+
+func (a hclAttributes) write(sb *strings.Builder, indent string) {
+	width := 0
+	for _, attribute := range a {
+		width = max(width, len(attribute.key))
+	}
+	for _, attribute := range a {
+		fmt.Fprintf(sb, "%s%-*s = %s\n", indent, width, attribute.key, attribute.value)
+	}
+}
+
+func writeImport(sb *strings.Builder, to string, id string) {
+	if id == "" {
+		return
+	}
+	fmt.Fprintf(sb, "\nimport {\n  to = %s\n  id = %s\n}\n", to, hclString(id))
+}
+
+// terraformRecordValue strips the outer quotes of TXT and SPF values, which the AWS provider adds itself. Values split
+// into several strings keep the inner quotes, matching what the provider reads back after the import.
+func terraformRecordValue(recordType route53Types.RRType, value string) string {
+	if recordType != route53Types.RRTypeTxt && recordType != route53Types.RRTypeSpf {
+		return value
+	}
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+var hclEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "${", "$${", "%{", "%%{")
+
+// hclString quotes s as a HCL string literal, escaping template sequences as well
+func hclString(s string) string {
+	return `"` + hclEscaper.Replace(s) + `"`
+}
+
+func relativeName(name string, zone string) string {
+	if name == zone {
+		return "apex"
+	}
+	return strings.TrimSuffix(name, "."+zone)
+}
+
+var invalidIdentifierChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// resourceNames hands out unique Terraform identifiers derived from DNS names
+type resourceNames map[string]bool
+
+func newResourceNames() resourceNames {
+	return make(resourceNames)
+}
+
+func (n resourceNames) unique(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), `\052`, "wildcard")
+	name = strings.ReplaceAll(name, "*", "wildcard")
+	name = strings.Trim(invalidIdentifierChars.ReplaceAllString(name, "_"), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') || name[0] == '-' {
+		name = "_" + name
+	}
+
+	payload := name
+	for i := 2; n[payload]; i++ {
+		payload = name + "_" + strconv.Itoa(i)
+	}
+	n[payload] = true
+	return payload
+}
@@ -0,0 +1,174 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dfds.cloud/oops/core/aws/fake"
+)
+
+func TestTerraform(t *testing.T) {
+	primary := fake.Record("api.example.com.", route53Types.RRTypeA, 300, "192.0.2.1")
+	primary.SetIdentifier = aws.String("primary")
+	primary.Failover = route53Types.ResourceRecordSetFailoverPrimary
+	primary.HealthCheckId = aws.String("abcdef-1234")
+
+	weighted := fake.Record("www.example.com.", route53Types.RRTypeCname, 300, "example.com.")
+	weighted.SetIdentifier = aws.String("blue")
+	weighted.Weight = aws.Int64(10)
+
+	zone := Zone{
+		Account: "111111111111",
+		Name:    "example.com.",
+		Id:      "/hostedzone/Z1",
+		Records: []route53Types.ResourceRecordSet{
+			fake.Record("example.com.", route53Types.RRTypeSoa, 300, "ns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400"),
+			fake.Record("example.com.", route53Types.RRTypeNs, 300, "ns-1.awsdns-01.org."),
+			weighted,
+			fake.Record("example.com.", route53Types.RRTypeTxt, 300, `"v=spf1 include:${domain} -all"`, `"part one" "part two"`),
+			{
+				Name: aws.String(`\052.example.com.`),
+				Type: route53Types.RRTypeA,
+				AliasTarget: &route53Types.AliasTarget{
+					DNSName:      aws.String("example.com."),
+					HostedZoneId: aws.String("Z1"),
+				},
+			},
+			primary,
+		},
+	}
+
+	content, err := Terraform(zone)
+	require.NoError(t, err)
+	assert.Equal(t, `# Hosted zone example.com of account 111111111111, exported by oops
+
+resource "aws_route53_zone" "example_com" {
+  name = "example.com"
+}
+
+import {
+  to = aws_route53_zone.example_com
+  id = "Z1"
+}
+
+resource "aws_route53_record" "example_com_wildcard_a" {
+  zone_id = aws_route53_zone.example_com.zone_id
+  name    = "*.example.com"
+  type    = "A"
+
+  alias {
+    name                   = "example.com"
+    zone_id                = aws_route53_zone.example_com.zone_id
+    evaluate_target_health = false
+  }
+}
+
+import {
+  to = aws_route53_record.example_com_wildcard_a
+  id = "Z1_*.example.com_A"
+}
+
+resource "aws_route53_record" "example_com_api_a_primary" {
+  zone_id         = aws_route53_zone.example_com.zone_id
+  name            = "api.example.com"
+  type            = "A"
+  set_identifier  = "primary"
+  health_check_id = "abcdef-1234"
+  ttl             = 300
+  records         = ["192.0.2.1"]
+
+  failover_routing_policy {
+    type = "PRIMARY"
+  }
+}
+
+import {
+  to = aws_route53_record.example_com_api_a_primary
+  id = "Z1_api.example.com_A_primary"
+}
+
+resource "aws_route53_record" "example_com_apex_txt" {
+  zone_id = aws_route53_zone.example_com.zone_id
+  name    = "example.com"
+  type    = "TXT"
+  ttl     = 300
+  records = [
+    "v=spf1 include:$${domain} -all",
+    "part one\" \"part two",
+  ]
+}
+
+import {
+  to = aws_route53_record.example_com_apex_txt
+  id = "Z1_example.com_TXT"
+}
+
+resource "aws_route53_record" "example_com_www_cname_blue" {
+  zone_id        = aws_route53_zone.example_com.zone_id
+  name           = "www.example.com"
+  type           = "CNAME"
+  set_identifier = "blue"
+  ttl            = 300
+  records        = ["example.com."]
+
+  weighted_routing_policy {
+    weight = 10
+  }
+}
+
+import {
+  to = aws_route53_record.example_com_www_cname_blue
+  id = "Z1_www.example.com_CNAME_blue"
+}
+`, content)
+}
+
+func TestTerraformWithoutZoneId(t *testing.T) {
+	content, err := Terraform(Zone{
+		Account: "111111111111",
+		Name:    "internal.example",
+		Private: true,
+		Records: []route53Types.ResourceRecordSet{fake.Record("db.internal.example.", route53Types.RRTypeA, 300, "10.0.0.1")},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, content, "import blocks are left out")
+	assert.NotContains(t, content, "import {")
+	assert.Contains(t, content, "ignore_changes = [vpc]")
+	assert.Contains(t, content, `resource "aws_route53_record" "internal_example_db_a"`)
+}
+
+func TestTerraformRoutingPolicyAlignment(t *testing.T) {
+	rec := fake.Record("geo.example.com.", route53Types.RRTypeA, 300, "192.0.2.1")
+	rec.SetIdentifier = aws.String("eu")
+	rec.GeoProximityLocation = &route53Types.GeoProximityLocation{
+		AWSRegion: aws.String("eu-west-1"),
+		Bias:      aws.Int32(10),
+		Coordinates: &route53Types.Coordinates{
+			Latitude:  aws.String("53.35"),
+			Longitude: aws.String("-6.26"),
+		},
+	}
+
+	content, err := Terraform(Zone{Account: "111111111111", Name: "example.com.", Records: []route53Types.ResourceRecordSet{rec}})
+	require.NoError(t, err)
+	assert.Contains(t, content, `  geoproximity_routing_policy {
+    aws_region = "eu-west-1"
+    bias       = 10
+
+    coordinates {
+      latitude  = "53.35"
+      longitude = "-6.26"
+    }
+  }
+`)
+}
+
+func TestResourceNames(t *testing.T) {
+	names := newResourceNames()
+	assert.Equal(t, "example_com_www_a", names.unique("example.com._www_A_"))
+	assert.Equal(t, "example_com_www_a_2", names.unique("example.com._www.A_"))
+	assert.Equal(t, "_1password_example_com", names.unique("1password.example.com."))
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/feats/notify"
+	"go.dfds.cloud/oops/feats/storage"
+	"go.uber.org/zap"
+)
+
+const BackupStalenessJob = "backupStaleness"
+
+// BackupStaleness checks every enabled backup location for jobs whose newest backup is older than the configured threshold
+func BackupStaleness(ctx context.Context, conf config.Config) error {
+	locations := conf.BackupLocations
+	threshold := conf.Job.BackupStaleness.Threshold
+	stale := make(map[string][]string)
+
+	for _, location := range locations {
+		if !location.Enabled || !storage.IsRegistered(location.Provider) {
+			continue
+		}
+
+		store, err := storage.Open(ctx, location)
+		if err != nil {
+			return err
+		}
+
+		for job, artifact := range Artifacts {
+			newest, found, err := storage.NewestBackup(ctx, store, artifact)
+			if err != nil {
+				return err
+			}
+
+			if !found {
+				stale[job] = append(stale[job], fmt.Sprintf("%s: no backups found", location.Name))
+				continue
+			}
+
+			age := time.Since(newest.Timestamp)
+			if age > threshold {
+				stale[job] = append(stale[job], fmt.Sprintf("%s: newest backup %s is %s old", location.Name, newest.Id, age.Round(time.Minute)))
+			}
+		}
+	}
+
+	for job, details := range stale {
+		logging.Logger.Warn("Backups are stale", zap.String("job", job), zap.Strings("locations", details))
+		notify.Notify(ctx, notify.Event{
+			Type:    notify.EventBackupStale,
+			Job:     job,
+			Summary: fmt.Sprintf("No backup newer than %s in %d location(s)", threshold, len(details)),
+			Details: details,
+		})
+	}
+
+	return nil
+}
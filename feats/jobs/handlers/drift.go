@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/core/util"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.dfds.cloud/oops/feats/notify"
+	"go.dfds.cloud/oops/feats/storage"
+	"go.uber.org/zap"
+)
+
+// detectDrift compares freshly fetched records with the latest backup and sends a notification if records changed.
+// Accounts missing from the fresh records, e.g. because assuming the role failed, and the failed zones of the other accounts
+// are left out so they don't show up as removed.
+func detectDrift(ctx context.Context, locations []config.BackupLocation, records map[string]map[string][]route53Types.ResourceRecordSet, failedZones map[string][]string) {
+	previous, err := loadLatestRecords(ctx, locations)
+	if err != nil {
+		logging.Logger.Info("Unable to load previous backup, skipping drift detection", zap.Error(err))
+		return
+	}
+
+	comparable := make(map[string]map[string][]route53Types.ResourceRecordSet)
+	for acc := range records {
+		zones, ok := previous[acc]
+		if !ok {
+			continue
+		}
+		comparable[acc] = make(map[string][]route53Types.ResourceRecordSet)
+		for name, zone := range zones {
+			if !slices.Contains(failedZones[acc], name) {
+				comparable[acc][name] = zone
+			}
+		}
+	}
+	current := make(map[string]map[string][]route53Types.ResourceRecordSet)
+	for acc, zones := range records {
+		if _, ok := previous[acc]; ok {
+			current[acc] = zones
+		}
+	}
+
+	changes := oopsAws.DiffRecords(comparable, current)
+	if len(changes) == 0 {
+		return
+	}
+
+	details := make([]string, 0, len(changes))
+	for _, change := range changes {
+		details = append(details, change.String())
+	}
+
+	notify.Notify(ctx, notify.Event{
+		Type:    notify.EventDnsDrift,
+		Job:     Route53BackupJob,
+		RunId:   runner.RunId(ctx),
+		Summary: fmt.Sprintf("%d record set(s) changed since the previous backup", len(changes)),
+		Details: details,
+		Data:    map[string]any{"changes": changes},
+	})
+}
+
+// loadLatestRecords reads records.json from the latest backup in the first enabled location that has one
+func loadLatestRecords(ctx context.Context, locations []config.BackupLocation) (map[string]map[string][]route53Types.ResourceRecordSet, error) {
+	var lastErr error = fmt.Errorf("no enabled backup location")
+
+	for _, location := range locations {
+		if !location.Enabled || !storage.IsRegistered(location.Provider) {
+			continue
+		}
+
+		records, err := loadRecordsFromLocation(ctx, location, storage.LatestKey)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		return records, nil
+	}
+
+	return nil, lastErr
+}
+
+func loadRecordsFromLocation(ctx context.Context, location config.BackupLocation, key string) (map[string]map[string][]route53Types.ResourceRecordSet, error) {
+	store, err := storage.Open(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return ReadBackupRecords(body)
+}
+
+// ReadBackupRecords reads the records by account and zone from a backup tarball
+func ReadBackupRecords(r io.Reader) (map[string]map[string][]route53Types.ResourceRecordSet, error) {
+	buf, err := util.ReadFileFromGzippedTarball(r, RecordsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var records map[string]map[string][]route53Types.ResourceRecordSet
+	err = json.Unmarshal(buf, &records)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not valid: %w", RecordsFile, err)
+	}
+
+	return records, nil
+}
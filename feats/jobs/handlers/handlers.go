@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+)
+
+const DummyJob = "dummy"
+
+// Schedule is how often a job is run by the scheduler, see job.<name>.enable and job.<name>.interval
+type Schedule struct {
+	Enable   bool
+	Interval time.Duration
+}
+
+// Register adds every job to the runner. Jobs get the config current at the start of the run passed, so changes applied
+// by a reload take effect from the next run on.
+func Register(r *runner.Runner, store *config.Store) {
+	r.Register(DummyJob, func(ctx context.Context) error {
+		logging.Logger.Info("dummy")
+		return nil
+	})
+	r.Register(Route53BackupJob, withConfig(store, Route53BackupJob, Route53Backup))
+	r.Register(BackupStalenessJob, withConfig(store, BackupStalenessJob, BackupStaleness))
+	r.Register(TakeoverScanJob, withConfig(store, TakeoverScanJob, TakeoverScan))
+}
+
+// withConfig also names the AWS role sessions of the run after the job and run id, so they can be told apart in CloudTrail
+func withConfig(store *config.Store, job string, fn func(ctx context.Context, conf config.Config) error) runner.JobFunc {
+	return func(ctx context.Context) error {
+		ctx = oopsAws.WithSessionName(ctx, oopsAws.SessionName(job, runner.RunId(ctx)))
+		return fn(ctx, store.Current())
+	}
+}
+
+// Schedules returns the schedule of every registered job
+func Schedules(conf config.Config) map[string]Schedule {
+	return map[string]Schedule{
+		DummyJob:           {Enable: conf.Job.Dummy.Enable, Interval: conf.Job.Dummy.Interval},
+		Route53BackupJob:   {Enable: conf.Job.Route53Backup.Enable, Interval: conf.Job.Route53Backup.Interval},
+		BackupStalenessJob: {Enable: conf.Job.BackupStaleness.Enable, Interval: conf.Job.BackupStaleness.Interval},
+		TakeoverScanJob:    {Enable: conf.Job.TakeoverScan.Enable, Interval: conf.Job.TakeoverScan.Interval},
+	}
+}
+
+// ChangedJobs returns the jobs whose settings, including their schedule, differ between the configs
+func ChangedJobs(old config.Config, new config.Config) []string {
+	var payload []string
+
+	if old.Job.Dummy != new.Job.Dummy {
+		payload = append(payload, DummyJob)
+	}
+
+	locationsChanged := !reflect.DeepEqual(old.BackupLocations, new.BackupLocations)
+	if locationsChanged || !reflect.DeepEqual(old.Job.Route53Backup, new.Job.Route53Backup) {
+		payload = append(payload, Route53BackupJob)
+	}
+	if locationsChanged || old.Job.BackupStaleness != new.Job.BackupStaleness {
+		payload = append(payload, BackupStalenessJob)
+	}
+	if locationsChanged || !reflect.DeepEqual(old.Job.Route53Backup, new.Job.Route53Backup) ||
+		old.Job.TakeoverScan != new.Job.TakeoverScan || old.SelfserviceApi != new.SelfserviceApi {
+		payload = append(payload, TakeoverScanJob)
+	}
+
+	return payload
+}
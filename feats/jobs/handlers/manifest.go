@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"go.dfds.cloud/oops/core/util"
+	"go.dfds.cloud/oops/feats/export"
+)
+
+const Route53BackupJob = "route53Backup"
+const Route53BackupArtifact = "zones.tar.gz"
+const ManifestFile = "manifest.json"
+const RecordsFile = "records.json"
+const LintFile = "lint.json"
+const DelegationsFile = "delegations.json"
+
+// Artifacts maps job names to the name of the tarball they upload to backup locations
+var Artifacts = map[string]string{
+	Route53BackupJob: Route53BackupArtifact,
+}
+
+// BackupManifest describes the content of a backup tarball, stored alongside the zone files as manifest.json
+type BackupManifest struct {
+	Job       string                           `json:"job"`
+	CreatedAt time.Time                        `json:"createdAt"`
+	Accounts  map[string]BackupManifestAccount `json:"accounts"`
+}
+
+type BackupManifestAccount struct {
+	Zones map[string]BackupManifestZone `json:"zones"`
+}
+
+type BackupManifestZone struct {
+	File    string `json:"file"`
+	Records int    `json:"records"`
+	// Id is the hosted zone id, exports need it to import the zone and its records. Older backups don't have it.
+	Id      string `json:"id,omitempty"`
+	Private bool   `json:"private,omitempty"`
+}
+
+// Route53BackupReport summarises a run of the Route53 backup job for the run history
+type Route53BackupReport struct {
+	Accounts       int      `json:"accounts"`
+	AccountsFailed []string `json:"accountsFailed"`
+	// ZonesFailed are the zones of backed up accounts whose records couldn't be fetched, as account/zone
+	ZonesFailed []string `json:"zonesFailed"`
+	// RecordsSkipped are values left out of the zone files as they couldn't be formatted, as account/zone: record. They're
+	// still in records.json.
+	RecordsSkipped   []string `json:"recordsSkipped"`
+	Zones            int      `json:"zones"`
+	Records          int      `json:"records"`
+	LintFindings     int      `json:"lintFindings"`
+	DelegationIssues int      `json:"delegationIssues"`
+	Locations        []string `json:"locations"`
+	// Artifact is where a dry run left the backup instead of uploading it
+	Artifact string `json:"artifact,omitempty"`
+}
+
+func newRoute53BackupReport(accounts []string, records map[string]map[string][]route53Types.ResourceRecordSet, failedZones map[string][]string) *Route53BackupReport {
+	report := &Route53BackupReport{
+		Accounts:       len(accounts),
+		AccountsFailed: []string{},
+		ZonesFailed:    []string{},
+		RecordsSkipped: []string{},
+		Locations:      []string{},
+	}
+
+	for _, acc := range accounts {
+		zones, ok := records[acc]
+		if !ok {
+			report.AccountsFailed = append(report.AccountsFailed, acc)
+			continue
+		}
+		for _, zone := range failedZones[acc] {
+			report.ZonesFailed = append(report.ZonesFailed, acc+"/"+zone)
+		}
+		report.Zones += len(zones)
+		for _, zone := range zones {
+			report.Records += len(zone)
+		}
+	}
+
+	return report
+}
+
+func (r *Route53BackupReport) FailedItems() []string {
+	var payload []string
+	for _, acc := range r.AccountsFailed {
+		payload = append(payload, fmt.Sprintf("account %s: unable to back up hosted zones", acc))
+	}
+	for _, zone := range r.ZonesFailed {
+		account, name, _ := strings.Cut(zone, "/")
+		payload = append(payload, fmt.Sprintf("account %s: unable to back up hosted zone %s", account, name))
+	}
+	for _, rec := range r.RecordsSkipped {
+		zone, description, _ := strings.Cut(rec, ": ")
+		account, name, _ := strings.Cut(zone, "/")
+		payload = append(payload, fmt.Sprintf("account %s: record left out of the zone file of %s, %s", account, name, description))
+	}
+	return payload
+}
+
+// ZoneFilePath returns the path of a zone file within a Route53 backup tarball
+func ZoneFilePath(account string, zone string) string {
+	if !strings.HasSuffix(zone, ".") {
+		zone += "."
+	}
+	return fmt.Sprintf("%s/%s-%s.zone", account, account, zone)
+}
+
+// ReadBackupZone reads a zone of an account from a backup tarball for exporting it. The hosted zone id is taken from the
+// manifest, backups without one export the zone without it.
+func ReadBackupZone(content []byte, account string, zone string) (export.Zone, error) {
+	if !strings.HasSuffix(zone, ".") {
+		zone += "."
+	}
+	payload := export.Zone{Account: account, Name: zone}
+
+	records, err := ReadBackupRecords(bytes.NewReader(content))
+	if err != nil {
+		return payload, err
+	}
+	recs, ok := records[account][zone]
+	if !ok {
+		return payload, fmt.Errorf("zone %s of account %s: %w", zone, account, os.ErrNotExist)
+	}
+	payload.Records = recs
+
+	manifest, err := ReadBackupManifest(content)
+	if err != nil || manifest == nil {
+		return payload, err
+	}
+	entry := manifest.Accounts[account].Zones[zone]
+	payload.Id = entry.Id
+	payload.Private = entry.Private
+
+	return payload, nil
+}
+
+// ReadBackupManifest reads the manifest of a backup tarball, it's nil for backups taken before manifests were introduced
+func ReadBackupManifest(content []byte) (*BackupManifest, error) {
+	rawManifest, err := util.ReadFileFromGzippedTarball(bytes.NewReader(content), ManifestFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest BackupManifest
+	err = json.Unmarshal(rawManifest, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not valid: %w", ManifestFile, err)
+	}
+	return &manifest, nil
+}
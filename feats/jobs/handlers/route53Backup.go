@@ -5,43 +5,93 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
-	"github.com/aws/aws-sdk-go-v2/service/sts"
 	oopsAws "go.dfds.cloud/oops/core/aws"
 	"go.dfds.cloud/oops/core/config"
 	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/core/metrics"
+	selfserviceapi "go.dfds.cloud/oops/core/ssu/selfservice-api"
 	"go.dfds.cloud/oops/core/util"
-	"go.dfds.cloud/oops/feats/storage/s3"
+	"go.dfds.cloud/oops/feats/delegation"
+	"go.dfds.cloud/oops/feats/export"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.dfds.cloud/oops/feats/lint"
+	"go.dfds.cloud/oops/feats/storage"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/semaphore"
 )
 
-func Route53Backup(ctx context.Context) error {
-	logging.Logger.Info("Taking backup of Route53 zones")
+// Route53BackupDeps are the AWS clients the Route53 backup talks to, tests replace them with the fake package.
+// Backup locations are opened through the storage registry.
+type Route53BackupDeps struct {
+	// Factory assumes the backup role in every account
+	Factory *oopsAws.ConfigFactory
+	// NewRoute53 creates the client for an account, it's called once per account and run
+	NewRoute53 func(session AwsSession) oopsAws.Route53Api
+	// Probe looks up record targets outside the backup for linting, lint checks needing it are skipped if it's nil
+	Probe lint.Probe
+	// Capabilities lists the capabilities of the Self-Service API to group delegation issues by, issues are left
+	// ungrouped if it's nil
+	Capabilities func() ([]*selfserviceapi.GetCapabilitiesResponseContextCapability, error)
+	// NameServers queries delegated name servers to find lame delegations, they aren't looked for if it's nil
+	NameServers delegation.Querier
+}
 
-	conf, err := config.LoadConfig()
-	if err != nil {
-		return err
+// NewRoute53BackupDeps returns the clients talking to AWS
+func NewRoute53BackupDeps(conf config.Config) Route53BackupDeps {
+	requestsPerSecond := conf.Job.Route53Backup.RequestsPerSecond
+
+	deps := Route53BackupDeps{
+		Factory: oopsAws.NewConfigFactory(conf.Job.Route53Backup.Aws),
+		NewRoute53: func(session AwsSession) oopsAws.Route53Api {
+			return oopsAws.NewRoute53Client(session.SessionConfig, oopsAws.NewRoute53Limiter(requestsPerSecond))
+		},
 	}
+	if conf.Job.Route53Backup.Lint.ProbeTargets {
+		deps.Probe = lint.NewProbe(conf.Job.Route53Backup.Lint.ProbeTimeout)
+	}
+	if conf.SelfserviceApi.Host != "" {
+		deps.Capabilities = selfserviceapi.NewClient(conf.SelfserviceApi).GetCapabilities
+	}
+	if conf.Job.Route53Backup.Delegations.QueryServers {
+		deps.NameServers = delegation.NewQuerier(conf.Job.Route53Backup.Delegations.QueryTimeout)
+	}
+
+	return deps
+}
+
+func Route53Backup(ctx context.Context, conf config.Config) error {
+	return RunRoute53Backup(ctx, conf, NewRoute53BackupDeps(conf))
+}
+
+// RunRoute53Backup takes the backup with the given dependencies
+func RunRoute53Backup(ctx context.Context, conf config.Config, deps Route53BackupDeps) error {
+	logging.Logger.Info("Taking backup of Route53 zones")
 
 	accs := conf.Route53AwsAccounts()
 
-	sessions, err := AssumeRoleForAccounts(ctx, accs, conf.Job.Route53Backup.AssumeRole)
+	sessions, err := AssumeRoleForAccounts(ctx, deps.Factory, accs, conf.Job.Route53Backup.AssumeRole, Route53BackupRoleOptions(conf))
 	if err != nil {
 		return err
 	}
 
 	// Fetch DNS records
-	recordsByAccountAndZone, err := fetchHostedZones(ctx, sessions)
+	recordsByAccountAndZone, hostedZones, failedZones, err := fetchHostedZones(ctx, sessions, deps.NewRoute53, conf.Job.Route53Backup.ZoneConcurrency)
 	if err != nil {
 		return err
 	}
+	partial := len(recordsByAccountAndZone) < len(accs) || len(failedZones) > 0
+
+	locations := conf.BackupLocations
+	detectDrift(ctx, locations, recordsByAccountAndZone, failedZones)
 
 	// Dump all records into JSON and zone files
 	serialised, err := json.MarshalIndent(recordsByAccountAndZone, "", "  ")
@@ -49,74 +99,292 @@ func Route53Backup(ctx context.Context) error {
 		return err
 	}
 
-	err = os.MkdirAll("zones", 0755)
+	// Every run is staged in a directory of its own, so nothing of earlier runs, like zone files of zones failing now or
+	// exports of formats no longer configured, ends up in the backup
+	dir, err := os.MkdirTemp("", "oops-route53-backup-")
 	if err != nil {
 		return err
 	}
+	defer os.RemoveAll(dir)
 
-	err = os.WriteFile("zones/records.json", serialised, 0644)
+	err = os.WriteFile(filepath.Join(dir, RecordsFile), serialised, 0644)
 	if err != nil {
 		return err
 	}
 
+	createdAt := time.Now().UTC()
+
+	var findings []lint.Finding
+	if conf.Job.Route53Backup.Lint.Enabled {
+		findings, err = lintRecords(ctx, dir, recordsByAccountAndZone, createdAt, lint.Options{
+			MaxTtl:  conf.Job.Route53Backup.Lint.MaxTtl,
+			Probe:   deps.Probe,
+			Partial: partial,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var issues []delegation.Issue
+	if conf.Job.Route53Backup.Delegations.Enabled {
+		issues, err = checkDelegations(ctx, dir, recordsByAccountAndZone, hostedZones, createdAt, partial, deps)
+		if err != nil {
+			return err
+		}
+	}
+
+	manifest := BackupManifest{
+		Job:       Route53BackupJob,
+		CreatedAt: createdAt,
+		Accounts:  make(map[string]BackupManifestAccount),
+	}
+
+	var skippedRecords []string
 	for acc, zones := range recordsByAccountAndZone {
+		manifest.Accounts[acc] = BackupManifestAccount{Zones: make(map[string]BackupManifestZone)}
+
 		for name, zone := range zones {
-			zoneFileContent, err := oopsAws.GenerateZoneFile(zone, name)
+			zoneFileContent, skipped, err := oopsAws.GenerateZoneFile(zone, name)
 			if err != nil {
 				return err
 			}
+			for _, rec := range skipped {
+				logging.Logger.Warn("Record value left out of the zone file", zap.String("account", acc), zap.String("zone", name), zap.String("record", rec))
+				skippedRecords = append(skippedRecords, fmt.Sprintf("%s/%s: %s", acc, name, rec))
+			}
 
-			dirPath := fmt.Sprintf("zones/%s", acc)
+			err = os.MkdirAll(filepath.Join(dir, acc), 0755)
+			if err != nil {
+				return err
+			}
 
-			err = os.MkdirAll(dirPath, 0755)
+			zoneFilePath := ZoneFilePath(acc, name)
+			err = os.WriteFile(filepath.Join(dir, zoneFilePath), []byte(zoneFileContent), 0644)
 			if err != nil {
 				return err
 			}
 
-			err = os.WriteFile(fmt.Sprintf("%s/%s-%s.zone", dirPath, acc, name), []byte(zoneFileContent), 0644)
+			hostedZone := hostedZones[acc][name]
+			manifestZone := BackupManifestZone{
+				File:    zoneFilePath,
+				Records: len(zone),
+				Id:      aws.ToString(hostedZone.Id),
+				Private: hostedZone.Config != nil && hostedZone.Config.PrivateZone,
+			}
+
+			err = writeExports(dir, conf.Job.Route53Backup.Exports, export.Zone{
+				Account: acc,
+				Name:    name,
+				Id:      manifestZone.Id,
+				Private: manifestZone.Private,
+				Records: zone,
+			})
 			if err != nil {
 				return err
 			}
+
+			manifest.Accounts[acc].Zones[name] = manifestZone
 		}
 	}
 
-	// compress and tarball
-	data, err := util.GzipAndTarballDirBuf("zones")
+	serialisedManifest, err := json.MarshalIndent(manifest, "", "  ")
 	if err != nil {
 		return err
 	}
-	err = os.WriteFile("zones.tar.gz", data, 0644)
+
+	err = os.WriteFile(filepath.Join(dir, ManifestFile), serialisedManifest, 0644)
 	if err != nil {
 		return err
 	}
 
-	// Replicate tarball to backup destinations
-	confFromJson, err := config.LoadConfigFromJsonFile("conf.json")
+	// compress and tarball
+	data, err := util.GzipAndTarballDirBuf(dir)
 	if err != nil {
 		return err
 	}
 
-	for _, location := range confFromJson.BackupLocations {
+	// Replicate tarball to backup destinations
+	report := newRoute53BackupReport(accs, recordsByAccountAndZone, failedZones)
+	sort.Strings(skippedRecords)
+	report.RecordsSkipped = append(report.RecordsSkipped, skippedRecords...)
+	report.LintFindings = len(findings)
+	report.DelegationIssues = len(issues)
+	recordBackupMetrics(report, recordsByAccountAndZone)
+
+	if runner.IsDryRun(ctx) {
+		report.Artifact, err = writeDryRunArtifact(data)
+		if err != nil {
+			return err
+		}
+		logging.Logger.Info(fmt.Sprintf("Dry run, leaving backup at %s instead of uploading it", report.Artifact))
+		runner.SetReport(ctx, report)
+		return nil
+	}
+
+	for _, location := range locations {
 		if !location.Enabled {
 			continue
 		}
-		switch location.Provider {
-		case "s3":
-			logging.Logger.Debug("using aws s3 backend", zap.String("locationName", location.Name))
-			err = s3.HandleS3LocationPut(ctx, location, "zones.tar.gz", data)
-			if err != nil {
-				return err
-			}
-		default:
+		if !storage.IsRegistered(location.Provider) {
 			logging.Logger.Info(fmt.Sprintf("unknown provider %s, skipping", location.Provider))
+			continue
 		}
+
+		logging.Logger.Debug("uploading backup", zap.String("locationName", location.Name), zap.String("provider", location.Provider))
+		uploadStart := time.Now()
+		err = uploadBackup(ctx, location, data)
+		metrics.UploadDuration.WithLabelValues(location.Name, location.Provider).Observe(time.Since(uploadStart).Seconds())
+		if err != nil {
+			metrics.UploadFailures.WithLabelValues(location.Name, location.Provider).Inc()
+			return err
+		}
+		metrics.UploadedBytes.WithLabelValues(location.Name, location.Provider).Add(float64(len(data)))
+		metrics.LastSuccessfulBackup.WithLabelValues(Route53BackupJob, location.Name).SetToCurrentTime()
+		runner.AddProgress(ctx, runner.ProgressUploadsDone, 1)
+		report.Locations = append(report.Locations, location.Name)
+	}
+
+	runner.SetReport(ctx, report)
+
+	return nil
+}
+
+func uploadBackup(ctx context.Context, location config.BackupLocation, data []byte) error {
+	store, err := storage.Open(ctx, location)
+	if err != nil {
+		return err
+	}
+
+	err = storage.PutBackup(ctx, store, Route53BackupArtifact, data)
+	if err != nil {
+		return err
 	}
 
+	logging.Logger.Info("Saved backup to storage location", zap.String("location", location.Name), zap.String("provider", location.Provider))
 	return nil
 }
 
-func fetchHostedZones(ctx context.Context, sessions map[string]AwsSession) (map[string]map[string][]route53Types.ResourceRecordSet, error) {
+// writeDryRunArtifact writes the tarball of a dry run to a temporary file and returns its path
+func writeDryRunArtifact(data []byte) (string, error) {
+	file, err := os.CreateTemp("", "oops-*-"+Route53BackupArtifact)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// writeExports renders the zone in every configured export format next to its zone file in dir. A format that can't
+// render the zone is logged and left out, exports are a convenience and shouldn't fail the backup.
+func writeExports(dir string, formats []string, zone export.Zone) error {
+	for _, name := range formats {
+		format, ok := export.Lookup(name)
+		if !ok {
+			logging.Logger.Warn("Skipping unknown export format", zap.String("format", name))
+			continue
+		}
+
+		content, err := format.Render(zone)
+		if err != nil {
+			logging.Logger.Warn("Unable to export hosted zone, leaving it out of the backup", zap.String("account", zone.Account), zap.String("zone", zone.Name), zap.String("format", name), zap.Error(err))
+			continue
+		}
+
+		path := filepath.Join(dir, export.FilePath(name, zone.Account, zone.Name))
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lintRecords checks the records, writes the findings to lint.json in dir and records them in metrics
+func lintRecords(ctx context.Context, dir string, records map[string]map[string][]route53Types.ResourceRecordSet, createdAt time.Time, opts lint.Options) ([]lint.Finding, error) {
+	findings := lint.Run(ctx, records, opts)
+
+	serialised, err := json.MarshalIndent(lint.Report{CreatedAt: createdAt, Findings: append([]lint.Finding{}, findings...)}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(filepath.Join(dir, LintFile), serialised, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.LintFindings.Reset()
+	for acc, checks := range lint.CountByCheck(records, findings) {
+		for check, count := range checks {
+			metrics.LintFindings.WithLabelValues(acc, check).Set(float64(count))
+		}
+	}
+
+	if len(findings) > 0 {
+		logging.Logger.Info(fmt.Sprintf("Linting found %d problem(s) with records, see %s in the backup", len(findings), LintFile))
+	}
+
+	return findings, nil
+}
+
+// checkDelegations checks the delegations between the public zones of all accounts, writes the issues grouped by
+// capability to delegations.json in dir and records them in metrics
+func checkDelegations(ctx context.Context, dir string, records map[string]map[string][]route53Types.ResourceRecordSet, hostedZones map[string]map[string]route53Types.HostedZone, createdAt time.Time, partial bool, deps Route53BackupDeps) ([]delegation.Issue, error) {
+	var zones []delegation.Zone
+	for acc, accountZones := range records {
+		for name, zoneRecords := range accountZones {
+			if hostedZone := hostedZones[acc][name]; hostedZone.Config != nil && hostedZone.Config.PrivateZone {
+				continue
+			}
+			zones = append(zones, delegation.Zone{Account: acc, Name: name, Records: zoneRecords})
+		}
+	}
+
+	issues := delegation.Check(ctx, zones, delegation.Options{Querier: deps.NameServers, Partial: partial})
+	grouped := delegation.Group(issues, accountOwners(deps.Capabilities))
+
+	serialised, err := json.MarshalIndent(delegation.Report{CreatedAt: createdAt, Capabilities: grouped}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(filepath.Join(dir, DelegationsFile), serialised, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.DelegationIssues.Reset()
+	for _, group := range grouped {
+		for _, issue := range group.Issues {
+			metrics.DelegationIssues.WithLabelValues(group.CapabilityId, issue.Problem).Inc()
+		}
+	}
+
+	if len(issues) > 0 {
+		logging.Logger.Info(fmt.Sprintf("Found %d problem(s) with delegations, see %s in the backup", len(issues), DelegationsFile))
+	}
+
+	return issues, nil
+}
+
+// fetchHostedZones fetches the records of every zone, zones of an account are fetched in parallel within the request rate
+// of the account. Accounts whose zones can't be listed after retries are left out. Zones whose records can't be fetched
+// are left out as well and returned by account, so one failing zone doesn't cost the backup of the whole account. The
+// hosted zones are returned by account and name as well.
+func fetchHostedZones(ctx context.Context, sessions map[string]AwsSession, newRoute53 func(session AwsSession) oopsAws.Route53Api, zoneConcurrency int) (map[string]map[string][]route53Types.ResourceRecordSet, map[string]map[string]route53Types.HostedZone, map[string][]string, error) {
 	payload := make(map[string]map[string][]route53Types.ResourceRecordSet)
+	hostedZones := make(map[string]map[string]route53Types.HostedZone)
+	failedZones := make(map[string][]string)
 	var maxConcurrentOps int64 = 30
 	var waitGroup sync.WaitGroup
 	payloadMutex := &sync.Mutex{}
@@ -130,61 +398,105 @@ func fetchHostedZones(ctx context.Context, sessions map[string]AwsSession) (map[
 			defer sem.Release(1)
 			defer waitGroup.Done()
 			logging.Logger.Info(fmt.Sprintf("Fetching hosted zones for account %s\n", sessionWg.AccountId))
-			route53Client := route53.NewFromConfig(sessionWg.SessionConfig)
+			route53Client := newRoute53(sessionWg)
 
-			respZones, err := route53Client.ListHostedZones(ctx, &route53.ListHostedZonesInput{})
+			zones, accountHostedZones, failed, err := fetchAccountZones(ctx, sessionWg.AccountId, route53Client, zoneConcurrency)
 			if err != nil {
-				logging.Logger.Error("Failed to list hosted zones", zap.Error(err))
+				logging.Logger.Error("Failed to fetch hosted zones, skipping account", zap.String("account", sessionWg.AccountId), zap.Error(err))
+				runner.AddProgress(ctx, runner.ProgressAccountsFailed, 1)
 				return
 			}
 
-			if _, ok := payload[sessionWg.AccountId]; !ok {
-				payloadMutex.Lock()
-				payload[sessionWg.AccountId] = make(map[string][]route53Types.ResourceRecordSet)
-				payloadMutex.Unlock()
+			payloadMutex.Lock()
+			payload[sessionWg.AccountId] = zones
+			hostedZones[sessionWg.AccountId] = accountHostedZones
+			if len(failed) > 0 {
+				failedZones[sessionWg.AccountId] = failed
 			}
+			payloadMutex.Unlock()
+			runner.AddProgress(ctx, runner.ProgressAccountsProcessed, 1)
+		}()
+	}
 
-			for _, zone := range respZones.HostedZones {
-				if _, ok := payload[sessionWg.AccountId][*zone.Name]; !ok {
-					payloadMutex.Lock()
-					payload[sessionWg.AccountId][*zone.Name] = []route53Types.ResourceRecordSet{}
-					payloadMutex.Unlock()
-				}
+	waitGroup.Wait()
 
-				pag := route53.NewListResourceRecordSetsPaginator(route53Client, &route53.ListResourceRecordSetsInput{HostedZoneId: zone.Id})
-				for pag.HasMorePages() {
-					recordsResp, err := pag.NextPage(ctx)
-					if err != nil {
-						logging.Logger.Error("Failed to paginate hosted zone", zap.Error(err))
-						return
-					}
-					for _, record := range recordsResp.ResourceRecordSets {
-						payloadMutex.Lock()
-						payload[sessionWg.AccountId][*zone.Name] = append(payload[sessionWg.AccountId][*zone.Name], record)
-						payloadMutex.Unlock()
-					}
+	return payload, hostedZones, failedZones, nil
+}
+
+// fetchAccountZones returns the records of the zones in the account, along with the sorted names of the zones whose
+// records couldn't be fetched. An error is only returned if the zones can't be listed.
+func fetchAccountZones(ctx context.Context, account string, route53Client oopsAws.Route53Api, zoneConcurrency int) (map[string][]route53Types.ResourceRecordSet, map[string]route53Types.HostedZone, []string, error) {
+	var hostedZones []route53Types.HostedZone
+	zonePag := route53.NewListHostedZonesPaginator(route53Client, &route53.ListHostedZonesInput{})
+	for zonePag.HasMorePages() {
+		resp, err := zonePag.NextPage(ctx)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("listing hosted zones: %w", err)
+		}
+		hostedZones = append(hostedZones, resp.HostedZones...)
+	}
+
+	payload := make(map[string][]route53Types.ResourceRecordSet)
+	var failed []string
+	mu := &sync.Mutex{}
+	group := &errgroup.Group{}
+	group.SetLimit(zoneConcurrency)
+
+	for _, zone := range hostedZones {
+		group.Go(func() error {
+			records := []route53Types.ResourceRecordSet{}
+			pag := route53.NewListResourceRecordSetsPaginator(route53Client, &route53.ListResourceRecordSetsInput{HostedZoneId: zone.Id})
+			for pag.HasMorePages() {
+				recordsResp, err := pag.NextPage(ctx)
+				if err != nil {
+					// The other zones of the account are still backed up
+					logging.Logger.Error("Failed to fetch records, skipping zone", zap.String("account", account), zap.String("zone", aws.ToString(zone.Name)), zap.Error(err))
+					mu.Lock()
+					failed = append(failed, aws.ToString(zone.Name))
+					mu.Unlock()
+					return nil
 				}
+				records = append(records, recordsResp.ResourceRecordSets...)
 			}
-		}()
+
+			mu.Lock()
+			payload[*zone.Name] = records
+			mu.Unlock()
+			runner.AddProgress(ctx, runner.ProgressZonesFetched, 1)
+			return nil
+		})
 	}
 
-	waitGroup.Wait()
+	group.Wait()
 
-	return payload, nil
+	byName := make(map[string]route53Types.HostedZone)
+	for _, zone := range hostedZones {
+		byName[*zone.Name] = zone
+	}
+	sort.Strings(failed)
+
+	return payload, byName, failed, nil
 }
 
-func AssumeRoleForAccounts(ctx context.Context, accounts []string, roleName string) (map[string]AwsSession, error) {
+// Route53BackupRoleOptions returns how the backup role is assumed in each account
+func Route53BackupRoleOptions(conf config.Config) oopsAws.RoleOptions {
+	return oopsAws.RoleOptions{
+		HubRoleArn: conf.Job.Route53Backup.HubRoleArn,
+		ExternalId: conf.Job.Route53Backup.ExternalId,
+		Duration:   conf.Job.Route53Backup.SessionDuration,
+		Tags:       conf.Job.Route53Backup.SessionTags,
+	}
+}
+
+// AssumeRoleForAccounts assumes roleName in every account, accounts where that fails are skipped. The sessions refresh their
+// credentials on their own, so they stay usable for runs longer than the session duration.
+func AssumeRoleForAccounts(ctx context.Context, factory *oopsAws.ConfigFactory, accounts []string, roleName string, opts oopsAws.RoleOptions) (map[string]AwsSession, error) {
 	payload := make(map[string]AwsSession)
 	var maxConcurrentOps int64 = 30
 	var waitGroup sync.WaitGroup
 	payloadMutex := &sync.Mutex{}
 	sem := semaphore.NewWeighted(maxConcurrentOps)
 
-	cfg, err := awsConfig.LoadDefaultConfig(ctx, awsConfig.WithRegion("eu-west-1"), awsConfig.WithHTTPClient(oopsAws.CreateHttpClientWithoutKeepAlive()))
-	if err != nil {
-		return payload, err
-	}
-
 	for _, acc := range accounts {
 		waitGroup.Add(1)
 		accWg := acc
@@ -193,19 +505,20 @@ func AssumeRoleForAccounts(ctx context.Context, accounts []string, roleName stri
 			defer sem.Release(1)
 			defer waitGroup.Done()
 
-			roleArn := fmt.Sprintf("arn:aws:iam::%s:role/%s", accWg, roleName)
+			roleArn := factory.RoleArn(accWg, roleName)
 
-			stsClient := sts.NewFromConfig(cfg)
-			roleSessionName := "oops"
-			assumedRole, err := stsClient.AssumeRole(ctx, &sts.AssumeRoleInput{RoleArn: &roleArn, RoleSessionName: &roleSessionName})
+			assumedCfg, err := factory.AssumeRoleConfig(ctx, roleArn, opts)
 			if err != nil {
-				logging.Logger.Debug(fmt.Sprintf("unable to assume role %s, skipping account", roleArn), zap.Error(err))
+				logging.Logger.Error(fmt.Sprintf("unable to load SDK config, %v", err))
 				return
 			}
 
-			assumedCfg, err := awsConfig.LoadDefaultConfig(ctx, awsConfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(*assumedRole.Credentials.AccessKeyId, *assumedRole.Credentials.SecretAccessKey, *assumedRole.Credentials.SessionToken)), awsConfig.WithRegion("eu-west-1"))
+			// Credentials are retrieved lazily, assume the role up front so accounts we can't access are skipped
+			_, err = assumedCfg.Credentials.Retrieve(ctx)
 			if err != nil {
-				logging.Logger.Error(fmt.Sprintf("unable to load SDK config, %v", err))
+				logging.Logger.Debug(fmt.Sprintf("unable to assume role %s, skipping account", roleArn), zap.Error(err))
+				runner.AddProgress(ctx, runner.ProgressAccountsFailed, 1)
+				metrics.AssumeRoleFailures.WithLabelValues(accWg).Inc()
 				return
 			}
 
@@ -223,6 +536,23 @@ func AssumeRoleForAccounts(ctx context.Context, accounts []string, roleName stri
 	return payload, nil
 }
 
+func recordBackupMetrics(report *Route53BackupReport, records map[string]map[string][]route53Types.ResourceRecordSet) {
+	metrics.BackupAccounts.WithLabelValues(Route53BackupJob, "succeeded").Set(float64(report.Accounts - len(report.AccountsFailed)))
+	metrics.BackupAccounts.WithLabelValues(Route53BackupJob, "failed").Set(float64(len(report.AccountsFailed)))
+
+	// Drop accounts that are no longer part of the backup
+	metrics.BackupZones.DeletePartialMatch(map[string]string{"job": Route53BackupJob})
+	metrics.BackupRecords.DeletePartialMatch(map[string]string{"job": Route53BackupJob})
+	for acc, zones := range records {
+		recordCount := 0
+		for _, zone := range zones {
+			recordCount += len(zone)
+		}
+		metrics.BackupZones.WithLabelValues(Route53BackupJob, acc).Set(float64(len(zones)))
+		metrics.BackupRecords.WithLabelValues(Route53BackupJob, acc).Set(float64(recordCount))
+	}
+}
+
 type AwsSession struct {
 	AccountId     string
 	SessionConfig aws.Config
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/aws/fake"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/core/util"
+	"go.dfds.cloud/oops/feats/delegation"
+	"go.dfds.cloud/oops/feats/export"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.dfds.cloud/oops/feats/lint"
+	"go.dfds.cloud/oops/feats/storage"
+	"go.dfds.cloud/oops/feats/storage/memory"
+	"go.uber.org/zap"
+)
+
+func TestRunRoute53Backup(t *testing.T) {
+	logging.Logger = zap.NewNop()
+	t.Chdir(t.TempDir())
+
+	store := memory.New()
+	storage.Register("memory-test", func(_ context.Context, _ config.BackupLocation) (storage.Storage, error) {
+		return store, nil
+	})
+
+	var conf config.Config
+	conf.Job.Route53Backup.AssumeRole = "oops-backup"
+	conf.Job.Route53Backup.Accounts = "111111111111, 222222222222"
+	conf.Job.Route53Backup.ZoneConcurrency = 2
+	conf.Job.Route53Backup.Exports = []string{"terraform", "octodns", "dnscontrol"}
+	conf.Job.Route53Backup.Lint.Enabled = true
+	conf.Job.Route53Backup.Lint.MaxTtl = 48 * time.Hour
+	conf.Job.Route53Backup.Delegations.Enabled = true
+	conf.BackupLocations = []config.BackupLocation{{Name: "memory", Provider: "memory-test", Enabled: true}}
+
+	zones := fake.NewRoute53()
+	// A page size of one makes every zone and record a page of its own
+	zones.PageSize = 1
+	zones.AddZone("example.com",
+		fake.Record("example.com.", route53Types.RRTypeA, 300, "192.0.2.1"),
+		fake.Record("www.example.com.", route53Types.RRTypeCname, 300, "example.com."),
+		fake.Record("dev.example.com.", route53Types.RRTypeNs, 300, "ns-3.awsdns-03.net."),
+	)
+	zones.AddZone("example.org", fake.Record("example.org.", route53Types.RRTypeTxt, 300, `"v=spf1 -all"`))
+
+	// The role can't be assumed in the second account, which should be reported instead of failing the run
+	fakeSts := fake.NewSts()
+	fakeSts.Deny("arn:aws:iam::222222222222:role/oops-backup")
+
+	deps := Route53BackupDeps{
+		Factory: oopsAws.NewConfigFactory().WithSts(fakeSts),
+		NewRoute53: func(session AwsSession) oopsAws.Route53Api {
+			assert.Equal(t, "111111111111", session.AccountId)
+			return zones
+		},
+	}
+
+	r := runner.New(context.Background(), runner.NewMemoryHistory(5))
+	r.Register(Route53BackupJob, func(ctx context.Context) error {
+		ctx = oopsAws.WithSessionName(ctx, oopsAws.SessionName(Route53BackupJob, runner.RunId(ctx)))
+		return RunRoute53Backup(ctx, conf, deps)
+	})
+	run, err := r.Run(context.Background(), Route53BackupJob, runner.TriggerCli)
+	require.NoError(t, err)
+
+	assert.Equal(t, runner.StatusSucceeded, run.Status)
+	report := run.Report.(*Route53BackupReport)
+	assert.Equal(t, 2, report.Accounts)
+	assert.Equal(t, []string{"222222222222"}, report.AccountsFailed)
+	assert.Equal(t, 2, report.Zones)
+	assert.Equal(t, 8, report.Records)
+	// The delegation isn't flagged, as its zone might be in the account that failed
+	assert.Equal(t, 0, report.LintFindings)
+	assert.Equal(t, 0, report.DelegationIssues)
+	assert.Equal(t, []string{"memory"}, report.Locations)
+
+	for _, call := range fakeSts.Calls() {
+		assert.Equal(t, "oops-route53Backup-"+run.Id, aws.ToString(call.RoleSessionName))
+	}
+
+	// The backup is stored as latest and under a dated key
+	objects, err := store.List(context.Background(), "")
+	require.NoError(t, err)
+	assert.Len(t, objects, 2)
+
+	latest, _, err := store.Get(context.Background(), storage.LatestKey)
+	require.NoError(t, err)
+	content, err := io.ReadAll(latest)
+	require.NoError(t, err)
+
+	records, err := ReadBackupRecords(bytes.NewReader(content))
+	require.NoError(t, err)
+	// SOA and NS are added by the fake like Route53 does
+	assert.Len(t, records["111111111111"]["example.com."], 5)
+	assert.Len(t, records["111111111111"]["example.org."], 3)
+	assert.NotContains(t, records, "222222222222")
+
+	// Exports are rendered next to the zone files, with the hosted zone ids from the manifest
+	zone, err := ReadBackupZone(content, "111111111111", "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "/hostedzone/Z1", zone.Id)
+	assert.Len(t, zone.Records, 5)
+
+	files, err := util.ReadGzippedTarball(bytes.NewReader(content))
+	require.NoError(t, err)
+	terraform := string(files["111111111111/terraform/example.com.tf"])
+	assert.Contains(t, terraform, `resource "aws_route53_record" "example_com_www_cname"`)
+	assert.Contains(t, terraform, `id = "Z1_www.example.com_CNAME"`)
+	assert.Contains(t, string(files["111111111111/octodns/example.org.yaml"]), "type: TXT")
+	assert.Contains(t, string(files["111111111111/dnscontrol/example.com.js"]), `CNAME("www", "example.com.", TTL(300))`)
+
+	var lintReport lint.Report
+	require.NoError(t, json.Unmarshal(files[LintFile], &lintReport))
+	assert.Empty(t, lintReport.Findings)
+
+	var delegationReport delegation.Report
+	require.NoError(t, json.Unmarshal(files[DelegationsFile], &delegationReport))
+	assert.Empty(t, delegationReport.Capabilities)
+
+	backups, err := storage.ListBackups(context.Background(), store, Route53BackupArtifact)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.True(t, strings.HasSuffix(backups[0].Key, Route53BackupArtifact))
+}
+
+func TestRunRoute53Backup_FailedZone(t *testing.T) {
+	logging.Logger = zap.NewNop()
+	t.Chdir(t.TempDir())
+
+	var conf config.Config
+	conf.Job.Route53Backup.AssumeRole = "oops-backup"
+	conf.Job.Route53Backup.Accounts = "111111111111"
+	conf.Job.Route53Backup.ZoneConcurrency = 2
+	conf.Job.Route53Backup.Exports = []string{"terraform"}
+	conf.Job.Route53Backup.Lint.Enabled = true
+
+	zones := fake.NewRoute53()
+	zones.AddZone("example.com", fake.Record("www.example.com.", route53Types.RRTypeA, 300, "192.0.2.1"))
+	failing := zones.AddZone("example.org")
+
+	deps := Route53BackupDeps{
+		Factory:    oopsAws.NewConfigFactory().WithSts(fake.NewSts()),
+		NewRoute53: func(session AwsSession) oopsAws.Route53Api { return zones },
+	}
+
+	r := runner.New(context.Background(), runner.NewMemoryHistory(5))
+	r.Register(Route53BackupJob, func(ctx context.Context) error {
+		return RunRoute53Backup(runner.WithDryRun(ctx), conf, deps)
+	})
+	backup := func() (*Route53BackupReport, []string) {
+		run, err := r.Run(context.Background(), Route53BackupJob, runner.TriggerCli)
+		require.NoError(t, err)
+		report := run.Report.(*Route53BackupReport)
+		t.Cleanup(func() { os.Remove(report.Artifact) })
+
+		content, err := os.ReadFile(report.Artifact)
+		require.NoError(t, err)
+		entries, err := util.ListGzippedTarball(bytes.NewReader(content))
+		require.NoError(t, err)
+		var files []string
+		for _, entry := range entries {
+			files = append(files, entry.Name)
+		}
+		return report, files
+	}
+
+	_, files := backup()
+	assert.Contains(t, files, ZoneFilePath("111111111111", "example.org."))
+	assert.Contains(t, files, LintFile)
+
+	// The second run in the same directory leaves out what the first run wrote for the failing zone, the removed export
+	// and the disabled check
+	zones.FailZone(failing, errors.New("throttled"))
+	conf.Job.Route53Backup.Exports = nil
+	conf.Job.Route53Backup.Lint.Enabled = false
+	report, files := backup()
+
+	// The zone that failed is reported, the other zone of the account is still backed up
+	assert.Empty(t, report.AccountsFailed)
+	assert.Equal(t, []string{"111111111111/example.org."}, report.ZonesFailed)
+	assert.Equal(t, []string{"account 111111111111: unable to back up hosted zone example.org."}, report.FailedItems())
+	assert.Equal(t, 1, report.Zones)
+	assert.Contains(t, files, ZoneFilePath("111111111111", "example.com."))
+	assert.NotContains(t, files, ZoneFilePath("111111111111", "example.org."))
+	assert.NotContains(t, files, export.FilePath("terraform", "111111111111", "example.com."))
+	assert.NotContains(t, files, LintFile)
+
+	content, err := os.ReadFile(report.Artifact)
+	require.NoError(t, err)
+	records, err := ReadBackupRecords(bytes.NewReader(content))
+	require.NoError(t, err)
+	assert.Len(t, records["111111111111"]["example.com."], 3)
+	assert.NotContains(t, records["111111111111"], "example.org.")
+
+	// Nothing is left in the working directory
+	leftovers, err := os.ReadDir(".")
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
+
+func TestWriteExports(t *testing.T) {
+	logging.Logger = zap.NewNop()
+	dir := t.TempDir()
+
+	export.Register("failing-test", export.Format{Extension: "txt", Render: func(zone export.Zone) (string, error) {
+		return "", errors.New("unsupported record")
+	}})
+
+	zone := export.Zone{Account: "111111111111", Name: "example.com.", Records: []route53Types.ResourceRecordSet{
+		fake.Record("www.example.com.", route53Types.RRTypeA, 300, "192.0.2.1"),
+	}}
+	err := writeExports(dir, []string{"failing-test", "terraform"}, zone)
+	require.NoError(t, err, "a format that can't render the zone doesn't fail the backup")
+
+	assert.FileExists(t, filepath.Join(dir, export.FilePath("terraform", "111111111111", "example.com.")))
+	assert.NoFileExists(t, filepath.Join(dir, export.FilePath("failing-test", "111111111111", "example.com.")))
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/core/metrics"
+	selfserviceapi "go.dfds.cloud/oops/core/ssu/selfservice-api"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.dfds.cloud/oops/feats/lint"
+	"go.dfds.cloud/oops/feats/notify"
+	"go.dfds.cloud/oops/feats/takeover"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const TakeoverScanJob = "takeoverScan"
+
+// TakeoverScanDeps are the clients the takeover scan talks to, tests replace them
+type TakeoverScanDeps struct {
+	// Factory assumes the backup role in every account. Besides Route53, the role needs to be allowed to list the
+	// resources with s3:ListAllMyBuckets, cloudfront:ListDistributions, elasticbeanstalk:DescribeEnvironments and
+	// elasticloadbalancing:DescribeLoadBalancers.
+	Factory *oopsAws.ConfigFactory
+	// NewResources creates the client of an account and region, the empty region being the default region of the session
+	NewResources func(session AwsSession, region string) oopsAws.ResourcesApi
+	// Capabilities lists the capabilities of the Self-Service API to find the owners of accounts, findings have no owner if it's nil
+	Capabilities func() ([]*selfserviceapi.GetCapabilitiesResponseContextCapability, error)
+	// Probe looks up resources missing from our accounts publicly, findings are unverified if it's nil
+	Probe lint.Probe
+}
+
+// NewTakeoverScanDeps returns the clients talking to AWS and the Self-Service API
+func NewTakeoverScanDeps(conf config.Config) TakeoverScanDeps {
+	deps := TakeoverScanDeps{
+		Factory: oopsAws.NewConfigFactory(conf.Job.Route53Backup.Aws),
+		NewResources: func(session AwsSession, region string) oopsAws.ResourcesApi {
+			cfg := session.SessionConfig.Copy()
+			if region != "" {
+				cfg.Region = region
+			}
+			return oopsAws.NewResources(cfg)
+		},
+	}
+	if conf.SelfserviceApi.Host != "" {
+		deps.Capabilities = selfserviceapi.NewClient(conf.SelfserviceApi).GetCapabilities
+	}
+	if conf.Job.TakeoverScan.Probe {
+		deps.Probe = lint.NewProbe(conf.Job.TakeoverScan.ProbeTimeout)
+	}
+
+	return deps
+}
+
+// TakeoverScanReport summarises a run of the takeover scan for the run history
+type TakeoverScanReport struct {
+	Accounts int `json:"accounts"`
+	// AccountsFailed are accounts whose resources couldn't be listed, records pointing at them may be reported wrongly
+	AccountsFailed []string `json:"accountsFailed"`
+	// Targets is the number of records pointing at AWS resources
+	Targets  int                `json:"targets"`
+	Findings []takeover.Finding `json:"findings"`
+}
+
+func (r *TakeoverScanReport) FailedItems() []string {
+	var payload []string
+	for _, acc := range r.AccountsFailed {
+		payload = append(payload, fmt.Sprintf("account %s: unable to list resources", acc))
+	}
+	return payload
+}
+
+func TakeoverScan(ctx context.Context, conf config.Config) error {
+	return RunTakeoverScan(ctx, conf, NewTakeoverScanDeps(conf))
+}
+
+// RunTakeoverScan checks the CNAME and alias records of the latest backup against the resources of the backed up accounts
+func RunTakeoverScan(ctx context.Context, conf config.Config, deps TakeoverScanDeps) error {
+	logging.Logger.Info("Scanning records for subdomain takeover risks")
+
+	records, err := loadLatestRecords(ctx, conf.BackupLocations)
+	if err != nil {
+		return fmt.Errorf("loading records of the latest backup: %w", err)
+	}
+	targets := takeover.Targets(records)
+
+	accs := conf.Route53AwsAccounts()
+	sessions, err := AssumeRoleForAccounts(ctx, deps.Factory, accs, conf.Job.Route53Backup.AssumeRole, Route53BackupRoleOptions(conf))
+	if err != nil {
+		return err
+	}
+
+	inventory, collected := collectInventories(ctx, sessions, deps.NewResources, takeover.Regions(targets))
+
+	findings := takeover.Check(ctx, targets, inventory, takeover.Options{
+		Probe:  deps.Probe,
+		Owners: accountOwners(deps.Capabilities),
+	})
+
+	report := &TakeoverScanReport{
+		Accounts:       len(accs),
+		AccountsFailed: []string{},
+		Targets:        len(targets),
+		Findings:       append([]takeover.Finding{}, findings...),
+	}
+	for _, acc := range accs {
+		if !collected[acc] {
+			report.AccountsFailed = append(report.AccountsFailed, acc)
+		}
+	}
+
+	metrics.TakeoverFindings.Reset()
+	for acc, statuses := range takeover.CountByStatus(accs, findings) {
+		for status, count := range statuses {
+			metrics.TakeoverFindings.WithLabelValues(acc, status).Set(float64(count))
+		}
+	}
+
+	// Only resources anyone can create are notified about, the others are in the report and metrics for review
+	var claimable []takeover.Finding
+	var details []string
+	for _, finding := range findings {
+		if finding.Claimable() {
+			claimable = append(claimable, finding)
+			details = append(details, finding.String())
+		}
+	}
+	if len(findings) > len(claimable) {
+		logging.Logger.Info(fmt.Sprintf("%d record(s) point at AWS resources outside our accounts that can't be claimed or couldn't be verified, see the run report", len(findings)-len(claimable)))
+	}
+	if len(claimable) > 0 {
+		logging.Logger.Warn("Records point at AWS resources anyone can create", zap.Strings("findings", details))
+		notify.Notify(ctx, notify.Event{
+			Type:    notify.EventTakeoverRisk,
+			Job:     TakeoverScanJob,
+			RunId:   runner.RunId(ctx),
+			Summary: fmt.Sprintf("%d record(s) point at AWS resources anyone can create", len(claimable)),
+			Details: details,
+			Data:    map[string]any{"findings": claimable},
+		})
+	}
+
+	runner.SetReport(ctx, report)
+
+	return nil
+}
+
+// collectInventories lists the resources of every account in parallel, accounts where that fails are left out. The
+// accounts whose resources were listed are returned as well.
+func collectInventories(ctx context.Context, sessions map[string]AwsSession, newResources func(session AwsSession, region string) oopsAws.ResourcesApi, regions []string) (takeover.Inventory, map[string]bool) {
+	inventory := make(takeover.Inventory)
+	collected := make(map[string]bool)
+	mu := &sync.Mutex{}
+
+	group := &errgroup.Group{}
+	group.SetLimit(10)
+	for _, session := range sessions {
+		group.Go(func() error {
+			accountInventory, err := takeover.Collect(ctx, session.AccountId, func(region string) oopsAws.ResourcesApi {
+				return newResources(session, region)
+			}, regions)
+			if err != nil {
+				logging.Logger.Error("Failed to list resources, skipping account", zap.String("account", session.AccountId), zap.Error(err))
+				return nil
+			}
+
+			mu.Lock()
+			inventory.Merge(accountInventory)
+			collected[session.AccountId] = true
+			mu.Unlock()
+			return nil
+		})
+	}
+	group.Wait()
+
+	return inventory, collected
+}
+
+// accountOwners maps account ids to their capability. Owners are left out if the Self-Service API can't be reached.
+func accountOwners(capabilities func() ([]*selfserviceapi.GetCapabilitiesResponseContextCapability, error)) map[string]selfserviceapi.AccountOwner {
+	if capabilities == nil {
+		return map[string]selfserviceapi.AccountOwner{}
+	}
+
+	list, err := capabilities()
+	if err != nil {
+		logging.Logger.Warn("Unable to look up capabilities, reporting without owners", zap.Error(err))
+		return map[string]selfserviceapi.AccountOwner{}
+	}
+
+	return selfserviceapi.OwnersByAwsAccount(list)
+}
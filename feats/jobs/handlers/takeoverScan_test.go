@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/aws/fake"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	selfserviceapi "go.dfds.cloud/oops/core/ssu/selfservice-api"
+	"go.dfds.cloud/oops/core/util"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.dfds.cloud/oops/feats/storage"
+	"go.dfds.cloud/oops/feats/storage/memory"
+	"go.dfds.cloud/oops/feats/takeover"
+	"go.uber.org/zap"
+)
+
+func TestRunTakeoverScan(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	store := memory.New()
+	storage.Register("memory-takeover", func(_ context.Context, _ config.BackupLocation) (storage.Storage, error) {
+		return store, nil
+	})
+
+	// The scan reads the records of the latest backup
+	dir := t.TempDir()
+	serialised, err := json.Marshal(map[string]map[string][]route53Types.ResourceRecordSet{
+		"111111111111": {"example.com.": {
+			fake.Record("www.example.com.", route53Types.RRTypeCname, 300, "d111.cloudfront.net."),
+			fake.Record("old.example.com.", route53Types.RRTypeCname, 300, "old-site.s3-website-eu-west-1.amazonaws.com."),
+		}},
+		"222222222222": {"team.example.com.": {
+			fake.Record("app.team.example.com.", route53Types.RRTypeCname, 300, "app.eu-west-1.elasticbeanstalk.com."),
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, RecordsFile), serialised, 0644))
+	tarball, err := util.GzipAndTarballDirBuf(dir)
+	require.NoError(t, err)
+	require.NoError(t, storage.PutBackup(context.Background(), store, Route53BackupArtifact, tarball))
+
+	var conf config.Config
+	conf.Job.Route53Backup.AssumeRole = "oops-backup"
+	conf.Job.Route53Backup.Accounts = "111111111111,222222222222,333333333333"
+	conf.BackupLocations = []config.BackupLocation{{Name: "memory", Provider: "memory-takeover", Enabled: true}}
+
+	resources := map[string]*fake.Resources{
+		"111111111111": {Distributions: []string{"d111.cloudfront.net"}},
+		"222222222222": {Environments: []string{"app.eu-west-1.elasticbeanstalk.com"}},
+		"333333333333": {Err: errors.New("AccessDenied")},
+	}
+	deps := TakeoverScanDeps{
+		Factory: oopsAws.NewConfigFactory().WithSts(fake.NewSts()),
+		NewResources: func(session AwsSession, region string) oopsAws.ResourcesApi {
+			return resources[session.AccountId]
+		},
+		Capabilities: func() ([]*selfserviceapi.GetCapabilitiesResponseContextCapability, error) {
+			return []*selfserviceapi.GetCapabilitiesResponseContextCapability{
+				{ID: "web-abcd", Name: "web", Contexts: []*selfserviceapi.GetCapabilitiesResponseContext{{AwsAccountID: "111111111111"}}},
+			}, nil
+		},
+	}
+
+	r := runner.New(context.Background(), runner.NewMemoryHistory(5))
+	r.Register(TakeoverScanJob, func(ctx context.Context) error {
+		return RunTakeoverScan(ctx, conf, deps)
+	})
+	run, err := r.Run(context.Background(), TakeoverScanJob, runner.TriggerCli)
+	require.NoError(t, err)
+
+	assert.Equal(t, runner.StatusSucceeded, run.Status)
+	report := run.Report.(*TakeoverScanReport)
+	assert.Equal(t, 3, report.Accounts)
+	assert.Equal(t, []string{"333333333333"}, report.AccountsFailed)
+	assert.Equal(t, 3, report.Targets)
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, "old.example.com.", report.Findings[0].Name)
+	assert.Equal(t, "old-site", report.Findings[0].Target.Name)
+	assert.Equal(t, takeover.StatusUnverified, report.Findings[0].Status)
+	assert.Equal(t, &selfserviceapi.AccountOwner{CapabilityId: "web-abcd", CapabilityName: "web"}, report.Findings[0].Owner)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/util"
+)
+
+// VerifyBackup checks a Route53 backup tarball for internal consistency, returning the problems found. An error is only
+// returned if the tarball can't be read at all.
+func VerifyBackup(content []byte) ([]string, error) {
+	var problems []string
+
+	files, err := util.ReadGzippedTarball(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	rawRecords, ok := files[RecordsFile]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", RecordsFile, os.ErrNotExist)
+	}
+
+	var records map[string]map[string][]route53Types.ResourceRecordSet
+	err = json.Unmarshal(rawRecords, &records)
+	if err != nil {
+		return append(problems, fmt.Sprintf("%s is not valid: %s", RecordsFile, err)), nil
+	}
+
+	var manifest *BackupManifest
+	// Backups taken before manifests were introduced don't have one
+	if rawManifest, ok := files[ManifestFile]; ok {
+		err = json.Unmarshal(rawManifest, &manifest)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s is not valid: %s", ManifestFile, err))
+			manifest = nil
+		}
+	}
+
+	for acc, zones := range records {
+		for zone, recs := range zones {
+			path := ZoneFilePath(acc, zone)
+			zoneFile, ok := files[path]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("zone file %s is missing", path))
+			} else if len(zoneFile) == 0 && len(recs) > 0 {
+				problems = append(problems, fmt.Sprintf("zone file %s is empty, but %d record sets were backed up", path, len(recs)))
+			} else if len(zoneFile) > 0 {
+				problems = append(problems, compareZoneFile(path, zoneFile, recs, zone)...)
+			}
+
+			if manifest == nil {
+				continue
+			}
+			entry, ok := manifest.Accounts[acc].Zones[zone]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("zone %s of account %s is missing from the manifest", zone, acc))
+			} else if entry.Records != len(recs) {
+				problems = append(problems, fmt.Sprintf("manifest lists %d record sets for zone %s of account %s, %s has %d", entry.Records, zone, acc, RecordsFile, len(recs)))
+			}
+		}
+	}
+
+	if manifest != nil {
+		for acc, account := range manifest.Accounts {
+			for zone := range account.Zones {
+				if _, ok := records[acc][zone]; !ok {
+					problems = append(problems, fmt.Sprintf("manifest lists zone %s of account %s, but %s doesn't", zone, acc, RecordsFile))
+				}
+			}
+		}
+	}
+
+	sort.Strings(problems)
+
+	return problems, nil
+}
+
+// compareZoneFile checks that the zone file can serve as a restore source, by parsing it back into the backed up records
+func compareZoneFile(path string, content []byte, records []route53Types.ResourceRecordSet, zone string) []string {
+	changes, err := oopsAws.CompareZoneFile(string(content), records, zone)
+	if err != nil {
+		return []string{fmt.Sprintf("zone file %s can't be parsed: %s", path, err)}
+	}
+
+	var payload []string
+	for _, change := range changes {
+		payload = append(payload, fmt.Sprintf("zone file %s doesn't match %s: %s", path, RecordsFile, change))
+	}
+	return payload
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/aws/fake"
+	"go.dfds.cloud/oops/core/util"
+)
+
+// tarball packs the files, paths relative to the root of the tarball
+func tarball(t *testing.T, files map[string][]byte) []byte {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, content, 0644))
+	}
+	content, err := util.GzipAndTarballDirBuf(dir)
+	require.NoError(t, err)
+	return content
+}
+
+func TestVerifyBackup(t *testing.T) {
+	soa := fake.Record("example.com.", route53Types.RRTypeSoa, 300, "ns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400")
+	records := map[string]map[string][]route53Types.ResourceRecordSet{
+		"111111111111": {"example.com.": {
+			soa,
+			fake.Record("www.example.com.", route53Types.RRTypeA, 300, "192.0.2.1"),
+			fake.Record("example.com.", route53Types.RRTypeTxt, 300, `"v=spf1 -all"`),
+		}},
+	}
+	zoneFile, _, err := oopsAws.GenerateZoneFile(records["111111111111"]["example.com."], "example.com.")
+	require.NoError(t, err)
+
+	longTtl := []route53Types.ResourceRecordSet{
+		soa,
+		fake.Record("www.example.com.", route53Types.RRTypeA, 300, "192.0.2.1"),
+		fake.Record("example.com.", route53Types.RRTypeTxt, 300, `"v=spf1 -all"`),
+	}
+	longTtl[1].TTL = aws.Int64(3600)
+	longTtlZoneFile, _, err := oopsAws.GenerateZoneFile(longTtl, "example.com.")
+	require.NoError(t, err)
+
+	manifest := func(zones map[string]int) []byte {
+		m := BackupManifest{Job: Route53BackupJob, Accounts: map[string]BackupManifestAccount{"111111111111": {Zones: map[string]BackupManifestZone{}}}}
+		for zone, count := range zones {
+			m.Accounts["111111111111"].Zones[zone] = BackupManifestZone{File: ZoneFilePath("111111111111", zone), Records: count}
+		}
+		serialised, err := json.Marshal(m)
+		require.NoError(t, err)
+		return serialised
+	}
+	serialisedRecords, err := json.Marshal(records)
+	require.NoError(t, err)
+	path := ZoneFilePath("111111111111", "example.com.")
+
+	tests := []struct {
+		name     string
+		files    map[string][]byte
+		problems []string
+	}{
+		{
+			name:  "consistent",
+			files: map[string][]byte{RecordsFile: serialisedRecords, path: []byte(zoneFile), ManifestFile: manifest(map[string]int{"example.com.": 3})},
+		},
+		{
+			name:  "without manifest",
+			files: map[string][]byte{RecordsFile: serialisedRecords, path: []byte(zoneFile)},
+		},
+		{
+			name:     "missing zone file",
+			files:    map[string][]byte{RecordsFile: serialisedRecords, ManifestFile: manifest(map[string]int{"example.com.": 3})},
+			problems: []string{"zone file 111111111111/111111111111-example.com..zone is missing"},
+		},
+		{
+			name:     "empty zone file",
+			files:    map[string][]byte{RecordsFile: serialisedRecords, path: {}},
+			problems: []string{"zone file 111111111111/111111111111-example.com..zone is empty, but 3 record sets were backed up"},
+		},
+		{
+			name:  "extra records",
+			files: map[string][]byte{RecordsFile: serialisedRecords, path: []byte(zoneFile + "extra.example.com. 300 IN A 192.0.2.9\n")},
+			problems: []string{
+				"zone file 111111111111/111111111111-example.com..zone doesn't match records.json: added /example.com.: extra.example.com. A",
+			},
+		},
+		{
+			name:  "TTL difference",
+			files: map[string][]byte{RecordsFile: serialisedRecords, path: []byte(longTtlZoneFile)},
+			problems: []string{
+				"zone file 111111111111/111111111111-example.com..zone doesn't match records.json: modified /example.com.: www.example.com. A",
+			},
+		},
+		{
+			name:     "zone missing from the manifest",
+			files:    map[string][]byte{RecordsFile: serialisedRecords, path: []byte(zoneFile), ManifestFile: manifest(map[string]int{})},
+			problems: []string{"zone example.com. of account 111111111111 is missing from the manifest"},
+		},
+		{
+			name:     "record count mismatch",
+			files:    map[string][]byte{RecordsFile: serialisedRecords, path: []byte(zoneFile), ManifestFile: manifest(map[string]int{"example.com.": 4})},
+			problems: []string{"manifest lists 4 record sets for zone example.com. of account 111111111111, records.json has 3"},
+		},
+		{
+			name:     "extra zone in the manifest",
+			files:    map[string][]byte{RecordsFile: serialisedRecords, path: []byte(zoneFile), ManifestFile: manifest(map[string]int{"example.com.": 3, "example.org.": 1})},
+			problems: []string{"manifest lists zone example.org. of account 111111111111, but records.json doesn't"},
+		},
+		{
+			name:     "invalid records",
+			files:    map[string][]byte{RecordsFile: []byte("{")},
+			problems: []string{"records.json is not valid: unexpected end of JSON input"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems, err := VerifyBackup(tarball(t, test.files))
+			require.NoError(t, err)
+			assert.Equal(t, test.problems, problems)
+		})
+	}
+
+	_, err = VerifyBackup(tarball(t, map[string][]byte{path: []byte(zoneFile)}))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
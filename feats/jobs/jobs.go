@@ -3,20 +3,22 @@ package jobs
 import (
 	"context"
 
-	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/core/config"
 	"go.dfds.cloud/oops/feats/jobs/handlers"
-	"go.dfds.cloud/orchestrator"
+	"go.dfds.cloud/oops/feats/jobs/runner"
 )
 
-func Init(orc *orchestrator.Orchestrator) {
-	configPrefix := "SSU_OOPS_JOB"
+// Init registers the jobs with the runner and schedules them. The returned scheduler reschedules jobs once their
+// schedule changes in a reloaded config.
+func Init(ctx context.Context, r *runner.Runner, store *config.Store) *Scheduler {
+	handlers.Register(r, store)
 
-	orc.AddJob(configPrefix, orchestrator.NewJob("dummy", func(ctx context.Context) error {
-		logging.Logger.Info("dummy")
-		return nil
-	}), &orchestrator.Schedule{})
+	// Scheduled runs go through the runner as well, so they can't overlap with runs triggered via the API
+	scheduler := NewScheduler(ctx, r)
+	schedules := handlers.Schedules(store.Current())
+	for _, name := range r.Jobs() {
+		scheduler.Reschedule(name, schedules[name])
+	}
 
-	orc.AddJob(configPrefix, orchestrator.NewJob("route53Backup", handlers.Route53Backup), &orchestrator.Schedule{})
-
-	orc.Run()
+	return scheduler
 }
@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/k8s"
+)
+
+// HistoryStore persists runs, keeping the last retention runs per job
+type HistoryStore interface {
+	// Save inserts or updates the run
+	Save(ctx context.Context, run Run) error
+	// List returns the stored runs of the job, newest first
+	List(ctx context.Context, job string) ([]Run, error)
+}
+
+func NewHistoryStore(conf config.Config) (HistoryStore, error) {
+	retention := conf.RunHistory.Retention
+	if retention <= 0 {
+		return nil, fmt.Errorf("run history retention must be positive, got %d", retention)
+	}
+
+	switch conf.RunHistory.Store {
+	case "", "memory":
+		return NewMemoryHistory(retention), nil
+	case "file":
+		return NewFileHistory(conf.RunHistory.Path, retention), nil
+	case "configmap":
+		client, err := k8s.GetK8sClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewConfigMapHistory(client, conf.RunHistory.Namespace, conf.RunHistory.ConfigMapName, retention)
+	default:
+		return nil, fmt.Errorf("unknown run history store %s", conf.RunHistory.Store)
+	}
+}
+
+// upsertRun adds or replaces run in runs, returning the runs of its job newest first with at most retention entries
+func upsertRun(runs []Run, run Run, retention int) []Run {
+	payload := []Run{run}
+	for _, existing := range runs {
+		if existing.Id != run.Id {
+			payload = append(payload, existing)
+		}
+	}
+
+	sort.SliceStable(payload, func(i, j int) bool {
+		return payload[i].StartedAt.After(payload[j].StartedAt)
+	})
+
+	if len(payload) > retention {
+		payload = payload[:retention]
+	}
+
+	return payload
+}
@@ -0,0 +1,152 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.dfds.cloud/oops/core/k8s"
+	"go.dfds.cloud/oops/core/logging"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// maxDataBytes leaves headroom below the 1MiB limit of a ConfigMap for its metadata
+const maxDataBytes = 900 * 1024
+
+// ConfigMapHistory keeps the run history in a ConfigMap, one key per job. Older runs are dropped when the runs of all
+// jobs together wouldn't fit in the ConfigMap anymore.
+type ConfigMapHistory struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	retention int
+	maxBytes  int
+}
+
+// NewConfigMapHistory creates the store. The namespace defaults to the one of the pod's service account.
+func NewConfigMapHistory(client kubernetes.Interface, namespace string, name string, retention int) (*ConfigMapHistory, error) {
+	if namespace == "" {
+		current, err := k8s.CurrentNamespace()
+		if err != nil {
+			return nil, err
+		}
+		namespace = current
+	}
+
+	return &ConfigMapHistory{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		retention: retention,
+		maxBytes:  maxDataBytes,
+	}, nil
+}
+
+func (c *ConfigMapHistory) Save(ctx context.Context, run Run) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      c.name,
+					Namespace: c.namespace,
+					Labels:    map[string]string{"app.kubernetes.io/managed-by": "oops"},
+				},
+			}
+			err = c.writeRuns(cm, run)
+			if err != nil {
+				return err
+			}
+			_, err = c.client.CoreV1().ConfigMaps(c.namespace).Create(ctx, cm, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		err = c.writeRuns(cm, run)
+		if err != nil {
+			return err
+		}
+		_, err = c.client.CoreV1().ConfigMaps(c.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (c *ConfigMapHistory) List(ctx context.Context, job string) ([]Run, error) {
+	cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return []Run{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return readRuns(cm, job)
+}
+
+func (c *ConfigMapHistory) writeRuns(cm *corev1.ConfigMap, run Run) error {
+	runs, err := readRuns(cm, run.Job)
+	if err != nil {
+		return err
+	}
+
+	budget := c.maxBytes
+	for job, raw := range cm.Data {
+		if job != run.Job {
+			budget -= len(job) + len(raw)
+		}
+	}
+	budget -= len(run.Job)
+
+	runs = upsertRun(runs, run, c.retention)
+	serialised, err := json.Marshal(runs)
+	if err != nil {
+		return err
+	}
+	for len(serialised) > budget && len(runs) > 1 {
+		dropped := runs[len(runs)-1]
+		runs = runs[:len(runs)-1]
+		logging.Logger.Warn("Dropping run from the history to fit the ConfigMap", zap.String("job", dropped.Job), zap.String("runId", dropped.Id), zap.Int("bytes", len(serialised)), zap.Int("budget", budget))
+		serialised, err = json.Marshal(runs)
+		if err != nil {
+			return err
+		}
+	}
+	if len(serialised) > budget && runs[0].Report != nil {
+		// The report of a single run can outgrow the ConfigMap, e.g. the findings of a scan over many records
+		logging.Logger.Warn("Dropping report of run from the history to fit the ConfigMap", zap.String("job", runs[0].Job), zap.String("runId", runs[0].Id), zap.Int("bytes", len(serialised)), zap.Int("budget", budget))
+		trimmed := runs[0]
+		trimmed.Report = nil
+		serialised, err = json.Marshal([]Run{trimmed})
+		if err != nil {
+			return err
+		}
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[run.Job] = string(serialised)
+
+	return nil
+}
+
+func readRuns(cm *corev1.ConfigMap, job string) ([]Run, error) {
+	raw, ok := cm.Data[job]
+	if !ok {
+		return []Run{}, nil
+	}
+
+	var runs []Run
+	err := json.Unmarshal([]byte(raw), &runs)
+	if err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
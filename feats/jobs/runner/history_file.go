@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileHistory keeps the run history of all jobs in a single JSON file, e.g. on a persistent volume
+type FileHistory struct {
+	mu        sync.Mutex
+	path      string
+	retention int
+}
+
+func NewFileHistory(path string, retention int) *FileHistory {
+	return &FileHistory{
+		path:      path,
+		retention: retention,
+	}
+}
+
+func (f *FileHistory) Save(_ context.Context, run Run) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	runs, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	runs[run.Job] = upsertRun(runs[run.Job], run, f.retention)
+
+	serialised, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated history behind
+	tmp := f.path + ".tmp"
+	err = os.WriteFile(tmp, serialised, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, f.path)
+}
+
+func (f *FileHistory) List(_ context.Context, job string) ([]Run, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	runs, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]Run{}, runs[job]...), nil
+}
+
+func (f *FileHistory) read() (map[string][]Run, error) {
+	runs := make(map[string][]Run)
+
+	buf, err := os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return runs, os.MkdirAll(filepath.Dir(f.path), 0755)
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal(buf, &runs)
+	if err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"context"
+	"sync"
+)
+
+type MemoryHistory struct {
+	mu        sync.Mutex
+	retention int
+	runs      map[string][]Run
+}
+
+func NewMemoryHistory(retention int) *MemoryHistory {
+	return &MemoryHistory{
+		retention: retention,
+		runs:      make(map[string][]Run),
+	}
+}
+
+func (m *MemoryHistory) Save(_ context.Context, run Run) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.runs[run.Job] = upsertRun(m.runs[run.Job], run, m.retention)
+	return nil
+}
+
+func (m *MemoryHistory) List(_ context.Context, job string) ([]Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Run{}, m.runs[job]...), nil
+}
@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dfds.cloud/oops/core/logging"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testRun(job string, i int, status Status) Run {
+	return Run{
+		Id:        job + "-" + strconv.Itoa(i),
+		Job:       job,
+		Status:    status,
+		StartedAt: time.Date(2025, 1, 1, 0, i, 0, 0, time.UTC),
+		Progress:  map[string]int64{},
+	}
+}
+
+// testHistoryStore checks the behaviour shared by all stores
+func testHistoryStore(t *testing.T, store HistoryStore) {
+	ctx := context.Background()
+
+	runs, err := store.List(ctx, "backup")
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, store.Save(ctx, testRun("backup", i, StatusRunning)))
+	}
+	require.NoError(t, store.Save(ctx, testRun("other", 0, StatusRunning)))
+	// Updating a run replaces it
+	require.NoError(t, store.Save(ctx, testRun("backup", 4, StatusSucceeded)))
+
+	runs, err = store.List(ctx, "backup")
+	require.NoError(t, err)
+	// Only the newest runs are retained
+	require.Len(t, runs, 3)
+	assert.Equal(t, []string{"backup-4", "backup-3", "backup-2"}, []string{runs[0].Id, runs[1].Id, runs[2].Id})
+	assert.Equal(t, StatusSucceeded, runs[0].Status)
+
+	runs, err = store.List(ctx, "other")
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+}
+
+func TestMemoryHistory(t *testing.T) {
+	testHistoryStore(t, NewMemoryHistory(3))
+}
+
+func TestFileHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "runs.json")
+	testHistoryStore(t, NewFileHistory(path, 3))
+
+	// The history survives restarts
+	runs, err := NewFileHistory(path, 3).List(context.Background(), "backup")
+	require.NoError(t, err)
+	assert.Len(t, runs, 3)
+	assert.NoFileExists(t, path+".tmp")
+}
+
+func TestConfigMapHistory(t *testing.T) {
+	client := fake.NewClientset()
+	store, err := NewConfigMapHistory(client, "oops", "run-history", 3)
+	require.NoError(t, err)
+	testHistoryStore(t, store)
+
+	cm, err := client.CoreV1().ConfigMaps("oops").Get(context.Background(), "run-history", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, cm.Data, "backup")
+	assert.Contains(t, cm.Data, "other")
+	assert.Equal(t, "oops", cm.Labels["app.kubernetes.io/managed-by"])
+}
+
+func TestConfigMapHistory_Size(t *testing.T) {
+	logging.Logger = zap.NewNop()
+	ctx := context.Background()
+	client := fake.NewClientset()
+	store, err := NewConfigMapHistory(client, "oops", "run-history", 10)
+	require.NoError(t, err)
+	store.maxBytes = 4096
+
+	require.NoError(t, store.Save(ctx, testRun("other", 0, StatusSucceeded)))
+	for i := 0; i < 5; i++ {
+		run := testRun("scan", i, StatusSucceeded)
+		run.Report = strings.Repeat("x", 1000)
+		require.NoError(t, store.Save(ctx, run))
+	}
+
+	// The oldest runs are dropped until the ConfigMap fits
+	runs, err := store.List(ctx, "scan")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"scan-4", "scan-3", "scan-2"}, []string{runs[0].Id, runs[1].Id, runs[2].Id})
+	assert.Len(t, runs, 3)
+	others, err := store.List(ctx, "other")
+	require.NoError(t, err)
+	assert.Len(t, others, 1)
+
+	// A report too large on its own is dropped, keeping the run
+	huge := testRun("scan", 5, StatusSucceeded)
+	huge.Report = strings.Repeat("x", 5000)
+	require.NoError(t, store.Save(ctx, huge))
+	runs, err = store.List(ctx, "scan")
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, "scan-5", runs[0].Id)
+	assert.Nil(t, runs[0].Report)
+}
+
+func TestUpsertRun(t *testing.T) {
+	var runs []Run
+	for _, i := range []int{2, 0, 3, 1} {
+		runs = upsertRun(runs, testRun("backup", i, StatusRunning), 3)
+	}
+	assert.Equal(t, []string{"backup-3", "backup-2", "backup-1"}, []string{runs[0].Id, runs[1].Id, runs[2].Id})
+
+	// A run older than the retained ones is dropped right away
+	runs = upsertRun(runs, testRun("backup", 0, StatusSucceeded), 3)
+	assert.Len(t, runs, 3)
+	assert.NotEqual(t, "backup-0", runs[2].Id)
+}
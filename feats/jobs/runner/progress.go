@@ -0,0 +1,62 @@
+package runner
+
+import "context"
+
+const (
+	ProgressAccountsProcessed = "accountsProcessed"
+	ProgressAccountsFailed    = "accountsFailed"
+	ProgressZonesFetched      = "zonesFetched"
+	ProgressUploadsDone       = "uploadsDone"
+)
+
+type progressKey struct{}
+type dryRunKey struct{}
+
+type progressReporter struct {
+	runner *Runner
+	run    *Run
+}
+
+func withProgress(ctx context.Context, r *Runner, run *Run) context.Context {
+	return context.WithValue(ctx, progressKey{}, &progressReporter{runner: r, run: run})
+}
+
+// AddProgress increments a progress counter of the run executing within ctx. It is a no-op outside of a run.
+func AddProgress(ctx context.Context, key string, delta int64) {
+	reporter, ok := ctx.Value(progressKey{}).(*progressReporter)
+	if !ok {
+		return
+	}
+
+	reporter.runner.addProgress(reporter.run, key, delta)
+}
+
+// SetReport attaches a summary to the run executing within ctx, stored in the run history once the run finishes
+func SetReport(ctx context.Context, report any) {
+	reporter, ok := ctx.Value(progressKey{}).(*progressReporter)
+	if !ok {
+		return
+	}
+
+	reporter.runner.setReport(reporter.run, report)
+}
+
+// RunId returns the id of the run executing within ctx, or an empty string outside of a run
+func RunId(ctx context.Context) string {
+	reporter, ok := ctx.Value(progressKey{}).(*progressReporter)
+	if !ok {
+		return ""
+	}
+
+	return reporter.run.Id
+}
+
+// WithDryRun marks runs executed within the returned context as dry runs, jobs should skip anything with side effects
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
@@ -0,0 +1,426 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/core/metrics"
+	"go.uber.org/zap"
+)
+
+const TriggerSchedule = "schedule"
+const TriggerManual = "manual"
+const TriggerCli = "cli"
+
+var ErrJobNotFound = errors.New("job not found")
+var ErrJobAlreadyRunning = errors.New("job is already running")
+var ErrRunNotFound = errors.New("run not found")
+
+// finalDeliveryTimeout is how long subscribers have to take the final state of a run off their channel
+var finalDeliveryTimeout = 30 * time.Second
+
+type JobFunc func(ctx context.Context) error
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	// StatusInterrupted is reported for runs recorded as running that aren't active anymore, e.g. because the pod was restarted
+	StatusInterrupted Status = "interrupted"
+)
+
+type Run struct {
+	Id         string           `json:"id"`
+	Job        string           `json:"job"`
+	Trigger    string           `json:"trigger"`
+	Status     Status           `json:"status"`
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Progress   map[string]int64 `json:"progress"`
+	Report     any              `json:"report,omitempty"`
+}
+
+// PartialFailure can be implemented by run reports to flag items that failed without failing the run as a whole
+type PartialFailure interface {
+	FailedItems() []string
+}
+
+type JobStatus struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+	LastRun *Run   `json:"lastRun,omitempty"`
+}
+
+func (r *Run) copy() Run {
+	payload := *r
+	payload.Progress = make(map[string]int64, len(r.Progress))
+	for k, v := range r.Progress {
+		payload.Progress[k] = v
+	}
+	return payload
+}
+
+// Runner executes jobs, whether triggered by the scheduler or on demand, making sure only one run per job is active at a time
+type Runner struct {
+	ctx         context.Context
+	mu          sync.Mutex
+	jobs        map[string]JobFunc
+	active      map[string]*Run
+	history     HistoryStore
+	subscribers map[string][]chan Run
+	onFinish    []func(ctx context.Context, run Run)
+}
+
+func New(ctx context.Context, history HistoryStore) *Runner {
+	return &Runner{
+		ctx:         ctx,
+		jobs:        make(map[string]JobFunc),
+		active:      make(map[string]*Run),
+		history:     history,
+		subscribers: make(map[string][]chan Run),
+	}
+}
+
+func (r *Runner) Register(name string, fn JobFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[name] = fn
+}
+
+// OnFinish registers a function called after every run, successful or not
+func (r *Runner) OnFinish(fn func(ctx context.Context, run Run)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onFinish = append(r.onFinish, fn)
+}
+
+func (r *Runner) Jobs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var payload []string
+	for name := range r.jobs {
+		payload = append(payload, name)
+	}
+	sort.Strings(payload)
+
+	return payload
+}
+
+// Scheduled returns a function for the scheduler, running the job synchronously through the Runner
+func (r *Runner) Scheduled(name string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := r.Run(ctx, name, TriggerSchedule)
+		return err
+	}
+}
+
+// Run executes the job synchronously, returning the finished run along with the error of the job
+func (r *Runner) Run(ctx context.Context, name string, trigger string) (Run, error) {
+	run, fn, err := r.start(name, trigger)
+	if err != nil {
+		return Run{}, err
+	}
+
+	err = r.execute(ctx, run, fn)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return run.copy(), err
+}
+
+// Trigger starts a run of the job in the background. ErrJobAlreadyRunning is returned if the job is currently running.
+func (r *Runner) Trigger(name string) (Run, error) {
+	run, fn, err := r.start(name, TriggerManual)
+	if err != nil {
+		return Run{}, err
+	}
+
+	r.mu.Lock()
+	snapshot := run.copy()
+	r.mu.Unlock()
+
+	go r.execute(r.ctx, run, fn)
+
+	return snapshot, nil
+}
+
+func (r *Runner) Get(ctx context.Context, job string, id string) (Run, error) {
+	runs, err := r.Runs(ctx, job)
+	if err != nil {
+		return Run{}, err
+	}
+
+	for _, run := range runs {
+		if run.Id == id {
+			return run, nil
+		}
+	}
+
+	return Run{}, ErrRunNotFound
+}
+
+// Runs returns the retained runs of a job, newest first. The history is read without holding the lock, so slow stores
+// don't hold up runs starting and finishing.
+func (r *Runner) Runs(ctx context.Context, job string) ([]Run, error) {
+	r.mu.Lock()
+	_, ok := r.jobs[job]
+	r.mu.Unlock()
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	runs, err := r.history.List(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	runs, settled := r.mergeActive(job, runs, false)
+	if settled {
+		return runs, nil
+	}
+
+	// A run may have finished after the history was read. Runs are saved before they're marked inactive, so reading
+	// the history again tells finished runs apart from interrupted ones.
+	runs, err = r.history.List(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	runs, _ = r.mergeActive(job, runs, true)
+
+	return runs, nil
+}
+
+// mergeActive replaces stored runs of the job that are still active with their live state, the stored copy lags behind
+// on progress. Stored runs recorded as running that aren't active are marked interrupted if interrupt is set, otherwise
+// false is returned for them.
+func (r *Runner) mergeActive(job string, runs []Run, interrupt bool) ([]Run, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	active, isActive := r.active[job]
+	settled := true
+	stored := false
+	for i, run := range runs {
+		if isActive && active.Id == run.Id {
+			runs[i] = active.copy()
+			stored = true
+			continue
+		}
+		if run.Status != StatusRunning {
+			continue
+		}
+		if interrupt {
+			runs[i].Status = StatusInterrupted
+		} else {
+			settled = false
+		}
+	}
+
+	// The run may not have been saved yet
+	if isActive && !stored {
+		runs = append([]Run{active.copy()}, runs...)
+	}
+
+	return runs, settled
+}
+
+// Overview returns every registered job along with its most recent run
+func (r *Runner) Overview(ctx context.Context) ([]JobStatus, error) {
+	payload := []JobStatus{}
+
+	for _, name := range r.Jobs() {
+		runs, err := r.Runs(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		status := JobStatus{Name: name}
+		if len(runs) > 0 {
+			status.LastRun = &runs[0]
+			status.Running = runs[0].Status == StatusRunning
+		}
+		payload = append(payload, status)
+	}
+
+	return payload, nil
+}
+
+// Subscribe returns a channel receiving a snapshot of the run whenever its progress changes. The channel is closed once the run has finished.
+func (r *Runner) Subscribe(ctx context.Context, job string, id string) (<-chan Run, func(), error) {
+	ch := make(chan Run, 64)
+
+	r.mu.Lock()
+	run, ok := r.active[job]
+	if !ok || run.Id != id {
+		r.mu.Unlock()
+
+		// Not running (anymore), only deliver the final state
+		finished, err := r.Get(ctx, job, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		ch <- finished
+		close(ch)
+		return ch, func() {}, nil
+	}
+	defer r.mu.Unlock()
+
+	ch <- run.copy()
+	r.subscribers[id] = append(r.subscribers[id], ch)
+
+	unsubscribe := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		subs := r.subscribers[id]
+		for i, sub := range subs {
+			if sub == ch {
+				r.subscribers[id] = append(subs[:i], subs[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+	}
+
+	return ch, unsubscribe, nil
+}
+
+func (r *Runner) start(name string, trigger string) (*Run, JobFunc, error) {
+	r.mu.Lock()
+
+	fn, ok := r.jobs[name]
+	if !ok {
+		r.mu.Unlock()
+		return nil, nil, ErrJobNotFound
+	}
+
+	if _, ok := r.active[name]; ok {
+		r.mu.Unlock()
+		return nil, nil, ErrJobAlreadyRunning
+	}
+
+	now := time.Now()
+	run := &Run{
+		Id:        strconv.FormatInt(now.UnixNano(), 36),
+		Job:       name,
+		Trigger:   trigger,
+		Status:    StatusRunning,
+		StartedAt: now.UTC(),
+		Progress:  make(map[string]int64),
+	}
+
+	r.active[name] = run
+	snapshot := run.copy()
+	r.mu.Unlock()
+
+	// Saved before the run is executed, so the final state is always saved after this one
+	r.save(snapshot)
+
+	return run, fn, nil
+}
+
+// save records the run in the history. Failing to do so shouldn't fail the job itself, so errors are only logged.
+func (r *Runner) save(run Run) {
+	err := r.history.Save(r.ctx, run)
+	if err != nil {
+		logging.Logger.Error("Unable to save run history", zap.String("job", run.Job), zap.String("runId", run.Id), zap.Error(err))
+	}
+}
+
+func (r *Runner) execute(ctx context.Context, run *Run, fn JobFunc) (err error) {
+	logging.Logger.Info("Job run started", zap.String("job", run.Job), zap.String("runId", run.Id), zap.String("trigger", run.Trigger))
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("job panicked: %v", rec)
+		}
+		finished, hooks := r.finish(run, err)
+		for _, hook := range hooks {
+			hook(ctx, finished)
+		}
+	}()
+
+	return fn(withProgress(ctx, r, run))
+}
+
+func (r *Runner) finish(run *Run, err error) (Run, []func(ctx context.Context, run Run)) {
+	r.mu.Lock()
+	now := time.Now().UTC()
+	run.FinishedAt = &now
+	run.Status = StatusSucceeded
+	if err != nil {
+		run.Status = StatusFailed
+		run.Error = err.Error()
+	}
+	finished := run.copy()
+	hooks := append([]func(ctx context.Context, run Run){}, r.onFinish...)
+	r.mu.Unlock()
+
+	metrics.JobRuns.WithLabelValues(run.Job, string(finished.Status), run.Trigger).Inc()
+	metrics.JobRunDuration.WithLabelValues(run.Job).Observe(now.Sub(run.StartedAt).Seconds())
+
+	// Persist before the job is marked inactive, otherwise lookups in between would see a stale running entry
+	r.save(finished)
+
+	if err != nil {
+		logging.Logger.Error("Job run failed", zap.String("job", run.Job), zap.String("runId", run.Id), zap.Error(err))
+	} else {
+		logging.Logger.Info("Job run finished", zap.String("job", run.Job), zap.String("runId", run.Id), zap.Duration("duration", now.Sub(run.StartedAt)))
+	}
+
+	r.mu.Lock()
+	delete(r.active, run.Job)
+	for _, sub := range r.subscribers[run.Id] {
+		go deliverFinal(sub, run.copy())
+	}
+	delete(r.subscribers, run.Id)
+	r.mu.Unlock()
+
+	return finished, hooks
+}
+
+// deliverFinal sends the final state of a run to a subscriber and closes its channel. Unlike progress updates the final
+// state isn't dropped for slow subscribers, only for ones not reading at all within finalDeliveryTimeout.
+func deliverFinal(sub chan Run, run Run) {
+	timer := time.NewTimer(finalDeliveryTimeout)
+	defer timer.Stop()
+
+	select {
+	case sub <- run:
+	case <-timer.C:
+		logging.Logger.Warn("Subscriber didn't receive the final state of the run", zap.String("job", run.Job), zap.String("runId", run.Id))
+	}
+	close(sub)
+}
+
+func (r *Runner) setReport(run *Run, report any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run.Report = report
+}
+
+func (r *Runner) addProgress(run *Run, key string, delta int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run.Progress[key] += delta
+
+	for _, sub := range r.subscribers[run.Id] {
+		// Slow subscribers miss intermediate updates, every snapshot carries the full progress anyway
+		select {
+		case sub <- run.copy():
+		default:
+		}
+	}
+}
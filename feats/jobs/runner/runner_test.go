@@ -0,0 +1,152 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.dfds.cloud/oops/core/logging"
+	"go.uber.org/zap"
+)
+
+func TestRunner_Trigger(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	release := make(chan struct{})
+	r := New(context.Background(), NewMemoryHistory(5))
+	r.Register("blocking", func(ctx context.Context) error {
+		AddProgress(ctx, ProgressZonesFetched, 2)
+		<-release
+		return nil
+	})
+
+	run, err := r.Trigger("blocking")
+	assert.NoError(t, err)
+	assert.Equal(t, StatusRunning, run.Status)
+
+	_, err = r.Trigger("blocking")
+	assert.ErrorIs(t, err, ErrJobAlreadyRunning)
+
+	err = r.Scheduled("blocking")(context.Background())
+	assert.ErrorIs(t, err, ErrJobAlreadyRunning)
+
+	updates, unsubscribe, err := r.Subscribe(context.Background(), "blocking", run.Id)
+	assert.NoError(t, err)
+	defer unsubscribe()
+
+	close(release)
+
+	var last Run
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				done = true
+				break
+			}
+			last = update
+		case <-timeout:
+			t.Fatal("run did not finish")
+		}
+	}
+
+	assert.Equal(t, StatusSucceeded, last.Status)
+	assert.Equal(t, int64(2), last.Progress[ProgressZonesFetched])
+
+	runs, err := r.Runs(context.Background(), "blocking")
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, StatusSucceeded, runs[0].Status)
+
+	_, err = r.Trigger("unknown")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+// slowHistory blocks saves of the job until released
+type slowHistory struct {
+	*MemoryHistory
+	job     string
+	release chan struct{}
+}
+
+func (h *slowHistory) Save(ctx context.Context, run Run) error {
+	if run.Job == h.job {
+		<-h.release
+	}
+	return h.MemoryHistory.Save(ctx, run)
+}
+
+func TestRunner_SlowHistory(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	history := &slowHistory{MemoryHistory: NewMemoryHistory(5), job: "slow", release: make(chan struct{})}
+	r := New(context.Background(), history)
+	r.Register("slow", func(ctx context.Context) error { return nil })
+	r.Register("fast", func(ctx context.Context) error { return nil })
+
+	started := make(chan struct{})
+	go func() {
+		close(started)
+		r.Run(context.Background(), "slow", TriggerCli)
+	}()
+	<-started
+
+	// Other jobs and lookups aren't held up by the slow save
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run, err := r.Run(context.Background(), "fast", TriggerCli)
+		assert.NoError(t, err)
+		assert.Equal(t, StatusSucceeded, run.Status)
+		_, err = r.Runs(context.Background(), "slow")
+		assert.NoError(t, err)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runner blocked on saving the history of another job")
+	}
+
+	close(history.release)
+	assert.Eventually(t, func() bool {
+		runs, err := r.Runs(context.Background(), "slow")
+		return err == nil && len(runs) == 1 && runs[0].Status == StatusSucceeded
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRunner_SlowSubscriber(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	release := make(chan struct{})
+	r := New(context.Background(), NewMemoryHistory(5))
+	r.Register("chatty", func(ctx context.Context) error {
+		<-release
+		// More updates than the subscriber channel buffers
+		for i := 0; i < 100; i++ {
+			AddProgress(ctx, ProgressZonesFetched, 1)
+		}
+		return nil
+	})
+
+	run, err := r.Trigger("chatty")
+	assert.NoError(t, err)
+	updates, unsubscribe, err := r.Subscribe(context.Background(), "chatty", run.Id)
+	assert.NoError(t, err)
+	defer unsubscribe()
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		runs, err := r.Runs(context.Background(), "chatty")
+		return err == nil && runs[0].Status == StatusSucceeded
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The subscriber only starts reading after the run finished, the final state still arrives last
+	var last Run
+	for update := range updates {
+		last = update
+	}
+	assert.Equal(t, StatusSucceeded, last.Status)
+	assert.Equal(t, int64(100), last.Progress[ProgressZonesFetched])
+}
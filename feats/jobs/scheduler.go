@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.uber.org/zap"
+)
+
+// Scheduler runs the jobs of a runner at the interval of their schedule until its context is done. Jobs can be
+// rescheduled while running, so reloaded schedules take effect without a restart.
+type Scheduler struct {
+	ctx    context.Context
+	runner *runner.Runner
+	mu     sync.Mutex
+	jobs   map[string]*scheduledJob
+}
+
+type scheduledJob struct {
+	schedule handlers.Schedule
+	// stop ends the loop of the current schedule, it's nil while the job isn't scheduled
+	stop context.CancelFunc
+	// lastStart is when the scheduler last ran the job, kept across schedules
+	lastStart time.Time
+}
+
+func NewScheduler(ctx context.Context, r *runner.Runner) *Scheduler {
+	return &Scheduler{
+		ctx:    ctx,
+		runner: r,
+		jobs:   make(map[string]*scheduledJob),
+	}
+}
+
+// Reschedule replaces the schedule of the job, leaving it alone if the schedule is unchanged. The next run is one
+// interval after the previous scheduled one, or right away for jobs the scheduler hasn't run yet. A run in progress
+// isn't interrupted.
+func (s *Scheduler) Reschedule(name string, schedule handlers.Schedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[name]
+	if ok && job.schedule == schedule {
+		return
+	}
+	if !ok {
+		job = &scheduledJob{}
+		s.jobs[name] = job
+	}
+	if job.stop != nil {
+		job.stop()
+		job.stop = nil
+	}
+	job.schedule = schedule
+
+	if !schedule.Enable {
+		logging.Logger.Info("Job is not scheduled", zap.String("job", name))
+		return
+	}
+
+	ctx, stop := context.WithCancel(s.ctx)
+	job.stop = stop
+	next := job.lastStart.Add(schedule.Interval)
+	go s.loop(ctx, name, job, schedule.Interval, next)
+
+	logging.Logger.Info("Job scheduled", zap.String("job", name), zap.Duration("interval", schedule.Interval))
+}
+
+func (s *Scheduler) loop(ctx context.Context, name string, job *scheduledJob, interval time.Duration, next time.Time) {
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		start := time.Now()
+		s.mu.Lock()
+		job.lastStart = start
+		s.mu.Unlock()
+
+		// Runs get the context of the scheduler, so a reschedule doesn't cancel them
+		err := s.runner.Scheduled(name)(s.ctx)
+		if errors.Is(err, runner.ErrJobAlreadyRunning) {
+			logging.Logger.Info("Skipping scheduled run, the job is already running", zap.String("job", name))
+		}
+
+		timer.Reset(time.Until(start.Add(interval)))
+	}
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/feats/jobs/handlers"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.uber.org/zap"
+)
+
+func TestScheduler_Reschedule(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int32
+	r := runner.New(ctx, runner.NewMemoryHistory(50))
+	r.Register("counting", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+	scheduler := NewScheduler(ctx, r)
+
+	scheduler.Reschedule("counting", handlers.Schedule{Enable: false, Interval: 10 * time.Millisecond})
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), runs.Load(), "disabled jobs aren't run")
+
+	scheduler.Reschedule("counting", handlers.Schedule{Enable: true, Interval: time.Hour})
+	assert.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, 5*time.Millisecond, "newly scheduled jobs run right away")
+
+	// An unchanged schedule is left alone, so the job doesn't run again before the interval has passed
+	scheduler.Reschedule("counting", handlers.Schedule{Enable: true, Interval: time.Hour})
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), runs.Load())
+
+	scheduler.Reschedule("counting", handlers.Schedule{Enable: true, Interval: 10 * time.Millisecond})
+	assert.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, 5*time.Millisecond, "a shorter interval takes effect")
+
+	scheduler.Reschedule("counting", handlers.Schedule{Enable: false, Interval: 10 * time.Millisecond})
+	time.Sleep(20 * time.Millisecond)
+	stopped := runs.Load()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, stopped, runs.Load(), "disabling the job stops its schedule")
+}
@@ -0,0 +1,315 @@
+// Package lint checks backed up records for subdomain takeover risks and misconfigurations Route53 accepts but resolvers
+// or receiving mail servers don't
+package lint
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// CheckDanglingTarget flags CNAME and alias records pointing at S3 buckets or CloudFront distributions that no longer
+	// exist, anyone creating a resource of that name takes over the record
+	CheckDanglingTarget = "dangling-target"
+	CheckCnameAtApex    = "cname-at-apex"
+	// CheckCnameConflict flags names with a CNAME next to records of other types
+	CheckCnameConflict = "cname-conflict"
+	CheckExcessiveTtl  = "excessive-ttl"
+	// CheckOrphanedDelegation flags delegations to Route53 name servers without a backed up hosted zone, or to name
+	// servers that don't resolve
+	CheckOrphanedDelegation = "orphaned-delegation"
+	// CheckSpfLookups flags SPF records needing more than the 10 DNS lookups receivers evaluate
+	CheckSpfLookups = "spf-lookups"
+)
+
+// Checks are all the checks, in the order they're reported in metrics
+var Checks = []string{
+	CheckDanglingTarget,
+	CheckCnameAtApex,
+	CheckCnameConflict,
+	CheckExcessiveTtl,
+	CheckOrphanedDelegation,
+	CheckSpfLookups,
+}
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Finding is a problem with a record set, or a name in case of CNAME conflicts
+type Finding struct {
+	Account  string              `json:"account"`
+	Zone     string              `json:"zone"`
+	Name     string              `json:"name"`
+	Type     route53Types.RRType `json:"type"`
+	Check    string              `json:"check"`
+	Severity string              `json:"severity"`
+	Message  string              `json:"message"`
+}
+
+// Report is stored as lint.json in the backup tarball
+type Report struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Findings  []Finding `json:"findings"`
+}
+
+type Options struct {
+	// MaxTtl is the TTL above which records are flagged, changes to them take too long to reach resolvers
+	MaxTtl time.Duration
+	// Probe looks up targets outside the backup, checks needing it are skipped without one
+	Probe Probe
+	// Partial is set when accounts are missing from the records, delegations to zones that might be in those accounts
+	// aren't flagged then
+	Partial bool
+}
+
+// Run checks the records by account and zone and returns the findings sorted by account, zone and name
+func Run(ctx context.Context, records map[string]map[string][]route53Types.ResourceRecordSet, opts Options) []Finding {
+	l := &linter{opts: opts, zones: make(map[string]bool), spf: make(map[string][]string), exists: make(map[string]bool)}
+	for _, zones := range records {
+		for zone, recs := range zones {
+			l.zones[strings.ToLower(zone)] = true
+			for _, rec := range recs {
+				if rec.Type == route53Types.RRTypeTxt {
+					name := strings.ToLower(aws.ToString(rec.Name))
+					l.spf[name] = append(l.spf[name], spfPolicies(rec)...)
+				}
+			}
+		}
+	}
+
+	// Checks looking up names outside the backup share the group, which bounds the lookups in flight
+	var group errgroup.Group
+	group.SetLimit(probeConcurrency)
+
+	var findings []Finding
+	var targets []target
+	for acc, zones := range records {
+		for zone, recs := range zones {
+			findings = append(findings, l.checkZone(acc, zone, recs)...)
+			l.checkLookups(ctx, &group, acc, zone, recs)
+			targets = append(targets, collectTargets(acc, zone, recs)...)
+		}
+	}
+	l.probeTargets(ctx, &group, targets)
+	group.Wait()
+	findings = append(findings, l.found...)
+	findings = append(findings, l.danglingTargets(targets)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		if a.Zone != b.Zone {
+			return a.Zone < b.Zone
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		return a.Message < b.Message
+	})
+
+	return findings
+}
+
+// CountByCheck returns the number of findings per account and check. Every account of records is included, with a zero
+// count for checks without findings, so metrics of fixed problems go back to zero.
+func CountByCheck(records map[string]map[string][]route53Types.ResourceRecordSet, findings []Finding) map[string]map[string]int {
+	payload := make(map[string]map[string]int)
+	for acc := range records {
+		payload[acc] = make(map[string]int)
+		for _, check := range Checks {
+			payload[acc][check] = 0
+		}
+	}
+	for _, finding := range findings {
+		if _, ok := payload[finding.Account]; !ok {
+			payload[finding.Account] = make(map[string]int)
+		}
+		payload[finding.Account][finding.Check]++
+	}
+	return payload
+}
+
+type linter struct {
+	opts Options
+	// zones holds the lower case names of every backed up hosted zone
+	zones map[string]bool
+	// spf holds the SPF policies of the backed up zones by lower case name, for following includes
+	spf map[string][]string
+
+	mu sync.Mutex
+	// found holds the findings of checks run in the lookup group
+	found []Finding
+	// exists holds whether the probed targets exist by targetKey, targets that couldn't be probed are missing
+	exists map[string]bool
+}
+
+func newFinding(acc string, zone string, rec route53Types.ResourceRecordSet, check string, severity string, format string, args ...any) Finding {
+	return Finding{
+		Account:  acc,
+		Zone:     zone,
+		Name:     aws.ToString(rec.Name),
+		Type:     rec.Type,
+		Check:    check,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// checkZone runs the checks that only need the records of the zone
+func (l *linter) checkZone(acc string, zone string, recs []route53Types.ResourceRecordSet) []Finding {
+	var findings []Finding
+
+	typesByName := make(map[string][]route53Types.RRType)
+	for _, rec := range recs {
+		name := aws.ToString(rec.Name)
+		if !containsType(typesByName[name], rec.Type) {
+			typesByName[name] = append(typesByName[name], rec.Type)
+		}
+
+		if rec.Type == route53Types.RRTypeCname && strings.EqualFold(name, zone) {
+			findings = append(findings, newFinding(acc, zone, rec, CheckCnameAtApex, SeverityError,
+				"CNAME at the zone apex hides the SOA and NS records, use an alias record instead"))
+		}
+
+		if ttl := time.Duration(aws.ToInt64(rec.TTL)) * time.Second; l.opts.MaxTtl > 0 && ttl > l.opts.MaxTtl {
+			findings = append(findings, newFinding(acc, zone, rec, CheckExcessiveTtl, SeverityWarning,
+				"TTL of %s exceeds %s, changes take that long to reach resolvers", ttl, l.opts.MaxTtl))
+		}
+	}
+
+	for name, nameTypes := range typesByName {
+		if !containsType(nameTypes, route53Types.RRTypeCname) || len(nameTypes) == 1 {
+			continue
+		}
+		var others []string
+		for _, t := range nameTypes {
+			if t != route53Types.RRTypeCname {
+				others = append(others, string(t))
+			}
+		}
+		sort.Strings(others)
+		findings = append(findings, Finding{
+			Account:  acc,
+			Zone:     zone,
+			Name:     name,
+			Type:     route53Types.RRTypeCname,
+			Check:    CheckCnameConflict,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("CNAME next to %s records, resolvers only see one of them", strings.Join(others, ", ")),
+		})
+	}
+
+	return findings
+}
+
+// checkLookups runs the checks of delegations and SPF records in the group, as they look up names outside the backup
+func (l *linter) checkLookups(ctx context.Context, group *errgroup.Group, acc string, zone string, recs []route53Types.ResourceRecordSet) {
+	for _, rec := range recs {
+		name := aws.ToString(rec.Name)
+
+		if rec.Type == route53Types.RRTypeNs && !strings.EqualFold(name, zone) {
+			group.Go(func() error {
+				message := l.orphanedDelegation(ctx, name, rec)
+				if message != "" {
+					l.report(newFinding(acc, zone, rec, CheckOrphanedDelegation, SeverityError, "%s", message))
+				}
+				return nil
+			})
+		}
+
+		if rec.Type == route53Types.RRTypeTxt || rec.Type == route53Types.RRTypeSpf {
+			for _, policy := range spfPolicies(rec) {
+				group.Go(func() error {
+					lookups, complete := l.countSpfLookups(ctx, policy, map[string]bool{strings.ToLower(name): true})
+					if lookups <= maxSpfLookups {
+						return nil
+					}
+					at := ""
+					if !complete {
+						at = "at least "
+					}
+					l.report(newFinding(acc, zone, rec, CheckSpfLookups, SeverityError,
+						"SPF record needs %s%d DNS lookups, receivers fail it beyond %d", at, lookups, maxSpfLookups))
+					return nil
+				})
+			}
+		}
+	}
+}
+
+func (l *linter) report(finding Finding) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.found = append(l.found, finding)
+}
+
+// orphanedDelegation describes the name servers of the delegation that can't be serving the child zone, it returns an
+// empty string when all of them might be
+func (l *linter) orphanedDelegation(ctx context.Context, name string, rec route53Types.ResourceRecordSet) string {
+	var orphaned, unresolved []string
+	child := strings.ToLower(name)
+
+	for _, value := range rec.ResourceRecords {
+		server := strings.ToLower(strings.TrimSuffix(aws.ToString(value.Value), "."))
+		if isRoute53NameServer(server) {
+			if !l.zones[child] && !l.opts.Partial {
+				orphaned = append(orphaned, server)
+			}
+			continue
+		}
+
+		if l.opts.Probe == nil {
+			continue
+		}
+		exists, err := l.opts.Probe.HostExists(ctx, server)
+		if err == nil && !exists {
+			unresolved = append(unresolved, server)
+		}
+	}
+
+	var messages []string
+	if len(orphaned) == 1 {
+		messages = append(messages, fmt.Sprintf("delegated to Route53 name server %s, but no backed up hosted zone is named %s", orphaned[0], name))
+	} else if len(orphaned) > 1 {
+		messages = append(messages, fmt.Sprintf("delegated to Route53 name servers %s, but no backed up hosted zone is named %s", strings.Join(orphaned, ", "), name))
+	}
+	if len(unresolved) == 1 {
+		messages = append(messages, fmt.Sprintf("delegated to name server %s, which doesn't resolve", unresolved[0]))
+	} else if len(unresolved) > 1 {
+		messages = append(messages, fmt.Sprintf("delegated to name servers %s, which don't resolve", strings.Join(unresolved, ", ")))
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func isRoute53NameServer(server string) bool {
+	labels := strings.Split(server, ".")
+	return len(labels) >= 2 && strings.HasPrefix(labels[1], "awsdns-")
+}
+
+func containsType(types []route53Types.RRType, t route53Types.RRType) bool {
+	for _, existing := range types {
+		if existing == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package lint
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dfds.cloud/oops/core/aws/fake"
+	"go.dfds.cloud/oops/core/logging"
+	"go.uber.org/zap"
+)
+
+func checks(findings []Finding) map[string][]string {
+	payload := make(map[string][]string)
+	for _, finding := range findings {
+		payload[finding.Check] = append(payload[finding.Check], finding.Name)
+	}
+	return payload
+}
+
+func TestRun(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	records := map[string]map[string][]route53Types.ResourceRecordSet{
+		"111111111111": {
+			"example.com.": {
+				fake.Record("example.com.", route53Types.RRTypeNs, 172800, "ns-1.awsdns-01.org.", "ns-2.awsdns-02.com."),
+				fake.Record("example.com.", route53Types.RRTypeCname, 300, "other.example.net."),
+				fake.Record("example.com.", route53Types.RRTypeTxt, 300,
+					`"v=spf1 include:_spf.example.com a mx ptr exists:%{i}.example.net include:mail.example.net ~all"`),
+				fake.Record("_spf.example.com.", route53Types.RRTypeTxt, 300, `"v=spf1 a:a.example.com a:b.example.com" " mx:c.example.com -all"`),
+				fake.Record("www.example.com.", route53Types.RRTypeCname, 604800, "d111.cloudfront.net."),
+				fake.Record("www.example.com.", route53Types.RRTypeTxt, 300, `"verification"`),
+				fake.Record("old.example.com.", route53Types.RRTypeCname, 300, "old-site.s3-website-eu-west-1.amazonaws.com."),
+				fake.Record("live.example.com.", route53Types.RRTypeCname, 300, "live.site.s3.eu-west-1.amazonaws.com."),
+				fake.Alias("assets.example.com.", route53Types.RRTypeA, "s3-website-eu-west-1.amazonaws.com."),
+				fake.Alias("cdn.example.com.", route53Types.RRTypeA, "d222.cloudfront.net."),
+				fake.Alias("slow.example.com.", route53Types.RRTypeA, "d333.cloudfront.net."),
+				fake.Record("dev.example.com.", route53Types.RRTypeNs, 300, "ns-3.awsdns-03.net.", "ns-4.awsdns-04.org."),
+				fake.Record("team.example.com.", route53Types.RRTypeNs, 300, "ns-4.awsdns-04.co.uk."),
+				fake.Record("legacy.example.com.", route53Types.RRTypeNs, 300, "ns1.expired.example.", "ns1.alive.example."),
+			},
+		},
+		"222222222222": {
+			"team.example.com.": {
+				fake.Record("team.example.com.", route53Types.RRTypeTxt, 300, `"v=spf1 redirect=_spf.example.com"`),
+			},
+		},
+	}
+
+	probe := &fake.Probe{
+		Hosts: map[string]bool{
+			"d111.cloudfront.net": true,
+			"d222.cloudfront.net": false,
+			"ns1.expired.example": false,
+			"ns1.alive.example":   true,
+		},
+		Buckets: map[string]bool{"live.site": true, "old-site": false, "assets.example.com": false},
+		Txt: map[string][]string{
+			"mail.example.net": {"v=spf1 include:a.example.net include:b.example.net -all"},
+			"a.example.net":    {"v=spf1 a mx -all"},
+			"b.example.net":    {"unrelated", "v=spf1 include:mail.example.net -all"},
+		},
+	}
+
+	findings := Run(context.Background(), records, Options{MaxTtl: 48 * time.Hour, Probe: probe})
+
+	assert.Equal(t, map[string][]string{
+		CheckCnameAtApex:   {"example.com."},
+		CheckCnameConflict: {"example.com.", "www.example.com."},
+		// Buckets are named after the record for aliases to S3 website endpoints
+		CheckDanglingTarget: {"assets.example.com.", "cdn.example.com.", "old.example.com."},
+		CheckExcessiveTtl:   {"www.example.com."},
+		// The Route53 delegation of team.example.com has a zone in another account
+		CheckOrphanedDelegation: {"dev.example.com.", "legacy.example.com."},
+		// 6 terms, 3 in _spf.example.com, 3 in mail.example.net and 2 in a.example.net, the loop back to mail.example.net isn't followed
+		CheckSpfLookups: {"example.com."},
+	}, checks(findings))
+
+	for _, finding := range findings {
+		switch finding.Check {
+		case CheckSpfLookups:
+			assert.Equal(t, "SPF record needs 14 DNS lookups, receivers fail it beyond 10", finding.Message)
+		case CheckCnameConflict:
+			if finding.Name == "example.com." {
+				assert.Equal(t, "CNAME next to NS, TXT records, resolvers only see one of them", finding.Message)
+			}
+		case CheckOrphanedDelegation:
+			// Reported once per delegation, listing the servers that can't be serving it
+			switch finding.Name {
+			case "dev.example.com.":
+				assert.Equal(t, "delegated to Route53 name servers ns-3.awsdns-03.net, ns-4.awsdns-04.org, but no backed up hosted zone is named dev.example.com.", finding.Message)
+			case "legacy.example.com.":
+				assert.Equal(t, "delegated to name server ns1.expired.example, which doesn't resolve", finding.Message)
+			}
+		case CheckDanglingTarget:
+			if finding.Name == "old.example.com." {
+				assert.Equal(t, "points at S3 bucket old-site through old-site.s3-website-eu-west-1.amazonaws.com, the bucket no longer exists", finding.Message)
+			}
+		}
+	}
+
+	counts := CountByCheck(records, findings)
+	assert.Equal(t, 3, counts["111111111111"][CheckDanglingTarget])
+	assert.Equal(t, 0, counts["222222222222"][CheckSpfLookups])
+	assert.Len(t, counts["222222222222"], len(Checks))
+}
+
+func TestRunWithoutProbe(t *testing.T) {
+	records := map[string]map[string][]route53Types.ResourceRecordSet{
+		"111111111111": {
+			"example.com.": {
+				fake.Record("example.com.", route53Types.RRTypeTxt, 300, `"v=spf1 include:a.example.net include:b.example.net a mx ptr exists:x.example.net -all"`),
+				fake.Record("old.example.com.", route53Types.RRTypeCname, 300, "old-site.s3-website-eu-west-1.amazonaws.com."),
+				fake.Record("dev.example.com.", route53Types.RRTypeNs, 300, "ns-3.awsdns-03.net."),
+			},
+		},
+	}
+
+	// Targets aren't probed and includes outside the backup count once, delegations are left alone for partial backups
+	findings := Run(context.Background(), records, Options{MaxTtl: time.Hour, Partial: true})
+	assert.Empty(t, findings)
+
+	records["111111111111"]["example.com."] = append(records["111111111111"]["example.com."],
+		fake.Record("more.example.com.", route53Types.RRTypeTxt, 300,
+			`"v=spf1 include:a.example.net include:b.example.net include:c.example.net include:d.example.net include:e.example.net"`,
+			`"v=spf1 a mx ptr exists:x.example.net include:f.example.net include:g.example.net -all"`))
+	findings = Run(context.Background(), records, Options{MaxTtl: time.Hour})
+	require.Len(t, findings, 1)
+	assert.Equal(t, CheckOrphanedDelegation, findings[0].Check)
+
+	records["111111111111"]["example.com."][3].ResourceRecords = []route53Types.ResourceRecord{{Value: aws.String(
+		`"v=spf1 include:a.example.net include:b.example.net include:c.example.net include:d.example.net include:e.example.net" " a mx ptr exists:x.example.net include:f.example.net include:g.example.net -all"`,
+	)}}
+	findings = Run(context.Background(), records, Options{MaxTtl: time.Hour})
+	assert.Equal(t, map[string][]string{
+		CheckOrphanedDelegation: {"dev.example.com."},
+		CheckSpfLookups:         {"more.example.com."},
+	}, checks(findings))
+	assert.Equal(t, "SPF record needs at least 11 DNS lookups, receivers fail it beyond 10", findings[1].Message)
+}
+
+func TestNetProbe_Timeout(t *testing.T) {
+	// A name server that never answers
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	probe := NewProbe(50 * time.Millisecond).(*netProbe)
+	probe.resolver = &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "udp", conn.LocalAddr().String())
+	}}
+
+	start := time.Now()
+	_, err = probe.HostExists(context.Background(), "unanswered.example.com")
+	assert.Error(t, err)
+	_, err = probe.LookupTXT(context.Background(), "unanswered.example.com")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second, "lookups give up after the probe timeout")
+}
@@ -0,0 +1,95 @@
+package lint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Probe looks up resources outside the backup. Lookups answer false only when the resource definitely doesn't exist,
+// failures to find out are returned as errors.
+type Probe interface {
+	HostExists(ctx context.Context, host string) (bool, error)
+	// BucketExists reports whether an S3 bucket of the name exists in any AWS account
+	BucketExists(ctx context.Context, bucket string) (bool, error)
+	// LookupTXT returns the TXT values of a name, the character strings of a value joined
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+type netProbe struct {
+	resolver *net.Resolver
+	// timeout limits each DNS lookup, HTTP requests are limited by the client
+	timeout time.Duration
+	client  *http.Client
+	// s3Url is the path style S3 endpoint buckets are looked up at
+	s3Url string
+}
+
+// NewProbe returns a probe using the system resolver and the public S3 endpoint, each lookup limited to timeout
+func NewProbe(timeout time.Duration) Probe {
+	return &netProbe{
+		resolver: net.DefaultResolver,
+		timeout:  timeout,
+		client: &http.Client{
+			Timeout: timeout,
+			// A redirect means the bucket exists in another region
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		s3Url: "https://s3.amazonaws.com",
+	}
+}
+
+func (p *netProbe) HostExists(ctx context.Context, host string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	_, err := p.resolver.LookupHost(ctx, host)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// BucketExists asks S3 for the bucket without credentials, it answers 404 only for buckets that don't exist and 403 or
+// a redirect for buckets of other accounts or regions
+func (p *netProbe) BucketExists(ctx context.Context, bucket string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, p.s3Url+"/"+url.PathEscape(bucket), nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode >= 500:
+		return false, fmt.Errorf("looking up bucket %s: %s", bucket, resp.Status)
+	default:
+		return true, nil
+	}
+}
+
+func (p *netProbe) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	values, err := p.resolver.LookupTXT(ctx, name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, nil
+	}
+	return values, err
+}
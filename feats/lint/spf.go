@@ -0,0 +1,139 @@
+package lint
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/logging"
+	"go.uber.org/zap"
+)
+
+// maxSpfLookups is the limit of RFC 7208 on mechanisms and modifiers causing DNS lookups
+const maxSpfLookups = 10
+
+// spfPolicies returns the SPF policies among the values of a TXT or SPF record, with the character strings of a value joined
+func spfPolicies(rec route53Types.ResourceRecordSet) []string {
+	var payload []string
+	for _, value := range rec.ResourceRecords {
+		texts, err := oopsAws.SplitCharacterStrings(aws.ToString(value.Value))
+		if err != nil {
+			continue
+		}
+		if policy := strings.Join(texts, ""); isSpfPolicy(policy) {
+			payload = append(payload, policy)
+		}
+	}
+	return payload
+}
+
+func isSpfPolicy(text string) bool {
+	lower := strings.ToLower(text)
+	return lower == "v=spf1" || strings.HasPrefix(lower, "v=spf1 ")
+}
+
+// countSpfLookups counts the lookups of a policy and the policies it includes or redirects to. Includes are looked up in
+// the backed up zones, and through the probe for other domains. complete is false if some includes couldn't be looked up,
+// making the count a lower bound. seen holds the domains on the way, so include loops end.
+func (l *linter) countSpfLookups(ctx context.Context, policy string, seen map[string]bool) (int, bool) {
+	count := 0
+	complete := true
+	hasAll := false
+	redirect := ""
+
+	follow := func(domain string) {
+		nested, ok := l.lookupSpf(ctx, domain, seen)
+		if !ok {
+			complete = false
+			return
+		}
+		seen[domain] = true
+		nestedCount, nestedComplete := l.countSpfLookups(ctx, nested, seen)
+		delete(seen, domain)
+		count += nestedCount
+		complete = complete && nestedComplete
+	}
+
+	for _, term := range strings.Fields(policy)[1:] {
+		term = strings.TrimLeft(term, "+-~?")
+		name, arg := splitSpfTerm(term)
+
+		switch name {
+		case "include":
+			count++
+			follow(arg)
+		case "a", "mx", "ptr", "exists":
+			count++
+		case "all":
+			hasAll = true
+		case "redirect":
+			redirect = arg
+		}
+	}
+
+	// The redirect modifier is ignored when the policy has an all mechanism
+	if redirect != "" && !hasAll {
+		count++
+		follow(redirect)
+	}
+
+	return count, complete
+}
+
+// lookupSpf returns the SPF policy of a domain. ok is false if the domain couldn't be looked up, or its name holds macros.
+// Domains without a policy return an empty one.
+func (l *linter) lookupSpf(ctx context.Context, domain string, seen map[string]bool) (string, bool) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if domain == "" || strings.Contains(domain, "%") {
+		return "", false
+	}
+	if seen[domain] {
+		return "v=spf1", true
+	}
+
+	if l.isBackedUp(domain) {
+		if policies := l.spf[domain+"."]; len(policies) > 0 {
+			return policies[0], true
+		}
+		return "v=spf1", true
+	}
+
+	if l.opts.Probe == nil {
+		return "", false
+	}
+	values, err := l.opts.Probe.LookupTXT(ctx, domain)
+	if err != nil {
+		logging.Logger.Debug("Unable to look up SPF include", zap.String("domain", domain), zap.Error(err))
+		return "", false
+	}
+	for _, value := range values {
+		if isSpfPolicy(value) {
+			return value, true
+		}
+	}
+	return "v=spf1", true
+}
+
+// isBackedUp reports whether the domain belongs to one of the backed up zones
+func (l *linter) isBackedUp(domain string) bool {
+	labels := strings.Split(domain, ".")
+	for i := range labels {
+		if l.zones[strings.Join(labels[i:], ".")+"."] {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSpfTerm returns the lower case name of a mechanism or modifier and its domain argument, if any
+func splitSpfTerm(term string) (string, string) {
+	if name, arg, ok := strings.Cut(term, "="); ok {
+		return strings.ToLower(name), arg
+	}
+	name, arg, _ := strings.Cut(term, ":")
+	name, _, _ = strings.Cut(name, "/")
+	arg, _, _ = strings.Cut(arg, "/")
+	return strings.ToLower(name), arg
+}
@@ -0,0 +1,109 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/logging"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// probeConcurrency limits the lookups in flight of the delegation, SPF and target checks
+const probeConcurrency = 8
+
+// target is a resource a record points at, which has to exist for the record to be safe
+type target struct {
+	account string
+	zone    string
+	rec     route53Types.ResourceRecordSet
+	oopsAws.Target
+}
+
+// collectTargets returns the S3 buckets and CloudFront distributions the CNAME and alias records of a zone point at.
+// Names of other resources, like load balancers, can't be claimed by others once deleted.
+func collectTargets(acc string, zone string, recs []route53Types.ResourceRecordSet) []target {
+	var payload []target
+
+	for _, rec := range recs {
+		for _, t := range oopsAws.RecordTargets(rec) {
+			if t.Kind != oopsAws.TargetS3Bucket && t.Kind != oopsAws.TargetCloudFront {
+				continue
+			}
+			payload = append(payload, target{account: acc, zone: zone, rec: rec, Target: t})
+		}
+	}
+
+	return payload
+}
+
+// probeTargets probes every distinct target once in the group, the results are in exists once the group is done
+func (l *linter) probeTargets(ctx context.Context, group *errgroup.Group, targets []target) {
+	if l.opts.Probe == nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		key := targetKey(t)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		group.Go(func() error {
+			var ok bool
+			var err error
+			switch t.Kind {
+			case oopsAws.TargetS3Bucket:
+				ok, err = l.opts.Probe.BucketExists(ctx, t.Name)
+			default:
+				ok, err = l.opts.Probe.HostExists(ctx, t.Name)
+			}
+			if err != nil {
+				logging.Logger.Debug("Unable to probe record target", zap.String("target", t.Name), zap.Error(err))
+				return nil
+			}
+
+			l.mu.Lock()
+			l.exists[key] = ok
+			l.mu.Unlock()
+			return nil
+		})
+	}
+}
+
+// danglingTargets returns the findings of targets the probe found missing. Targets that can't be probed, e.g. due to
+// timeouts, aren't reported.
+func (l *linter) danglingTargets(targets []target) []Finding {
+	var findings []Finding
+	for _, t := range targets {
+		ok, probed := l.exists[targetKey(t)]
+		if !probed || ok {
+			continue
+		}
+
+		message := fmt.Sprintf("points at CloudFront distribution %s, which no longer exists", t.Name)
+		if t.Kind == oopsAws.TargetS3Bucket {
+			message = fmt.Sprintf("points at S3 bucket %s through %s, the bucket no longer exists", t.Name, t.Host)
+		}
+		findings = append(findings, Finding{
+			Account:  t.account,
+			Zone:     t.zone,
+			Name:     aws.ToString(t.rec.Name),
+			Type:     t.rec.Type,
+			Check:    CheckDanglingTarget,
+			Severity: SeverityError,
+			Message:  message,
+		})
+	}
+
+	return findings
+}
+
+func targetKey(t target) string {
+	return string(t.Kind) + "|" + t.Name
+}
@@ -0,0 +1,221 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.uber.org/zap"
+)
+
+type EventType string
+
+const (
+	EventJobFailed         EventType = "job_failed"
+	EventJobPartialFailure EventType = "job_partial_failure"
+	EventBackupStale       EventType = "backup_stale"
+	EventDnsDrift          EventType = "dns_drift"
+	EventTakeoverRisk      EventType = "takeover_risk"
+)
+
+type Event struct {
+	Type    EventType `json:"type"`
+	Job     string    `json:"job"`
+	RunId   string    `json:"runId,omitempty"`
+	Time    time.Time `json:"time"`
+	Summary string    `json:"summary"`
+	// Details lists the individual items the event is about, e.g. failed accounts or changed records
+	Details []string       `json:"details,omitempty"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// Message is an Event rendered through the subject and body templates
+type Message struct {
+	Subject string
+	Body    string
+	Event   Event
+}
+
+type Sink interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type SinkFactory func(sink config.NotificationSink) (Sink, error)
+
+var providers = map[string]SinkFactory{}
+var schemas = map[string]func() config.Schema{}
+
+func Register(provider string, factory SinkFactory) {
+	providers[provider] = factory
+}
+
+// RegisterSchema sets the function generating the JSON Schema of the provider's spec, usually config.SchemaOf
+func RegisterSchema(provider string, schema func() config.Schema) {
+	schemas[provider] = schema
+}
+
+// Schemas returns the spec schema of every provider that registered one
+func Schemas() map[string]config.Schema {
+	payload := make(map[string]config.Schema)
+	for provider, schema := range schemas {
+		payload[provider] = schema()
+	}
+	return payload
+}
+
+type namedSink struct {
+	name   string
+	events map[EventType]bool
+	sink   Sink
+}
+
+type Notifier struct {
+	sinks     []namedSink
+	subjects  map[EventType]*template.Template
+	bodies    map[EventType]*template.Template
+	maxDetail int
+}
+
+// defaultNotifier is used by Notify. It has no sinks until Init is called, and is replaced whenever the config is reloaded.
+var defaultNotifier atomic.Pointer[Notifier]
+
+func init() {
+	defaultNotifier.Store(&Notifier{})
+}
+
+// Init replaces the notifier used by Notify. The previous one is kept if conf is invalid.
+func Init(conf config.Notifications) error {
+	notifier, err := New(conf)
+	if err != nil {
+		return err
+	}
+	defaultNotifier.Store(notifier)
+	return nil
+}
+
+func Notify(ctx context.Context, event Event) {
+	defaultNotifier.Load().Notify(ctx, event)
+}
+
+func New(conf config.Notifications) (*Notifier, error) {
+	notifier := &Notifier{
+		subjects:  make(map[EventType]*template.Template),
+		bodies:    make(map[EventType]*template.Template),
+		maxDetail: 50,
+	}
+
+	for _, eventType := range []EventType{EventJobFailed, EventJobPartialFailure, EventBackupStale, EventDnsDrift, EventTakeoverRisk} {
+		subject := defaultSubject
+		if override, ok := conf.Templates[string(eventType)+".subject"]; ok {
+			subject = override
+		}
+		body := defaultBodies[eventType]
+		if override, ok := conf.Templates[string(eventType)+".body"]; ok {
+			body = override
+		}
+
+		var err error
+		notifier.subjects[eventType], err = template.New(string(eventType) + ".subject").Parse(subject)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subject template for %s: %w", eventType, err)
+		}
+		notifier.bodies[eventType], err = template.New(string(eventType) + ".body").Parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body template for %s: %w", eventType, err)
+		}
+	}
+
+	for _, sinkConf := range conf.Sinks {
+		if !sinkConf.Enabled {
+			continue
+		}
+
+		factory, ok := providers[sinkConf.Provider]
+		if !ok {
+			return nil, fmt.Errorf("unknown notification provider %s for sink %s", sinkConf.Provider, sinkConf.Name)
+		}
+
+		sink, err := factory(sinkConf)
+		if err != nil {
+			return nil, fmt.Errorf("invalid notification sink %s: %w", sinkConf.Name, err)
+		}
+
+		events := make(map[EventType]bool)
+		for _, eventType := range sinkConf.Events {
+			events[EventType(eventType)] = true
+		}
+
+		notifier.sinks = append(notifier.sinks, namedSink{name: sinkConf.Name, events: events, sink: sink})
+	}
+
+	return notifier, nil
+}
+
+// Notify renders the event and sends it to every sink subscribed to its type. Delivery failures are logged, not returned,
+// as failing to notify shouldn't fail whatever triggered the notification.
+func (n *Notifier) Notify(ctx context.Context, event Event) {
+	if len(n.sinks) == 0 {
+		return
+	}
+
+	if runner.IsDryRun(ctx) {
+		logging.Logger.Info("Dry run, not sending notification", zap.String("event", string(event.Type)), zap.String("summary", event.Summary))
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	msg, err := n.render(event)
+	if err != nil {
+		logging.Logger.Error("Unable to render notification", zap.String("event", string(event.Type)), zap.Error(err))
+		return
+	}
+
+	for _, sink := range n.sinks {
+		if len(sink.events) > 0 && !sink.events[event.Type] {
+			continue
+		}
+
+		err := sink.sink.Send(ctx, msg)
+		if err != nil {
+			logging.Logger.Error("Unable to send notification", zap.String("sink", sink.name), zap.String("event", string(event.Type)), zap.Error(err))
+			continue
+		}
+		logging.Logger.Debug("Notification sent", zap.String("sink", sink.name), zap.String("event", string(event.Type)))
+	}
+}
+
+func (n *Notifier) render(event Event) (Message, error) {
+	subjectTmpl, ok := n.subjects[event.Type]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown event type %s", event.Type)
+	}
+
+	data := templateData{Event: event, Details: event.Details}
+	if len(event.Details) > n.maxDetail {
+		data.Details = event.Details[:n.maxDetail]
+		data.Truncated = len(event.Details) - n.maxDetail
+	}
+
+	var subject bytes.Buffer
+	err := subjectTmpl.Execute(&subject, data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	var body bytes.Buffer
+	err = n.bodies[event.Type].Execute(&body, data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{Subject: subject.String(), Body: body.String(), Event: event}, nil
+}
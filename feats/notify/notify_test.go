@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/core/logging"
+	"go.dfds.cloud/oops/feats/jobs/runner"
+	"go.uber.org/zap"
+)
+
+// smtpStandIn accepts a single mail and hands the DATA section to the returned channel
+func smtpStandIn(t *testing.T) (string, int, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	mails := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		defer listener.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP stand-in")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil || dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				mails <- data.String()
+				reply("250 queued")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, mails
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	received := make(map[string][]map[string]any)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		assert.NoError(t, err)
+		received[r.URL.Path] = append(received[r.URL.Path], payload)
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+	}))
+	defer srv.Close()
+
+	smtpHost, smtpPort, mails := smtpStandIn(t)
+
+	notifier, err := New(config.Notifications{
+		Templates: map[string]string{
+			"backup_stale.subject": "STALE {{ .Job }}",
+		},
+		Sinks: []config.NotificationSink{
+			{Name: "slack", Provider: "slack", Enabled: true, Spec: map[string]interface{}{"url": srv.URL + "/slack", "headers": map[string]string{"X-Token": "secret"}}},
+			{Name: "teams", Provider: "teams", Enabled: true, Events: []string{"job_failed"}, Spec: map[string]interface{}{"url": srv.URL + "/teams", "headers": map[string]string{"X-Token": "secret"}}},
+			{Name: "hook", Provider: "webhook", Enabled: true, Spec: map[string]interface{}{"url": srv.URL + "/hook", "headers": map[string]string{"X-Token": "secret"}}},
+			{Name: "disabled", Provider: "webhook", Enabled: false, Spec: map[string]interface{}{"url": srv.URL + "/disabled"}},
+			{Name: "mail", Provider: "smtp", Enabled: true, Events: []string{"backup_stale"}, Spec: map[string]interface{}{
+				"host": smtpHost, "port": smtpPort, "from": "oops@dfds.cloud", "to": []string{"oncall@dfds.cloud"},
+			}},
+		},
+	})
+	assert.NoError(t, err)
+
+	notifier.Notify(context.Background(), Event{
+		Type:    EventBackupStale,
+		Job:     "route53Backup",
+		Summary: "No backup newer than 26h0m0s in 1 location(s)",
+		Details: []string{"s3-primary: no backups found"},
+	})
+
+	assert.Len(t, received["/slack"], 1)
+	assert.Contains(t, received["/slack"][0]["text"], "*STALE route53Backup*")
+	assert.Contains(t, received["/slack"][0]["text"], "- s3-primary: no backups found")
+	assert.Len(t, received["/teams"], 0)
+	assert.Len(t, received["/disabled"], 0)
+	assert.Len(t, received["/hook"], 1)
+	assert.Equal(t, "backup_stale", received["/hook"][0]["type"])
+	assert.Equal(t, "STALE route53Backup", received["/hook"][0]["subject"])
+
+	mail := <-mails
+	assert.Contains(t, mail, "Subject: STALE route53Backup\r\n")
+	assert.Contains(t, mail, "To: oncall@dfds.cloud\r\n")
+	assert.Contains(t, mail, "- s3-primary: no backups found")
+
+	notifier.Notify(context.Background(), Event{Type: EventJobFailed, Job: "route53Backup", RunId: "abc", Summary: "boom"})
+	assert.Len(t, received["/teams"], 1)
+	assert.Equal(t, "[oops] boom", received["/teams"][0]["title"])
+	assert.Contains(t, received["/teams"][0]["text"], "(run abc)")
+}
+
+type partialReport struct{ failed []string }
+
+func (p partialReport) FailedItems() []string { return p.failed }
+
+func TestRunFinished(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	var events []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		events = append(events, payload["type"].(string))
+	}))
+	defer srv.Close()
+
+	err := Init(config.Notifications{Sinks: []config.NotificationSink{
+		{Name: "hook", Provider: "webhook", Enabled: true, Spec: map[string]interface{}{"url": srv.URL}},
+	}})
+	assert.NoError(t, err)
+	defer defaultNotifier.Store(&Notifier{})
+
+	RunFinished(context.Background(), runner.Run{Job: "a", Status: runner.StatusSucceeded})
+	RunFinished(context.Background(), runner.Run{Job: "a", Status: runner.StatusSucceeded, Report: partialReport{failed: []string{"account 1"}}})
+	RunFinished(context.Background(), runner.Run{Job: "a", Status: runner.StatusFailed, Error: "boom"})
+
+	assert.Equal(t, []string{string(EventJobPartialFailure), string(EventJobFailed)}, events)
+}
+
+func TestSmtpSink_Unresponsive(t *testing.T) {
+	// Accepts connections but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	sink := &smtpSink{conf: SmtpConfig{Host: addr.IP.String(), Port: addr.Port, From: "oops@dfds.cloud", To: []string{"oncall@dfds.cloud"}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err = sink.Send(ctx, Message{Subject: "stale", Body: "body"})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(started), 5*time.Second)
+}
+
+func TestNew_InvalidSink(t *testing.T) {
+	_, err := New(config.Notifications{Sinks: []config.NotificationSink{{Name: "x", Provider: "pigeon", Enabled: true}}})
+	assert.Error(t, err)
+
+	_, err = New(config.Notifications{Sinks: []config.NotificationSink{{Name: "x", Provider: "slack", Enabled: true}}})
+	assert.Error(t, err)
+
+	_, err = New(config.Notifications{Templates: map[string]string{"dns_drift.body": "{{ .Nope"}})
+	assert.Error(t, err)
+}
+
+func TestEncodeSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{subject: "STALE route53Backup", want: "STALE route53Backup"},
+		{subject: "boom\r\nBcc: attacker@example.com", want: "boom Bcc: attacker@example.com"},
+		{subject: "boom\rBcc: x", want: "boom Bcc: x"},
+		{subject: "zone æøå.dk", want: "=?utf-8?q?zone_=C3=A6=C3=B8=C3=A5.dk?="},
+	}
+	for _, test := range tests {
+		t.Run(test.subject, func(t *testing.T) {
+			assert.Equal(t, test.want, encodeSubject(test.subject))
+		})
+	}
+}
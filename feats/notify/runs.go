@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"go.dfds.cloud/oops/feats/jobs/runner"
+)
+
+// RunFinished notifies about failed runs and runs whose report flags partial failures. Meant for runner.Runner.OnFinish.
+func RunFinished(ctx context.Context, run runner.Run) {
+	switch run.Status {
+	case runner.StatusFailed:
+		Notify(ctx, Event{
+			Type:    EventJobFailed,
+			Job:     run.Job,
+			RunId:   run.Id,
+			Summary: run.Error,
+		})
+	case runner.StatusSucceeded:
+		report, ok := run.Report.(runner.PartialFailure)
+		if !ok || len(report.FailedItems()) == 0 {
+			return
+		}
+		Notify(ctx, Event{
+			Type:    EventJobPartialFailure,
+			Job:     run.Job,
+			RunId:   run.Id,
+			Summary: fmt.Sprintf("%d item(s) failed", len(report.FailedItems())),
+			Details: report.FailedItems(),
+		})
+	}
+}
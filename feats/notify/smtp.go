@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.dfds.cloud/oops/core/config"
+)
+
+type SmtpConfig struct {
+	Host     string        `json:"host" required:"true"`
+	Port     int           `json:"port" description:"defaults to 587"`
+	Username string        `json:"username"`
+	Password config.Secret `json:"password"`
+	From     string        `json:"from" required:"true"`
+	To       []string      `json:"to" required:"true"`
+}
+
+// smtpTimeout bounds a whole delivery, like the webhook sinks' request timeout
+const smtpTimeout = 15 * time.Second
+
+type smtpSink struct {
+	conf SmtpConfig
+}
+
+func init() {
+	Register("smtp", func(sink config.NotificationSink) (Sink, error) {
+		spec, err := config.SinkSpecToType[SmtpConfig](sink)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Host == "" || spec.From == "" || len(spec.To) == 0 {
+			return nil, errors.New("host, from and to must be set")
+		}
+		if spec.Port == 0 {
+			spec.Port = 587
+		}
+
+		return &smtpSink{conf: *spec}, nil
+	})
+	RegisterSchema("smtp", config.SchemaOf[SmtpConfig])
+}
+
+// Send delivers the message like smtp.SendMail, upgrading to TLS whenever the server offers STARTTLS, but gives up
+// once ctx is done or smtpTimeout has passed
+func (s *smtpSink) Send(ctx context.Context, msg Message) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(s.conf.Host, strconv.Itoa(s.conf.Port))
+	dialer := net.Dialer{Timeout: smtpTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return err
+	}
+	// The deadline covers the timeout, closing the connection covers ctx being cancelled early
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, s.conf.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: s.conf.Host})
+		if err != nil {
+			return err
+		}
+	}
+	if s.conf.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", s.conf.Username, s.conf.Password.Value(), s.conf.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(s.conf.From)
+	if err != nil {
+		return err
+	}
+	for _, to := range s.conf.To {
+		err = client.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("From: %s\r\n", s.conf.From))
+	sb.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(s.conf.To, ", ")))
+	sb.WriteString(fmt.Sprintf("Subject: %s\r\n", encodeSubject(msg.Subject)))
+	sb.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	sb.WriteString("\r\n")
+
+	_, err = writer.Write([]byte(sb.String()))
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// encodeSubject folds the subject onto a single line, so nothing in it can end the header, and encodes non-ASCII text
+func encodeSubject(subject string) string {
+	subject = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(subject)
+	return mime.QEncoding.Encode("utf-8", subject)
+}
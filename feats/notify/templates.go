@@ -0,0 +1,26 @@
+package notify
+
+type templateData struct {
+	Event
+	Details   []string
+	Truncated int
+}
+
+const defaultSubject = `[oops] {{ .Summary }}`
+
+const detailsTemplate = `{{ range .Details }}
+- {{ . }}{{ end }}{{ if .Truncated }}
+... and {{ .Truncated }} more{{ end }}`
+
+var defaultBodies = map[EventType]string{
+	EventJobFailed: `Job {{ .Job }} failed{{ if .RunId }} (run {{ .RunId }}){{ end }} at {{ .Time.Format "2006-01-02 15:04:05 MST" }}.
+{{ .Summary }}` + detailsTemplate,
+	EventJobPartialFailure: `Job {{ .Job }}{{ if .RunId }} (run {{ .RunId }}){{ end }} finished at {{ .Time.Format "2006-01-02 15:04:05 MST" }}, but not everything could be processed.
+{{ .Summary }}` + detailsTemplate,
+	EventBackupStale: `Backups of job {{ .Job }} are stale as of {{ .Time.Format "2006-01-02 15:04:05 MST" }}.
+{{ .Summary }}` + detailsTemplate,
+	EventDnsDrift: `Job {{ .Job }} detected DNS changes since the previous backup.
+{{ .Summary }}` + detailsTemplate,
+	EventTakeoverRisk: `Job {{ .Job }} found records anyone creating the AWS resources they point at could take over.
+{{ .Summary }}` + detailsTemplate,
+}
@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.dfds.cloud/oops/core/config"
+)
+
+type WebhookConfig struct {
+	// Url is a secret, as Slack and Teams webhook URLs carry their own credentials
+	Url     config.Secret     `json:"url" required:"true"`
+	Headers map[string]string `json:"headers"`
+}
+
+// webhookSink posts a JSON payload, built by the payload function of the provider, to an incoming webhook
+type webhookSink struct {
+	httpClient *http.Client
+	conf       WebhookConfig
+	payload    func(msg Message) any
+}
+
+func init() {
+	Register("slack", newWebhookSink(func(msg Message) any {
+		return map[string]string{"text": fmt.Sprintf("*%s*\n%s", msg.Subject, msg.Body)}
+	}))
+	Register("teams", newWebhookSink(func(msg Message) any {
+		return map[string]string{
+			"@type":    "MessageCard",
+			"@context": "http://schema.org/extensions",
+			"summary":  msg.Subject,
+			"title":    msg.Subject,
+			"text":     msg.Body,
+		}
+	}))
+	Register("webhook", newWebhookSink(func(msg Message) any {
+		return struct {
+			Event
+			Subject string `json:"subject"`
+			Body    string `json:"body"`
+		}{Event: msg.Event, Subject: msg.Subject, Body: msg.Body}
+	}))
+
+	for _, provider := range []string{"slack", "teams", "webhook"} {
+		RegisterSchema(provider, config.SchemaOf[WebhookConfig])
+	}
+}
+
+func newWebhookSink(payload func(msg Message) any) SinkFactory {
+	return func(sink config.NotificationSink) (Sink, error) {
+		spec, err := config.SinkSpecToType[WebhookConfig](sink)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Url == "" {
+			return nil, errors.New("url must be set")
+		}
+
+		return &webhookSink{
+			httpClient: &http.Client{Timeout: 15 * time.Second},
+			conf:       *spec,
+			payload:    payload,
+		}, nil
+	}
+}
+
+func (w *webhookSink) Send(ctx context.Context, msg Message) error {
+	serialised, err := json.Marshal(w.payload(msg))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.conf.Url.Value(), bytes.NewReader(serialised))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "oops - github.com/dfds/oops")
+	for k, v := range w.conf.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("response returned unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+)
+
+// ImportPlan is what importing records, e.g. from the zone file of a registrar, into a hosted zone changes
+type ImportPlan struct {
+	Zone string
+	// HostedZoneId is empty if the hosted zone doesn't exist yet and will be created
+	HostedZoneId string
+	Changes      []route53Types.Change
+	// Conflicts are the changes that replace existing records with different values
+	Conflicts []route53Types.Change
+	// Warnings describe the records left out of the import, e.g. of types Route53 doesn't support
+	Warnings []string
+}
+
+// ErrImportConflicts is returned by ApplyImport if the plan would overwrite existing records
+var ErrImportConflicts = errors.New("records to import conflict with existing records")
+
+// PlanImport compares the records with the hosted zone of the same name. Existing records missing from the import are
+// left alone, and the apex SOA and NS records are never imported, as they belong to the hosted zone. Records of types
+// Route53 doesn't support are left out with a warning.
+func PlanImport(ctx context.Context, client oopsAws.Route53Api, zone string, records []route53Types.ResourceRecordSet) (ImportPlan, error) {
+	if !strings.HasSuffix(zone, ".") {
+		zone += "."
+	}
+	zone = route53Name(zone)
+	plan := ImportPlan{Zone: zone}
+
+	desired := make([]route53Types.ResourceRecordSet, 0, len(records))
+	for _, rec := range records {
+		name := route53Name(aws.ToString(rec.Name))
+		if name != zone && !strings.HasSuffix(name, "."+zone) {
+			return plan, fmt.Errorf("record %s %s is outside of zone %s", name, rec.Type, zone)
+		}
+		if !slices.Contains(rec.Type.Values(), rec.Type) {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("skipped %s record of %s, Route53 doesn't support the type", rec.Type, name))
+			continue
+		}
+		rec.Name = aws.String(name)
+		desired = append(desired, rec)
+	}
+
+	var current []route53Types.ResourceRecordSet
+	hostedZoneId, err := FindHostedZoneId(ctx, client, zone)
+	switch {
+	case err == nil:
+		plan.HostedZoneId = hostedZoneId
+		current, err = FetchZoneRecords(ctx, client, hostedZoneId)
+		if err != nil {
+			return plan, err
+		}
+	case errors.Is(err, errHostedZoneNotFound):
+	default:
+		return plan, err
+	}
+
+	existing := indexRecords(zone, current)
+	plan.Changes = PlanZone(zone, current, desired, false)
+	for _, change := range plan.Changes {
+		if _, ok := existing[recordKey(*change.ResourceRecordSet)]; ok {
+			plan.Conflicts = append(plan.Conflicts, change)
+		}
+	}
+
+	return plan, nil
+}
+
+// ApplyImport creates the hosted zone if needed and upserts the records. Conflicting records are only overwritten with
+// overwrite set. It returns the id of the hosted zone and, if it was created, the name servers to delegate to.
+func ApplyImport(ctx context.Context, client oopsAws.Route53Api, plan ImportPlan, overwrite bool) (string, []string, error) {
+	if len(plan.Conflicts) > 0 && !overwrite {
+		return plan.HostedZoneId, nil, fmt.Errorf("%w: %d record sets", ErrImportConflicts, len(plan.Conflicts))
+	}
+
+	hostedZoneId := plan.HostedZoneId
+	var nameServers []string
+	if hostedZoneId == "" {
+		resp, err := client.CreateHostedZone(ctx, &route53.CreateHostedZoneInput{
+			Name:            aws.String(plan.Zone),
+			CallerReference: aws.String(fmt.Sprintf("oops-import-%s-%d", strings.TrimSuffix(plan.Zone, "."), time.Now().UnixNano())),
+			HostedZoneConfig: &route53Types.HostedZoneConfig{
+				Comment: aws.String("imported by oops"),
+			},
+		})
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to create hosted zone %s: %w", plan.Zone, err)
+		}
+		hostedZoneId = aws.ToString(resp.HostedZone.Id)
+		if resp.DelegationSet != nil {
+			nameServers = resp.DelegationSet.NameServers
+		}
+	}
+
+	return hostedZoneId, nameServers, Apply(ctx, client, hostedZoneId, plan.Changes)
+}
+
+// route53Name writes a name the way Route53 returns it, lower case and with the wildcard escaped
+func route53Name(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "*", `\052`)
+}
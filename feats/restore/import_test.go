@@ -0,0 +1,109 @@
+package restore
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/aws/fake"
+)
+
+const importZoneFile = `$ORIGIN example.com.
+$TTL 300
+@	3600	IN	SOA	ns1.registrar.net. hostmaster.example.com. 1 7200 900 1209600 300
+@	3600	IN	NS	ns1.registrar.net.
+WWW	IN	A	192.0.2.1
+api	IN	CNAME	www
+*	IN	TXT	"wildcard"
+`
+
+func TestImportCreatesZone(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewRoute53()
+
+	records, _, err := oopsAws.ParseZoneFile(strings.NewReader(importZoneFile), "example.com.")
+	require.NoError(t, err)
+
+	plan, err := PlanImport(ctx, client, "Example.com", records)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com.", plan.Zone)
+	assert.Empty(t, plan.HostedZoneId)
+	assert.Empty(t, plan.Conflicts)
+	var described []string
+	for _, change := range plan.Changes {
+		described = append(described, DescribeChange(change))
+	}
+	assert.ElementsMatch(t, []string{
+		`UPSERT \052.example.com. TXT 300 "wildcard"`,
+		"UPSERT api.example.com. CNAME 300 www.example.com.",
+		"UPSERT www.example.com. A 300 192.0.2.1",
+	}, described)
+
+	hostedZoneId, nameServers, err := ApplyImport(ctx, client, plan, false)
+	require.NoError(t, err)
+	assert.NotEmpty(t, nameServers)
+
+	current, err := FetchZoneRecords(ctx, client, hostedZoneId)
+	require.NoError(t, err)
+	// The apex SOA and NS records are the ones of the created zone, not of the zone file
+	assert.Len(t, current, 5)
+
+	plan, err = PlanImport(ctx, client, "example.com", records)
+	require.NoError(t, err)
+	assert.Equal(t, hostedZoneId, plan.HostedZoneId)
+	assert.Empty(t, plan.Changes)
+}
+
+func TestImportConflicts(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewRoute53()
+	hostedZoneId := client.AddZone("example.com.",
+		fake.Record("www.example.com.", route53Types.RRTypeA, 300, "192.0.2.9"),
+		fake.Record("old.example.com.", route53Types.RRTypeA, 300, "192.0.2.8"),
+	)
+
+	records, _, err := oopsAws.ParseZoneFile(strings.NewReader(importZoneFile), "example.com.")
+	require.NoError(t, err)
+
+	plan, err := PlanImport(ctx, client, "example.com.", records)
+	require.NoError(t, err)
+	assert.Equal(t, hostedZoneId, plan.HostedZoneId)
+	require.Len(t, plan.Conflicts, 1)
+	assert.Equal(t, "UPSERT www.example.com. A 300 192.0.2.1", DescribeChange(plan.Conflicts[0]))
+
+	_, _, err = ApplyImport(ctx, client, plan, false)
+	assert.ErrorIs(t, err, ErrImportConflicts)
+	assert.Len(t, client.Records(hostedZoneId), 4)
+
+	_, _, err = ApplyImport(ctx, client, plan, true)
+	require.NoError(t, err)
+	// Records missing from the zone file are kept
+	assert.Len(t, client.Records(hostedZoneId), 6)
+}
+
+func TestImportRejectsForeignRecords(t *testing.T) {
+	_, err := PlanImport(context.Background(), fake.NewRoute53(), "example.com.", []route53Types.ResourceRecordSet{
+		fake.Record("www.example.org.", route53Types.RRTypeA, 300, "192.0.2.1"),
+	})
+	assert.ErrorContains(t, err, "outside of zone example.com.")
+}
+
+func TestImportSkipsUnsupportedTypes(t *testing.T) {
+	ctx := context.Background()
+	content := importZoneFile + "host\tIN\tHINFO\t\"x86\" \"linux\"\n"
+
+	records, warnings, err := oopsAws.ParseZoneFile(strings.NewReader(content), "example.com.")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 8: skipped HINFO record of host.example.com., Route53 doesn't support the type"}, warnings)
+
+	// Record sets from elsewhere are checked as well
+	records = append(records, fake.Record("key.example.com.", route53Types.RRType("DNSKEY"), 300, "257 3 13 aGVsbG8="))
+	plan, err := PlanImport(ctx, fake.NewRoute53(), "example.com.", records)
+	require.NoError(t, err)
+	assert.Len(t, plan.Changes, 3)
+	assert.Equal(t, []string{"skipped DNSKEY record of key.example.com., Route53 doesn't support the type"}, plan.Warnings)
+}
@@ -0,0 +1,162 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+)
+
+var errHostedZoneNotFound = errors.New("hosted zone not found")
+
+// maxChangesPerBatch stays well below the Route53 limit of 1000 resource records per ChangeResourceRecordSets call
+const maxChangesPerBatch = 100
+
+// PlanZone returns the changes needed to turn the current records of a zone into the desired ones. The apex SOA and NS
+// records belong to the hosted zone itself and are never touched. Records only present in current are deleted if prune is set.
+func PlanZone(zone string, current []route53Types.ResourceRecordSet, desired []route53Types.ResourceRecordSet, prune bool) []route53Types.Change {
+	var payload []route53Types.Change
+
+	if !strings.HasSuffix(zone, ".") {
+		zone += "."
+	}
+
+	currentByKey := indexRecords(zone, current)
+	desiredByKey := indexRecords(zone, desired)
+
+	for _, change := range oopsAws.DiffZone("", zone, current, desired) {
+		key := changeKey(change)
+		switch change.Change {
+		case oopsAws.ChangeAdded, oopsAws.ChangeModified:
+			rec, ok := desiredByKey[key]
+			if !ok {
+				continue
+			}
+			payload = append(payload, route53Types.Change{Action: route53Types.ChangeActionUpsert, ResourceRecordSet: &rec})
+		case oopsAws.ChangeRemoved:
+			rec, ok := currentByKey[key]
+			if !ok || !prune {
+				continue
+			}
+			payload = append(payload, route53Types.Change{Action: route53Types.ChangeActionDelete, ResourceRecordSet: &rec})
+		}
+	}
+
+	sort.SliceStable(payload, func(i, j int) bool {
+		return recordKey(*payload[i].ResourceRecordSet) < recordKey(*payload[j].ResourceRecordSet)
+	})
+
+	return payload
+}
+
+// Apply submits the changes to the hosted zone in batches
+func Apply(ctx context.Context, client oopsAws.Route53Api, hostedZoneId string, changes []route53Types.Change) error {
+	for start := 0; start < len(changes); start += maxChangesPerBatch {
+		end := min(start+maxChangesPerBatch, len(changes))
+
+		_, err := client.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: &hostedZoneId,
+			ChangeBatch: &route53Types.ChangeBatch{
+				Comment: aws.String("restored by oops"),
+				Changes: changes[start:end],
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply changes %d-%d: %w", start, end, err)
+		}
+	}
+
+	return nil
+}
+
+// FindHostedZoneId looks up the public or private hosted zone with the name. It fails if the name is ambiguous.
+func FindHostedZoneId(ctx context.Context, client oopsAws.Route53Api, zone string) (string, error) {
+	if !strings.HasSuffix(zone, ".") {
+		zone += "."
+	}
+
+	var matches []string
+	pag := route53.NewListHostedZonesPaginator(client, &route53.ListHostedZonesInput{})
+	for pag.HasMorePages() {
+		resp, err := pag.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+		for _, hz := range resp.HostedZones {
+			if aws.ToString(hz.Name) == zone {
+				matches = append(matches, aws.ToString(hz.Id))
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", errHostedZoneNotFound, zone)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("hosted zone %s is ambiguous, found %s", zone, strings.Join(matches, ", "))
+	}
+}
+
+func FetchZoneRecords(ctx context.Context, client oopsAws.Route53Api, hostedZoneId string) ([]route53Types.ResourceRecordSet, error) {
+	var payload []route53Types.ResourceRecordSet
+
+	pag := route53.NewListResourceRecordSetsPaginator(client, &route53.ListResourceRecordSetsInput{HostedZoneId: &hostedZoneId})
+	for pag.HasMorePages() {
+		resp, err := pag.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		payload = append(payload, resp.ResourceRecordSets...)
+	}
+
+	return payload, nil
+}
+
+func DescribeChange(change route53Types.Change) string {
+	rec := change.ResourceRecordSet
+	desc := fmt.Sprintf("%s %s %s", change.Action, aws.ToString(rec.Name), rec.Type)
+	if rec.SetIdentifier != nil {
+		desc += fmt.Sprintf(" (%s)", aws.ToString(rec.SetIdentifier))
+	}
+	if rec.AliasTarget != nil {
+		return desc + fmt.Sprintf(" ALIAS %s", aws.ToString(rec.AliasTarget.DNSName))
+	}
+
+	var values []string
+	for _, val := range rec.ResourceRecords {
+		values = append(values, aws.ToString(val.Value))
+	}
+	return desc + fmt.Sprintf(" %d %s", aws.ToInt64(rec.TTL), strings.Join(values, " "))
+}
+
+func isApexManaged(zone string, rec route53Types.ResourceRecordSet) bool {
+	return aws.ToString(rec.Name) == zone && (rec.Type == route53Types.RRTypeSoa || rec.Type == route53Types.RRTypeNs)
+}
+
+func recordKey(rec route53Types.ResourceRecordSet) string {
+	return fmt.Sprintf("%s|%s|%s", aws.ToString(rec.Name), rec.Type, aws.ToString(rec.SetIdentifier))
+}
+
+func changeKey(change oopsAws.RecordChange) string {
+	return fmt.Sprintf("%s|%s|%s", change.Name, change.Type, change.SetIdentifier)
+}
+
+// indexRecords keys the records of a zone the way DiffZone does, leaving out the apex records managed by Route53
+func indexRecords(zone string, records []route53Types.ResourceRecordSet) map[string]route53Types.ResourceRecordSet {
+	payload := make(map[string]route53Types.ResourceRecordSet)
+	for _, rec := range records {
+		if isApexManaged(zone, rec) {
+			continue
+		}
+		payload[recordKey(rec)] = rec
+	}
+	return payload
+}
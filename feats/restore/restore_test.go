@@ -0,0 +1,45 @@
+package restore
+
+import (
+	"testing"
+
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"go.dfds.cloud/oops/core/aws/fake"
+)
+
+func TestPlanZone(t *testing.T) {
+	current := []route53Types.ResourceRecordSet{
+		fake.Record("example.com.", route53Types.RRTypeSoa, 900, "ns-1.awsdns-01.org. hostmaster.example.com. 2 7200 900 1209600 86400"),
+		fake.Record("example.com.", route53Types.RRTypeNs, 172800, "ns-1.awsdns-01.org."),
+		fake.Record("www.example.com.", route53Types.RRTypeA, 300, "192.0.2.1"),
+		fake.Record("api.example.com.", route53Types.RRTypeCname, 300, "www.example.com."),
+		fake.Record("new.example.com.", route53Types.RRTypeA, 300, "192.0.2.9"),
+	}
+	desired := []route53Types.ResourceRecordSet{
+		fake.Record("example.com.", route53Types.RRTypeSoa, 900, "ns-2.awsdns-02.org. hostmaster.example.com. 1 7200 900 1209600 86400"),
+		fake.Record("example.com.", route53Types.RRTypeNs, 172800, "ns-2.awsdns-02.org."),
+		fake.Record("www.example.com.", route53Types.RRTypeA, 300, "192.0.2.1"),
+		fake.Record("api.example.com.", route53Types.RRTypeCname, 60, "www.example.com."),
+		fake.Record("mail.example.com.", route53Types.RRTypeMx, 300, "10 mx.example.com."),
+	}
+
+	describe := func(changes []route53Types.Change) []string {
+		var payload []string
+		for _, change := range changes {
+			payload = append(payload, DescribeChange(change))
+		}
+		return payload
+	}
+
+	assert.Equal(t, []string{
+		"UPSERT api.example.com. CNAME 60 www.example.com.",
+		"UPSERT mail.example.com. MX 300 10 mx.example.com.",
+	}, describe(PlanZone("example.com", current, desired, false)))
+
+	assert.Equal(t, []string{
+		"UPSERT api.example.com. CNAME 60 www.example.com.",
+		"UPSERT mail.example.com. MX 300 10 mx.example.com.",
+		"DELETE new.example.com. A 300 192.0.2.9",
+	}, describe(PlanZone("example.com.", current, desired, true)))
+}
@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"sort"
+
+	"go.dfds.cloud/oops/core/config"
+	"go.dfds.cloud/oops/feats/export"
+	"go.dfds.cloud/oops/feats/notify"
+	"go.dfds.cloud/oops/feats/storage"
+	_ "go.dfds.cloud/oops/feats/storage/s3"
+)
+
+// Schemas is the JSON Schema of the config file along with the spec schemas of the registered providers
+type Schemas struct {
+	Config            config.Schema            `json:"config"`
+	BackupLocations   map[string]config.Schema `json:"backupLocations"`
+	NotificationSinks map[string]config.Schema `json:"notificationSinks"`
+}
+
+func All() Schemas {
+	locations := storage.Schemas()
+	sinks := notify.Schemas()
+
+	conf := config.SchemaOf[config.Config]()
+	constrainSpecs(conf, "backupLocations", locations)
+	constrainSpecs(conf["properties"].(config.Schema)["notifications"].(config.Schema), "sinks", sinks)
+	constrainExports(conf)
+
+	return Schemas{
+		Config:            conf,
+		BackupLocations:   locations,
+		NotificationSinks: sinks,
+	}
+}
+
+// constrainSpecs limits the provider of the items of the array property to the registered ones, and validates each
+// item's spec against the schema of its provider
+func constrainSpecs(parent config.Schema, property string, specs map[string]config.Schema) {
+	items := parent["properties"].(config.Schema)[property].(config.Schema)["items"].(config.Schema)
+
+	var providers []string
+	for provider := range specs {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	var enum []interface{}
+	var conditions []interface{}
+	for _, provider := range providers {
+		enum = append(enum, provider)
+
+		spec := config.Schema{}
+		for key, value := range specs[provider] {
+			if key != "$schema" {
+				spec[key] = value
+			}
+		}
+		conditions = append(conditions, config.Schema{
+			"if": config.Schema{
+				"properties": config.Schema{"provider": config.Schema{"const": provider}},
+				"required":   []interface{}{"provider"},
+			},
+			"then": config.Schema{
+				"properties": config.Schema{"spec": spec},
+			},
+		})
+	}
+
+	items["properties"].(config.Schema)["provider"] = config.Schema{"type": "string", "enum": enum}
+	items["required"] = []interface{}{"name", "provider"}
+	items["allOf"] = conditions
+}
+
+// constrainExports limits job.route53Backup.exports to the formats registered by the export package
+func constrainExports(conf config.Schema) {
+	var enum []interface{}
+	for _, name := range export.Names() {
+		enum = append(enum, name)
+	}
+
+	job := conf["properties"].(config.Schema)["job"].(config.Schema)
+	backup := job["properties"].(config.Schema)["route53Backup"].(config.Schema)
+	exports := backup["properties"].(config.Schema)["exports"].(config.Schema)
+	exports["items"].(config.Schema)["enum"] = enum
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const LatestKey = "latest.tar.gz"
+const LatestId = "latest"
+
+var ErrBackupNotFound = errors.New("backup not found")
+
+type Backup struct {
+	Id        string    `json:"id"`
+	Key       string    `json:"key"`
+	Name      string    `json:"name"`
+	Timestamp time.Time `json:"timestamp"`
+	Size      int64     `json:"size"`
+}
+
+// BackupKey returns the key a backup taken at t is stored under, e.g. 2025/1/31/1738281600-zones.tar.gz
+func BackupKey(t time.Time, name string) string {
+	return fmt.Sprintf("%d/%d/%d/%d-%s", t.Year(), t.Month(), t.Day(), t.Unix(), name)
+}
+
+// PutBackup stores content as the latest backup, and under the BackupKey of now
+func PutBackup(ctx context.Context, s Storage, name string, content []byte) error {
+	err := s.Put(ctx, LatestKey, content)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, BackupKey(time.Now(), name), content)
+}
+
+// ParseBackupKey is the inverse of BackupKey. Objects not following the layout are ignored.
+func ParseBackupKey(obj Object) (Backup, bool) {
+	parts := strings.Split(obj.Key, "/")
+	if len(parts) != 4 {
+		return Backup{}, false
+	}
+
+	base := path.Base(obj.Key)
+	unixRaw, name, found := strings.Cut(base, "-")
+	if !found {
+		return Backup{}, false
+	}
+
+	unix, err := strconv.ParseInt(unixRaw, 10, 64)
+	if err != nil {
+		return Backup{}, false
+	}
+
+	return Backup{
+		Id:        base,
+		Key:       obj.Key,
+		Name:      name,
+		Timestamp: time.Unix(unix, 0).UTC(),
+		Size:      obj.Size,
+	}, true
+}
+
+// newestLookbackMonths is how many months NewestBackup narrows its listing to, before falling back to listing everything
+const newestLookbackMonths = 12
+
+// ListBackups returns all backups of the artifact name in the storage, newest first. It walks the whole storage, prefer
+// ListBackupsBetween and NewestBackup.
+func ListBackups(ctx context.Context, s Storage, name string) ([]Backup, error) {
+	objects, err := s.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return filterBackups(objects, name, time.Time{}, time.Time{}), nil
+}
+
+// ListBackupsBetween returns the backups of the artifact name taken between from and to, newest first. Only the month
+// prefixes of BackupKey covering the range are listed.
+func ListBackupsBetween(ctx context.Context, s Storage, name string, from time.Time, to time.Time) ([]Backup, error) {
+	var objects []Object
+	for _, prefix := range monthPrefixes(from, to) {
+		monthObjects, err := s.List(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, monthObjects...)
+	}
+
+	return filterBackups(objects, name, from, to), nil
+}
+
+// NewestBackup returns the most recent backup of the artifact name, looking at recent months first. The bool is false if
+// there's no backup at all.
+func NewestBackup(ctx context.Context, s Storage, name string) (Backup, bool, error) {
+	now := time.Now()
+	for i := 0; i < newestLookbackMonths; i++ {
+		month := time.Date(now.Year(), now.Month()-time.Month(i), 1, 0, 0, 0, 0, time.UTC)
+		backups, err := ListBackupsBetween(ctx, s, name, month, month.AddDate(0, 1, 0).Add(-time.Second))
+		if err != nil {
+			return Backup{}, false, err
+		}
+		if len(backups) > 0 {
+			return backups[0], true, nil
+		}
+	}
+
+	backups, err := ListBackups(ctx, s, name)
+	if err != nil || len(backups) == 0 {
+		return Backup{}, false, err
+	}
+	return backups[0], true, nil
+}
+
+// monthPrefixes returns the BackupKey prefixes of the months between from and to. The date prefix depends on the timezone
+// of the uploader, so the range is widened by a day on both ends.
+func monthPrefixes(from time.Time, to time.Time) []string {
+	from = from.UTC().AddDate(0, 0, -1)
+	to = to.UTC().AddDate(0, 0, 1)
+
+	var payload []string
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(to); month = month.AddDate(0, 1, 0) {
+		payload = append(payload, fmt.Sprintf("%d/%d/", month.Year(), month.Month()))
+	}
+	return payload
+}
+
+// filterBackups returns the objects that are backups of the artifact name within from and to, newest first. Zero times
+// leave the range open.
+func filterBackups(objects []Object, name string, from time.Time, to time.Time) []Backup {
+	var payload []Backup
+	for _, obj := range objects {
+		backup, ok := ParseBackupKey(obj)
+		if !ok || backup.Name != name {
+			continue
+		}
+		if (!from.IsZero() && backup.Timestamp.Before(from)) || (!to.IsZero() && backup.Timestamp.After(to)) {
+			continue
+		}
+		payload = append(payload, backup)
+	}
+
+	sort.Slice(payload, func(i, j int) bool {
+		return payload[i].Timestamp.After(payload[j].Timestamp)
+	})
+
+	return payload
+}
+
+// FindBackup resolves a backup id, as returned by ListBackups, to its storage key. The id "latest" refers to the most recently uploaded backup.
+func FindBackup(ctx context.Context, s Storage, id string) (Backup, error) {
+	if id == LatestId {
+		return Backup{Id: LatestId, Key: LatestKey}, nil
+	}
+
+	base := path.Base(id)
+	unixRaw, name, found := strings.Cut(base, "-")
+	if !found {
+		return Backup{}, ErrBackupNotFound
+	}
+	unix, err := strconv.ParseInt(unixRaw, 10, 64)
+	if err != nil {
+		return Backup{}, ErrBackupNotFound
+	}
+
+	// The date prefix depends on the timezone of the uploader, so look in the surrounding days as well
+	ts := time.Unix(unix, 0)
+	for _, day := range []time.Time{ts.UTC(), ts.UTC().AddDate(0, 0, -1), ts.UTC().AddDate(0, 0, 1)} {
+		objects, err := s.List(ctx, fmt.Sprintf("%d/%d/%d/", day.Year(), day.Month(), day.Day()))
+		if err != nil {
+			return Backup{}, err
+		}
+		for _, obj := range objects {
+			backup, ok := ParseBackupKey(obj)
+			if ok && backup.Id == base && backup.Name == name {
+				return backup, nil
+			}
+		}
+	}
+
+	return Backup{}, ErrBackupNotFound
+}
@@ -0,0 +1,121 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dfds.cloud/oops/feats/storage"
+	"go.dfds.cloud/oops/feats/storage/memory"
+)
+
+// listCounter records the prefixes listed
+type listCounter struct {
+	storage.Storage
+	prefixes []string
+}
+
+func (l *listCounter) List(ctx context.Context, prefix string) ([]storage.Object, error) {
+	l.prefixes = append(l.prefixes, prefix)
+	return l.Storage.List(ctx, prefix)
+}
+
+func putAt(t *testing.T, s storage.Storage, ts time.Time, name string) string {
+	key := storage.BackupKey(ts, name)
+	require.NoError(t, s.Put(context.Background(), key, []byte(key)))
+	return key
+}
+
+func TestParseBackupKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		ok   bool
+		name string
+	}{
+		{key: "2025/1/31/1738281600-zones.tar.gz", ok: true, name: "zones.tar.gz"},
+		{key: "2025/1/31/1738281600-my-zones.tar.gz", ok: true, name: "my-zones.tar.gz"},
+		{key: storage.LatestKey},
+		{key: "2025/1/31/zones.tar.gz"},
+		{key: "2025/1/31/now-zones.tar.gz"},
+		{key: "backups/2025/1/31/1738281600-zones.tar.gz"},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			backup, ok := storage.ParseBackupKey(storage.Object{Key: test.key, Size: 42})
+			assert.Equal(t, test.ok, ok)
+			if !test.ok {
+				return
+			}
+			assert.Equal(t, test.name, backup.Name)
+			assert.Equal(t, "1738281600-"+test.name, backup.Id)
+			assert.Equal(t, time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), backup.Timestamp)
+			assert.Equal(t, int64(42), backup.Size)
+		})
+	}
+
+	// Keys round trip
+	ts := time.Date(2025, 10, 3, 12, 0, 0, 0, time.UTC)
+	backup, ok := storage.ParseBackupKey(storage.Object{Key: storage.BackupKey(ts, "zones.tar.gz")})
+	require.True(t, ok)
+	assert.Equal(t, ts, backup.Timestamp)
+}
+
+func TestListBackupsBetween(t *testing.T) {
+	store := &listCounter{Storage: memory.New()}
+	putAt(t, store, time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC), "zones.tar.gz")
+	putAt(t, store, time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC), "zones.tar.gz")
+	putAt(t, store, time.Date(2025, 1, 15, 1, 0, 0, 0, time.UTC), "other.tar.gz")
+	putAt(t, store, time.Date(2025, 2, 10, 1, 0, 0, 0, time.UTC), "zones.tar.gz")
+	putAt(t, store, time.Date(2025, 3, 10, 1, 0, 0, 0, time.UTC), "zones.tar.gz")
+
+	backups, err := storage.ListBackupsBetween(context.Background(), store, "zones.tar.gz",
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, time.Date(2025, 2, 10, 1, 0, 0, 0, time.UTC), backups[0].Timestamp)
+	assert.Equal(t, time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC), backups[1].Timestamp)
+	// Only the months around the range are listed
+	assert.Equal(t, []string{"2024/12/", "2025/1/", "2025/2/", "2025/3/"}, store.prefixes)
+}
+
+func TestNewestBackup(t *testing.T) {
+	store := &listCounter{Storage: memory.New()}
+	_, found, err := storage.NewestBackup(context.Background(), store, "zones.tar.gz")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	putAt(t, store, time.Now().AddDate(-3, 0, 0), "zones.tar.gz")
+	newest, found, err := storage.NewestBackup(context.Background(), store, "zones.tar.gz")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, time.Now().AddDate(-3, 0, 0).Year(), newest.Timestamp.Year())
+
+	recent := putAt(t, store, time.Now().Add(-time.Hour), "zones.tar.gz")
+	store.prefixes = nil
+	newest, found, err = storage.NewestBackup(context.Background(), store, "zones.tar.gz")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, recent, newest.Key)
+	assert.NotContains(t, store.prefixes, "")
+}
+
+func TestFindBackup(t *testing.T) {
+	store := memory.New()
+	key := putAt(t, store, time.Date(2025, 1, 31, 23, 30, 0, 0, time.UTC), "zones.tar.gz")
+
+	backup, err := storage.FindBackup(context.Background(), store, storage.LatestId)
+	require.NoError(t, err)
+	assert.Equal(t, storage.LatestKey, backup.Key)
+
+	backup, err = storage.FindBackup(context.Background(), store, "1738366200-zones.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, key, backup.Key)
+
+	for _, id := range []string{"1738366200-other.tar.gz", "1738281600-zones.tar.gz", "zones.tar.gz", "x-zones.tar.gz"} {
+		_, err = storage.FindBackup(context.Background(), store, id)
+		assert.True(t, errors.Is(err, storage.ErrBackupNotFound), id)
+	}
+}
@@ -0,0 +1,80 @@
+// Package memory implements storage.Storage in memory. It isn't registered as a provider, it's meant for tests and for
+// callers that want to collect backups before handing them on.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.dfds.cloud/oops/feats/storage"
+)
+
+type Storage struct {
+	mu      sync.Mutex
+	objects map[string]object
+}
+
+type object struct {
+	content      []byte
+	lastModified time.Time
+}
+
+func New() *Storage {
+	return &Storage{objects: make(map[string]object)}
+}
+
+func (m *Storage) Put(_ context.Context, path string, content []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.objects[path] = object{content: bytes.Clone(content), lastModified: time.Now()}
+	return nil
+}
+
+func (m *Storage) Get(_ context.Context, path string) (io.ReadCloser, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	obj, ok := m.objects[path]
+	if !ok {
+		return nil, 0, storage.ErrBackupNotFound
+	}
+	return io.NopCloser(bytes.NewReader(obj.content)), int64(len(obj.content)), nil
+}
+
+func (m *Storage) List(_ context.Context, prefix string) ([]storage.Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var payload []storage.Object
+	for key, obj := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			payload = append(payload, storage.Object{Key: key, Size: int64(len(obj.content)), LastModified: obj.lastModified})
+		}
+	}
+	// S3 lists keys in lexicographic order as well
+	sort.Slice(payload, func(i, j int) bool { return payload[i].Key < payload[j].Key })
+
+	return payload, nil
+}
+
+func (m *Storage) Delete(_ context.Context, path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.objects, path)
+	return nil
+}
+
+func (m *Storage) Exists(_ context.Context, path string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.objects[path]
+	return ok, nil
+}
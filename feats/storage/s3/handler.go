@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -12,67 +11,122 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	awsOops "go.dfds.cloud/oops/core/aws"
 	"go.dfds.cloud/oops/core/config"
-	"go.dfds.cloud/oops/core/logging"
-	"go.uber.org/zap"
+	"go.dfds.cloud/oops/feats/storage"
 )
 
 type Config struct {
-	Auth    string `json:"auth"`
-	Bucket  string `json:"bucket"`
-	RoleArn string `json:"roleArn"`
-	Region  string `json:"region"`
+	Auth    string `json:"auth" enum:",aws-default,aws-assume,aws-static" description:"aws-default is used if empty"`
+	Bucket  string `json:"bucket" required:"true"`
+	RoleArn string `json:"roleArn" description:"role assumed with aws-assume auth"`
+	// HubRoleArn, ExternalId, SessionDuration and SessionTags configure how RoleArn is assumed, like the settings of the
+	// same name of the route53Backup job
+	HubRoleArn      string            `json:"hubRoleArn" description:"assumed before roleArn, for role chaining"`
+	ExternalId      string            `json:"externalId"`
+	SessionDuration string            `json:"sessionDuration" description:"e.g. 1h, between 15m and 12h or 1h when chained"`
+	SessionTags     map[string]string `json:"sessionTags"`
+	Region          string            `json:"region" description:"defaults to aws.region"`
+	// Partition and StsEndpoint override the AWS defaults for this location
+	Partition   string `json:"partition" description:"defaults to aws.partition"`
+	StsEndpoint string `json:"stsEndpoint" description:"regional, global or an https URL, defaults to aws.stsEndpoint"`
+	// AccessKeyId and SecretAccessKey are used by aws-static, preferably through secret references
+	AccessKeyId     config.Secret `json:"accessKeyId" description:"used with aws-static auth"`
+	SecretAccessKey config.Secret `json:"secretAccessKey" description:"used with aws-static auth"`
 }
 
-func HandleS3LocationPut(ctx context.Context, location config.BackupLocation, name string, content []byte) error {
-	spec, err := config.LocationSpecToType[Config](location)
-	if err != nil {
-		return err
+func init() {
+	storage.Register("s3", func(ctx context.Context, location config.BackupLocation) (storage.Storage, error) {
+		return NewBackendFromLocation(ctx, location)
+	})
+	storage.RegisterValidator("s3", func(conf config.Config, location config.BackupLocation) error {
+		spec, err := config.LocationSpecToType[Config](location)
+		if err != nil {
+			return err
+		}
+		return spec.Validate(conf.AwsDefaults())
+	})
+	storage.RegisterSchema("s3", config.SchemaOf[Config])
+}
+
+// Validate checks the spec, with its AWS settings merged onto defaults as they will be when the location is opened
+func (c *Config) Validate(defaults config.AwsSettings) error {
+	if c.Bucket == "" {
+		return errors.New("spec.bucket is required")
 	}
 
-	var awsCfg aws.Config
-	// Determine config
-	switch spec.Auth {
+	switch c.Auth {
+	case "", "aws-default":
 	case "aws-assume":
-		creds, err := awsOops.AssumeRole(ctx, spec.RoleArn)
-		if err != nil {
-			return err
+		if c.RoleArn == "" {
+			return errors.New("spec.roleArn is required for aws-assume auth")
 		}
-		awsCfg, err = awsConfig.LoadDefaultConfig(ctx, awsConfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(*creds.AccessKeyId, *creds.SecretAccessKey, *creds.SessionToken)), awsConfig.WithRegion(spec.Region))
+		duration, err := c.sessionDuration()
 		if err != nil {
-			return err
+			return fmt.Errorf("spec.sessionDuration: %w", err)
 		}
-	case "aws-default":
-		awsCfg, err = awsConfig.LoadDefaultConfig(ctx, awsConfig.WithRegion(spec.Region), awsConfig.WithHTTPClient(awsOops.CreateHttpClientWithoutKeepAlive()))
-		if err != nil {
-			log.Fatalf("unable to load SDK config, %v", err)
+		if err := config.ValidateSessionDuration(duration, c.HubRoleArn != ""); err != nil {
+			return fmt.Errorf("spec.sessionDuration %w", err)
 		}
-	default:
-		if spec.Auth == "" {
-			awsCfg, err = awsConfig.LoadDefaultConfig(ctx, awsConfig.WithRegion(spec.Region), awsConfig.WithHTTPClient(awsOops.CreateHttpClientWithoutKeepAlive()))
-			if err != nil {
-				log.Fatalf("unable to load SDK config, %v", err)
-			}
-		} else {
-			return errors.New("unknown auth type for s3 location")
+	case "aws-static":
+		if c.AccessKeyId == "" || c.SecretAccessKey == "" {
+			return errors.New("spec.accessKeyId and spec.secretAccessKey are required for aws-static auth")
 		}
+	default:
+		return fmt.Errorf("unknown auth type %s for s3 location", c.Auth)
 	}
 
-	backend := NewBackend(awsCfg, spec.Bucket)
+	return defaults.Merge(c.awsSettings()).Validate("spec")
+}
 
-	// latest
-	err = backend.Put(ctx, fmt.Sprintf("latest.tar.gz"), content)
-	if err != nil {
-		return err
+// sessionDuration parses SessionDuration, zero selects the default
+func (c *Config) sessionDuration() (time.Duration, error) {
+	if c.SessionDuration == "" {
+		return 0, nil
 	}
+	return time.ParseDuration(c.SessionDuration)
+}
 
-	// current day
-	currentTime := time.Now()
-	err = backend.Put(ctx, fmt.Sprintf(fmt.Sprintf("%d/%d/%d/%d-%s", currentTime.Year(), currentTime.Month(), currentTime.Day(), currentTime.Unix(), name)), content)
+func (c *Config) awsSettings() config.AwsSettings {
+	return config.AwsSettings{Region: c.Region, Partition: c.Partition, StsEndpoint: c.StsEndpoint}
+}
+
+func NewBackendFromLocation(ctx context.Context, location config.BackupLocation) (*Backend, error) {
+	spec, err := config.LocationSpecToType[Config](location)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	logging.Logger.Info("Saved backup to storage location", zap.String("location", location.Name), zap.String("provider", location.Provider), zap.String("bucket", spec.Bucket))
+	factory := awsOops.NewConfigFactory(spec.awsSettings())
+
+	var awsCfg aws.Config
+	// Determine config
+	switch spec.Auth {
+	case "aws-assume":
+		duration, err := spec.sessionDuration()
+		if err != nil {
+			return nil, err
+		}
+		awsCfg, err = factory.AssumeRoleConfig(ctx, spec.RoleArn, awsOops.RoleOptions{
+			HubRoleArn: spec.HubRoleArn,
+			ExternalId: spec.ExternalId,
+			Duration:   duration,
+			Tags:       spec.SessionTags,
+		})
+		if err != nil {
+			return nil, err
+		}
+	case "aws-static":
+		awsCfg, err = factory.Load(ctx, awsConfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(spec.AccessKeyId.Value(), spec.SecretAccessKey.Value(), "")))
+		if err != nil {
+			return nil, err
+		}
+	case "", "aws-default":
+		awsCfg, err = factory.Load(ctx)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unknown auth type for s3 location")
+	}
 
-	return nil
+	return NewBackend(awsCfg, spec.Bucket), nil
 }
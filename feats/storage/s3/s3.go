@@ -4,21 +4,37 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
 	"net/http"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	s3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.dfds.cloud/oops/feats/storage"
 )
 
+// Api is the part of the S3 client the backend uses
+type Api interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	s3.ListObjectsV2APIClient
+}
+
 type Backend struct {
-	client *s3.Client
+	client Api
 	bucket string
 }
 
 func NewBackend(awsConf aws.Config, bucket string) *Backend {
+	return NewBackendWithClient(s3.NewFromConfig(awsConf), bucket)
+}
+
+// NewBackendWithClient uses client for requests to the bucket, e.g. one with custom options or a fake
+func NewBackendWithClient(client Api, bucket string) *Backend {
 	return &Backend{
-		client: s3.NewFromConfig(awsConf),
+		client: client,
 		bucket: bucket,
 	}
 }
@@ -36,6 +52,46 @@ func (s *Backend) Put(ctx context.Context, path string, content []byte) error {
 	return nil
 }
 
+func (s *Backend) Get(ctx context.Context, path string) (io.ReadCloser, int64, error) {
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &path,
+	})
+	if err != nil {
+		var responseError *awshttp.ResponseError
+		if errors.As(err, &responseError) && responseError.ResponseError.HTTPStatusCode() == http.StatusNotFound {
+			return nil, 0, storage.ErrBackupNotFound
+		}
+		return nil, 0, err
+	}
+
+	return resp.Body, aws.ToInt64(resp.ContentLength), nil
+}
+
+func (s *Backend) List(ctx context.Context, prefix string) ([]storage.Object, error) {
+	var payload []storage.Object
+
+	pag := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: &s.bucket,
+		Prefix: &prefix,
+	})
+	for pag.HasMorePages() {
+		resp, err := pag.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range resp.Contents {
+			payload = append(payload, storage.Object{
+				Key:          aws.ToString(obj.Key),
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+		}
+	}
+
+	return payload, nil
+}
+
 func (s *Backend) Delete(ctx context.Context, path string) error {
 	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: &s.bucket,
@@ -1,9 +1,91 @@
 package storage
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"go.dfds.cloud/oops/core/config"
+)
 
 type Storage interface {
 	Put(ctx context.Context, path string, content []byte) error
+	Get(ctx context.Context, path string) (io.ReadCloser, int64, error)
+	List(ctx context.Context, prefix string) ([]Object, error)
 	Delete(ctx context.Context, path string) error
-	Exists(ctx context.Context, path string) bool
+	Exists(ctx context.Context, path string) (bool, error)
+}
+
+type Object struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// Factory opens a Storage for a configured backup location
+type Factory func(ctx context.Context, location config.BackupLocation) (Storage, error)
+
+// Validator checks the spec of a backup location without connecting to it, conf is the config the location belongs to
+type Validator func(conf config.Config, location config.BackupLocation) error
+
+var providers = map[string]Factory{}
+var validators = map[string]Validator{}
+var schemas = map[string]func() config.Schema{}
+
+// Register makes a storage provider available under the name used in BackupLocation.Provider
+func Register(provider string, factory Factory) {
+	providers[provider] = factory
+}
+
+// RegisterValidator sets the spec validation used by ValidateLocation for the provider
+func RegisterValidator(provider string, validator Validator) {
+	validators[provider] = validator
+}
+
+// RegisterSchema sets the function generating the JSON Schema of the provider's spec, usually config.SchemaOf
+func RegisterSchema(provider string, schema func() config.Schema) {
+	schemas[provider] = schema
+}
+
+// Schemas returns the spec schema of every provider that registered one
+func Schemas() map[string]config.Schema {
+	payload := make(map[string]config.Schema)
+	for provider, schema := range schemas {
+		payload[provider] = schema()
+	}
+	return payload
+}
+
+func IsRegistered(provider string) bool {
+	_, ok := providers[provider]
+	return ok
+}
+
+func Open(ctx context.Context, location config.BackupLocation) (Storage, error) {
+	factory, ok := providers[location.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %s", location.Provider)
+	}
+
+	return factory(ctx, location)
+}
+
+// ValidateLocation checks that the provider of the location is known and its spec is valid in conf
+func ValidateLocation(conf config.Config, location config.BackupLocation) error {
+	if !IsRegistered(location.Provider) {
+		return fmt.Errorf("backup location %s: unknown provider %s", location.Name, location.Provider)
+	}
+
+	validator, ok := validators[location.Provider]
+	if !ok {
+		return nil
+	}
+
+	err := validator(conf, location)
+	if err != nil {
+		return fmt.Errorf("backup location %s: %w", location.Name, err)
+	}
+
+	return nil
 }
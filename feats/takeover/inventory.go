@@ -0,0 +1,125 @@
+package takeover
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+)
+
+// Inventory holds the resources existing in the scanned accounts, by kind and lower case name, mapped to their account
+type Inventory map[oopsAws.TargetKind]map[string]string
+
+func (i Inventory) Add(kind oopsAws.TargetKind, name string, account string) {
+	if i[kind] == nil {
+		i[kind] = make(map[string]string)
+	}
+	i[kind][strings.ToLower(strings.TrimSuffix(name, "."))] = account
+}
+
+// Merge adds the resources of other
+func (i Inventory) Merge(other Inventory) {
+	for kind, resources := range other {
+		for name, account := range resources {
+			i.Add(kind, name, account)
+		}
+	}
+}
+
+func (i Inventory) Contains(target oopsAws.Target) bool {
+	_, ok := i[target.Kind][strings.ToLower(target.Name)]
+	return ok
+}
+
+// Collect lists the resources of an account. newClient returns the client of a region, the empty region being the
+// default one used for S3 and CloudFront. Environments and load balancers are listed in regions.
+func Collect(ctx context.Context, account string, newClient func(region string) oopsAws.ResourcesApi, regions []string) (Inventory, error) {
+	payload := make(Inventory)
+	global := newClient("")
+
+	buckets := s3.NewListBucketsPaginator(global, &s3.ListBucketsInput{})
+	for buckets.HasMorePages() {
+		resp, err := buckets.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing S3 buckets: %w", err)
+		}
+		for _, bucket := range resp.Buckets {
+			payload.Add(oopsAws.TargetS3Bucket, aws.ToString(bucket.Name), account)
+		}
+	}
+
+	distributions := cloudfront.NewListDistributionsPaginator(global, &cloudfront.ListDistributionsInput{})
+	for distributions.HasMorePages() {
+		resp, err := distributions.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing CloudFront distributions: %w", err)
+		}
+		if resp.DistributionList == nil {
+			continue
+		}
+		for _, distribution := range resp.DistributionList.Items {
+			payload.Add(oopsAws.TargetCloudFront, aws.ToString(distribution.DomainName), account)
+		}
+	}
+
+	for _, region := range regions {
+		err := collectRegion(ctx, account, newClient(region), payload)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", region, err)
+		}
+	}
+
+	return payload, nil
+}
+
+func collectRegion(ctx context.Context, account string, client oopsAws.ResourcesApi, payload Inventory) error {
+	input := &elasticbeanstalk.DescribeEnvironmentsInput{IncludeDeleted: aws.Bool(false)}
+	for {
+		resp, err := client.DescribeEnvironments(ctx, input)
+		if err != nil {
+			return fmt.Errorf("listing Elastic Beanstalk environments: %w", err)
+		}
+		for _, env := range resp.Environments {
+			payload.Add(oopsAws.TargetElasticBeanstalk, aws.ToString(env.CNAME), account)
+		}
+		if resp.NextToken == nil {
+			break
+		}
+		input.NextToken = resp.NextToken
+	}
+
+	loadBalancers := elasticloadbalancingv2.NewDescribeLoadBalancersPaginator(client, &elasticloadbalancingv2.DescribeLoadBalancersInput{})
+	for loadBalancers.HasMorePages() {
+		resp, err := loadBalancers.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("listing load balancers: %w", err)
+		}
+		for _, lb := range resp.LoadBalancers {
+			payload.Add(oopsAws.TargetLoadBalancer, aws.ToString(lb.DNSName), account)
+		}
+	}
+
+	classicInput := &elasticloadbalancing.DescribeLoadBalancersInput{}
+	for {
+		resp, err := client.DescribeClassicLoadBalancers(ctx, classicInput)
+		if err != nil {
+			return fmt.Errorf("listing classic load balancers: %w", err)
+		}
+		for _, lb := range resp.LoadBalancerDescriptions {
+			payload.Add(oopsAws.TargetLoadBalancer, aws.ToString(lb.DNSName), account)
+		}
+		if resp.NextMarker == nil {
+			break
+		}
+		classicInput.Marker = resp.NextMarker
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+// Package takeover finds records pointing at AWS resources that don't exist in any of our accounts. Whoever creates a
+// resource of that name, e.g. an S3 bucket, serves content under our domain.
+package takeover
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/logging"
+	selfserviceapi "go.dfds.cloud/oops/core/ssu/selfservice-api"
+	"go.dfds.cloud/oops/feats/lint"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// probeConcurrency limits the public lookups in flight
+const probeConcurrency = 8
+
+const (
+	// StatusMissing is a resource that doesn't exist at all, anyone can create it
+	StatusMissing = "missing"
+	// StatusForeign is a resource existing outside our accounts, e.g. of a vendor or someone who already took it over
+	StatusForeign = "foreign"
+	// StatusUnverified is a resource missing from our accounts that couldn't be looked up publicly
+	StatusUnverified = "unverified"
+)
+
+// Statuses are all the statuses, in the order they're reported in metrics
+var Statuses = []string{StatusMissing, StatusForeign, StatusUnverified}
+
+// RecordTarget is an AWS resource a record points at
+type RecordTarget struct {
+	Account string              `json:"account"`
+	Zone    string              `json:"zone"`
+	Name    string              `json:"name"`
+	Type    route53Types.RRType `json:"type"`
+	Target  oopsAws.Target      `json:"target"`
+}
+
+// Finding is a record pointing at a resource that doesn't exist in the scanned accounts
+type Finding struct {
+	RecordTarget
+	Status string `json:"status"`
+	// Owner is the capability of the account hosting the record, if known
+	Owner *selfserviceapi.AccountOwner `json:"owner,omitempty"`
+}
+
+func (f Finding) String() string {
+	owner := ""
+	if f.Owner != nil {
+		owner = fmt.Sprintf(", capability %s", f.Owner.CapabilityName)
+	}
+	return fmt.Sprintf("%s %s in zone %s of account %s%s: %s %s is %s", f.Name, f.Type, f.Zone, f.Account, owner, f.Target.Kind, f.Target.Name, f.Status)
+}
+
+// Claimable reports whether anyone can create the missing resource under the name the record points at. CloudFront
+// distributions and load balancers get names from AWS, so records pointing at missing ones can't be taken over.
+func (f Finding) Claimable() bool {
+	if f.Status != StatusMissing {
+		return false
+	}
+	return f.Target.Kind == oopsAws.TargetS3Bucket || f.Target.Kind == oopsAws.TargetElasticBeanstalk
+}
+
+// CountByStatus returns the number of findings per account and status. Every account is included, with a zero count for
+// statuses without findings, so metrics of fixed records go back to zero.
+func CountByStatus(accounts []string, findings []Finding) map[string]map[string]int {
+	payload := make(map[string]map[string]int)
+	for _, acc := range accounts {
+		payload[acc] = make(map[string]int)
+		for _, status := range Statuses {
+			payload[acc][status] = 0
+		}
+	}
+	for _, finding := range findings {
+		if _, ok := payload[finding.Account]; !ok {
+			payload[finding.Account] = make(map[string]int)
+		}
+		payload[finding.Account][finding.Status]++
+	}
+	return payload
+}
+
+type Options struct {
+	// Probe looks up resources missing from the inventory publicly, all findings are unverified without one
+	Probe lint.Probe
+	// Owners maps account ids to the capability they belong to
+	Owners map[string]selfserviceapi.AccountOwner
+}
+
+// Targets returns the AWS resources the records by account and zone point at, sorted by account, zone and name
+func Targets(records map[string]map[string][]route53Types.ResourceRecordSet) []RecordTarget {
+	var payload []RecordTarget
+	for acc, zones := range records {
+		for zone, recs := range zones {
+			for _, rec := range recs {
+				for _, target := range oopsAws.RecordTargets(rec) {
+					payload = append(payload, RecordTarget{Account: acc, Zone: zone, Name: aws.ToString(rec.Name), Type: rec.Type, Target: target})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(payload, func(i, j int) bool {
+		a, b := payload[i], payload[j]
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		if a.Zone != b.Zone {
+			return a.Zone < b.Zone
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Target.Name < b.Target.Name
+	})
+
+	return payload
+}
+
+// Regions returns the regions of the regional resources among the targets, sorted
+func Regions(targets []RecordTarget) []string {
+	seen := make(map[string]bool)
+	var payload []string
+	for _, target := range targets {
+		if region := target.Target.Region; region != "" && !seen[region] {
+			seen[region] = true
+			payload = append(payload, region)
+		}
+	}
+	sort.Strings(payload)
+	return payload
+}
+
+// Check returns the targets missing from the inventory. Each of them is looked up once through the probe, telling
+// resources nobody has apart from those of third parties.
+func Check(ctx context.Context, targets []RecordTarget, inventory Inventory, opts Options) []Finding {
+	var missing []RecordTarget
+	for _, target := range targets {
+		if !inventory.Contains(target.Target) {
+			missing = append(missing, target)
+		}
+	}
+
+	statuses := probe(ctx, missing, opts.Probe)
+
+	var payload []Finding
+	for _, target := range missing {
+		finding := Finding{RecordTarget: target, Status: StatusUnverified}
+		if status, ok := statuses[targetKey(target.Target)]; ok {
+			finding.Status = status
+		}
+		if owner, ok := opts.Owners[target.Account]; ok {
+			finding.Owner = &owner
+		}
+		payload = append(payload, finding)
+	}
+
+	return payload
+}
+
+// probe returns the status of every distinct target it could look up
+func probe(ctx context.Context, targets []RecordTarget, prober lint.Probe) map[string]string {
+	payload := make(map[string]string)
+	if prober == nil {
+		return payload
+	}
+
+	mu := &sync.Mutex{}
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(probeConcurrency)
+
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		key := targetKey(target.Target)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		group.Go(func() error {
+			var exists bool
+			var err error
+			switch target.Target.Kind {
+			case oopsAws.TargetS3Bucket:
+				exists, err = prober.BucketExists(groupCtx, target.Target.Name)
+			default:
+				exists, err = prober.HostExists(groupCtx, target.Target.Name)
+			}
+			if err != nil {
+				logging.Logger.Debug("Unable to look up record target", zap.String("target", target.Target.Name), zap.Error(err))
+				return nil
+			}
+
+			status := StatusMissing
+			if exists {
+				status = StatusForeign
+			}
+			mu.Lock()
+			payload[key] = status
+			mu.Unlock()
+			return nil
+		})
+	}
+	group.Wait()
+
+	return payload
+}
+
+func targetKey(target oopsAws.Target) string {
+	return string(target.Kind) + "|" + strings.ToLower(target.Name)
+}
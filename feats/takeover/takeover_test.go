@@ -0,0 +1,113 @@
+package takeover
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	route53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	oopsAws "go.dfds.cloud/oops/core/aws"
+	"go.dfds.cloud/oops/core/aws/fake"
+	"go.dfds.cloud/oops/core/logging"
+	selfserviceapi "go.dfds.cloud/oops/core/ssu/selfservice-api"
+	"go.uber.org/zap"
+)
+
+func TestCheck(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	records := map[string]map[string][]route53Types.ResourceRecordSet{
+		"111111111111": {"example.com.": {
+			fake.Record("www.example.com.", route53Types.RRTypeCname, 300, "d111.cloudfront.net."),
+			fake.Record("old.example.com.", route53Types.RRTypeCname, 300, "old-site.s3-website-eu-west-1.amazonaws.com."),
+			fake.Record("vendor.example.com.", route53Types.RRTypeCname, 300, "d999.cloudfront.net."),
+			fake.Record("app.example.com.", route53Types.RRTypeCname, 300, "app.eu-central-1.elasticbeanstalk.com."),
+			fake.Record("api.example.com.", route53Types.RRTypeCname, 300, "api-123.eu-west-1.elb.amazonaws.com."),
+			fake.Record("mail.example.com.", route53Types.RRTypeCname, 300, "mail.example.net."),
+		}},
+	}
+
+	targets := Targets(records)
+	require.Len(t, targets, 5)
+	assert.Equal(t, []string{"eu-central-1", "eu-west-1"}, Regions(targets))
+
+	// Resources are spread over accounts, S3 and CloudFront are listed once per account
+	accounts := map[string]map[string]*fake.Resources{
+		"111111111111": {
+			"":             {Distributions: []string{"D111.cloudfront.net"}},
+			"eu-west-1":    {ClassicLoadBalancers: []string{"api-123.eu-west-1.elb.amazonaws.com"}},
+			"eu-central-1": {},
+		},
+		"222222222222": {
+			"":             {Buckets: []string{"other"}},
+			"eu-west-1":    {},
+			"eu-central-1": {Environments: []string{"app.eu-central-1.elasticbeanstalk.com"}},
+		},
+	}
+	inventory := make(Inventory)
+	for acc, regions := range accounts {
+		accountInventory, err := Collect(context.Background(), acc, func(region string) oopsAws.ResourcesApi {
+			return regions[region]
+		}, Regions(targets))
+		require.NoError(t, err)
+		inventory.Merge(accountInventory)
+	}
+
+	findings := Check(context.Background(), targets, inventory, Options{
+		Probe:  &fake.Probe{Hosts: map[string]bool{"d999.cloudfront.net": true}, Buckets: map[string]bool{"old-site": false}},
+		Owners: map[string]selfserviceapi.AccountOwner{"111111111111": {CapabilityId: "web-abcd", CapabilityName: "web"}},
+	})
+	require.Len(t, findings, 2)
+	assert.Equal(t, "old.example.com.", findings[0].Name)
+	assert.Equal(t, StatusMissing, findings[0].Status)
+	assert.Equal(t, "web", findings[0].Owner.CapabilityName)
+	assert.Equal(t, "vendor.example.com. CNAME in zone example.com. of account 111111111111, capability web: cloudfront-distribution d999.cloudfront.net is foreign", findings[1].String())
+
+	// Without a probe nothing is looked up publicly
+	findings = Check(context.Background(), targets, inventory, Options{})
+	require.Len(t, findings, 2)
+	assert.Equal(t, StatusUnverified, findings[0].Status)
+	assert.Nil(t, findings[0].Owner)
+
+	_, err := Collect(context.Background(), "333333333333", func(string) oopsAws.ResourcesApi {
+		return &fake.Resources{Err: errors.New("AccessDenied")}
+	}, nil)
+	assert.ErrorContains(t, err, "listing S3 buckets: AccessDenied")
+}
+
+func TestFinding_Claimable(t *testing.T) {
+	tests := []struct {
+		kind      oopsAws.TargetKind
+		status    string
+		claimable bool
+	}{
+		{oopsAws.TargetS3Bucket, StatusMissing, true},
+		{oopsAws.TargetElasticBeanstalk, StatusMissing, true},
+		{oopsAws.TargetCloudFront, StatusMissing, false},
+		{oopsAws.TargetLoadBalancer, StatusMissing, false},
+		{oopsAws.TargetS3Bucket, StatusForeign, false},
+		{oopsAws.TargetS3Bucket, StatusUnverified, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.kind)+" "+tt.status, func(t *testing.T) {
+			finding := Finding{RecordTarget: RecordTarget{Target: oopsAws.Target{Kind: tt.kind}}, Status: tt.status}
+			assert.Equal(t, tt.claimable, finding.Claimable())
+		})
+	}
+}
+
+func TestCountByStatus(t *testing.T) {
+	findings := []Finding{
+		{RecordTarget: RecordTarget{Account: "111111111111"}, Status: StatusMissing},
+		{RecordTarget: RecordTarget{Account: "111111111111"}, Status: StatusMissing},
+		{RecordTarget: RecordTarget{Account: "111111111111"}, Status: StatusForeign},
+	}
+
+	assert.Equal(t, map[string]map[string]int{
+		"111111111111": {StatusMissing: 2, StatusForeign: 1, StatusUnverified: 0},
+		"222222222222": {StatusMissing: 0, StatusForeign: 0, StatusUnverified: 0},
+	}, CountByStatus([]string{"111111111111", "222222222222"}, findings))
+}